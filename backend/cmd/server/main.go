@@ -1,17 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/plc-visualizer/backend/internal/api"
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/auth"
 	"github.com/plc-visualizer/backend/internal/config"
+	"github.com/plc-visualizer/backend/internal/parser"
 	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/storage"
 	"github.com/plc-visualizer/backend/internal/upload"
@@ -57,20 +62,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Effective configuration, updated at runtime through /api/admin/config
+	live := config.NewLive(cfg, configPath)
+
+	// Share the DuckDB memory and thread budget among all sessions
+	applyResourceLimits(cfg)
+
 	// Initialize session manager
 	sessionMgr := session.NewManager()
-
-	// Start background session cleanup
-	go func() {
-		ticker := time.NewTicker(time.Duration(cfg.Processing.CleanupIntervalMinutes) * time.Minute)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				sessionMgr.CleanupOldSessions(time.Duration(cfg.Processing.SessionTimeoutMinutes) * time.Minute)
-			}
+	applySessionConfig(sessionMgr, cfg)
+
+	// Token authentication and roles; Security settings apply live
+	authenticator := auth.NewAuthenticator(auth.NewUserStore(cfg.GetDataDir()))
+	authenticator.Configure(cfg.Security.RequireAuth, cfg.Security.AuthToken)
+	if cfg.Security.RequireAuth && cfg.Security.AuthToken == "" {
+		// Without a static token someone must be able to log in: create the first admin
+		// from ADMIN_USERNAME (default "admin") and ADMIN_PASSWORD, or refuse to start
+		username := os.Getenv("ADMIN_USERNAME")
+		if username == "" {
+			username = "admin"
 		}
-	}()
+		created, err := authenticator.Users().EnsureAdmin(username, os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			fmt.Printf("Authentication is required but no AuthToken or users are configured; set ADMIN_PASSWORD to create the first admin: %v\n", err)
+			os.Exit(1)
+		}
+		if created {
+			fmt.Printf("Created initial admin user %q\n", username)
+		}
+	}
+	live.OnChange(func(c *config.AppConfig) {
+		authenticator.Configure(c.Security.RequireAuth, c.Security.AuthToken)
+	})
 
 	// Initialize upload processing manager
 	uploadMgr := upload.NewManager(cfg.GetUploadDir(), fileStore)
@@ -82,6 +105,9 @@ func main() {
 		UploadMgr:  uploadMgr,
 		DataDir:    cfg.GetDataDir(),
 		Version:    Version,
+		Config:     live,
+		Auth:       authenticator,
+		Audit:      audit.NewLog(cfg.GetDataDir()),
 	}
 
 	// Create all handlers using the new modular structure
@@ -97,16 +123,49 @@ func main() {
 		fmt.Println("Default rules loaded successfully")
 	}
 
+	// Start background cleanup of sessions and idle workspaces; a config change restarts
+	// the interval
+	cleanupReset := make(chan struct{}, 1)
+	go func() {
+		for {
+			timer := time.NewTimer(time.Duration(live.Get().Processing.CleanupIntervalMinutes) * time.Minute)
+			select {
+			case <-timer.C:
+				timeout := time.Duration(live.Get().Processing.SessionTimeoutMinutes) * time.Minute
+				sessionMgr.CleanupOldSessions(timeout)
+				handlers.Map.ExpireWorkspaces(timeout)
+				handlers.Carrier.ExpireWorkspaces(timeout)
+			case <-cleanupReset:
+				timer.Stop()
+			}
+		}
+	}()
+
+	// Apply hot fields of configuration updates
+	live.OnChange(func(c *config.AppConfig) {
+		applyResourceLimits(c)
+		applySessionConfig(sessionMgr, c)
+		select {
+		case cleanupReset <- struct{}{}:
+		default:
+		}
+	})
+
 	e := echo.New()
 
 	// Setup custom error handler
 	api.SetupMiddleware(e)
 
 	// Configure middleware
+	// The default format, logging the URI with any token query parameter redacted
 	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: strings.Replace(middleware.DefaultLoggerConfig.Format, "${uri}", "${custom}", 1),
+		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) {
+			return buf.WriteString(api.RedactedURI(c.Request()))
+		},
 		Skipper: func(c echo.Context) bool {
 			// Skip logging if disabled in config
-			if !cfg.Advanced.EnableRequestLogging {
+			if !live.Get().Advanced.EnableRequestLogging {
 				return true
 			}
 			path := c.Request().URL.Path
@@ -122,17 +181,7 @@ func main() {
 		LogLevel:          0,
 	}))
 
-	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-		Timeout: time.Duration(cfg.Server.ReadTimeout) * time.Second,
-		Skipper: func(c echo.Context) bool {
-			path := c.Request().URL.Path
-			return strings.Contains(path, "/stream") ||
-				strings.Contains(path, "/upload") ||
-				strings.Contains(path, "/entries") ||
-				c.Request().Header.Get("Accept") == "text/event-stream"
-		},
-		ErrorMessage: "Request timeout - query took too long",
-	}))
+	e.Use(api.TimeoutMiddleware(time.Duration(cfg.Server.ReadTimeout) * time.Second))
 
 	// Compression middleware
 	if cfg.Processing.EnableCompression {
@@ -144,24 +193,19 @@ func main() {
 		}))
 	}
 
-	// Body limit middleware
-	e.Use(middleware.BodyLimit(cfg.Server.BodyLimit))
+	// Body limit middleware, following BodyLimit changes
+	e.Use(liveBodyLimit(live))
 
 	// CORS configuration
 	if cfg.Server.EnableCORS {
 		if embeddedMode {
-			// In embedded mode, use config settings
-			origins := strings.Split(cfg.Server.AllowOrigins, ",")
-			for i := range origins {
-				origins[i] = strings.TrimSpace(origins[i])
-			}
-			if len(origins) == 0 || (len(origins) == 1 && origins[0] == "") {
-				origins = []string{"*"}
-			}
+			// In embedded mode, use config settings (read per request so changes apply live)
 			e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-				AllowOrigins: origins,
+				AllowOriginFunc: func(origin string) (bool, error) {
+					return originAllowed(live.Get().Server.AllowOrigins, origin), nil
+				},
 				AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-				AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+				AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Auth-Token", api.WorkspaceHeader},
 			}))
 		} else {
 			// Development mode - only allow localhost
@@ -172,16 +216,20 @@ func main() {
 					"http://localhost:3000", "http://127.0.0.1:3000",
 				},
 				AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-				AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
+				AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Auth-Token", api.WorkspaceHeader},
 			}))
 		}
 	}
 
+	// Authentication and role checks (after CORS so rejected requests keep CORS headers)
+	e.Use(api.AuthMiddleware(authenticator))
+
 	// API Routes - using the new modular handler structure
 	apiGroup := e.Group("/api")
 
 	// Health check
 	apiGroup.GET("/health", handlers.Health.HandleHealth)
+	apiGroup.GET("/health/resources", handlers.Health.HandleResources)
 
 	// WebSocket endpoint (uses legacy handler)
 	apiGroup.GET("/ws/uploads", wsHandler.HandleWebSocket)
@@ -201,9 +249,19 @@ func main() {
 	}
 	apiGroup.PUT("/files/:id", handlers.Upload.HandleRenameFile)
 
+	// Annotation routes (persistent bookmarks/notes keyed by file ID)
+	apiGroup.GET("/files/:id/annotations", handlers.Annotation.HandleListAnnotations)
+	apiGroup.POST("/files/:id/annotations", handlers.Annotation.HandleCreateAnnotation)
+	apiGroup.PUT("/files/:id/annotations/:annotationId", handlers.Annotation.HandleUpdateAnnotation)
+	apiGroup.DELETE("/files/:id/annotations/:annotationId", handlers.Annotation.HandleDeleteAnnotation)
+
 	// Parse management routes (new handlers)
 	apiGroup.POST("/parse", handlers.Parse.HandleStartParse)
 	apiGroup.GET("/parse/:sessionId/status", handlers.Parse.HandleParseStatus)
+	apiGroup.DELETE("/parse/:sessionId", handlers.Parse.HandleCancelParse)
+	apiGroup.POST("/parse/:sessionId/pause", handlers.Parse.HandlePauseParse)
+	apiGroup.POST("/parse/:sessionId/resume", handlers.Parse.HandleResumeParse)
+	apiGroup.PUT("/parse/:sessionId/priority", handlers.Parse.HandleParsePriority)
 	apiGroup.GET("/parse/:sessionId/progress", handlers.Parse.HandleParseProgressStream)
 	apiGroup.GET("/parse/:sessionId/entries", handlers.Parse.HandleParseEntries)
 	apiGroup.GET("/parse/:sessionId/entries/msgpack", handlers.Parse.HandleParseEntriesMsgpack)
@@ -217,6 +275,33 @@ func main() {
 	apiGroup.GET("/parse/:sessionId/index-of-time", handlers.Parse.HandleGetIndexByTime)
 	apiGroup.GET("/parse/:sessionId/time-tree", handlers.Parse.HandleGetTimeTree)
 	apiGroup.POST("/parse/:sessionId/keepalive", handlers.Parse.HandleSessionKeepAlive)
+	apiGroup.GET("/parse/:sessionId/annotations", handlers.Annotation.HandleGetSessionAnnotations)
+	apiGroup.GET("/parse/:sessionId/export", handlers.Parse.HandleExportEntries)
+	apiGroup.GET("/parse/:sessionId/export/:exportId", handlers.Parse.HandleExportProgress)
+
+	// Analysis routes (computed in DuckDB)
+	apiGroup.POST("/parse/:sessionId/transitions", handlers.Analysis.HandleGetTransitions)
+	apiGroup.GET("/parse/:sessionId/signals/stats", handlers.Analysis.HandleGetSignalStats)
+	apiGroup.GET("/parse/:sessionId/waveform", handlers.Analysis.HandleGetWaveform)
+	apiGroup.GET("/parse/:sessionId/edges", handlers.Analysis.HandleGetEdges)
+	apiGroup.GET("/parse/:sessionId/edges/:direction", handlers.Analysis.HandleFindEdge)
+	apiGroup.POST("/parse/:sessionId/sequences", handlers.Analysis.HandleFindSequences)
+	apiGroup.POST("/parse/:sessionId/correlations", handlers.Analysis.HandleFindCorrelations)
+	apiGroup.GET("/parse/:sessionId/anomalies", handlers.Analysis.HandleGetAnomalies)
+	apiGroup.GET("/parse/:sessionId/diff/:otherId", handlers.Analysis.HandleDiffSessions)
+	apiGroup.GET("/parse/:sessionId/diff/:otherId/ranges", handlers.Analysis.HandleDiffSessionSignal)
+	apiGroup.POST("/parse/:sessionId/sql", handlers.Analysis.HandleRunSQL)
+	apiGroup.DELETE("/parse/:sessionId/sql/:queryId", handlers.Analysis.HandleCancelSQL)
+	apiGroup.GET("/parse/:sessionId/derived", handlers.Analysis.HandleListDerivedSignals)
+	apiGroup.POST("/parse/:sessionId/derived", handlers.Analysis.HandleSetDerivedSignal)
+	apiGroup.DELETE("/parse/:sessionId/derived/:key", handlers.Analysis.HandleDeleteDerivedSignal)
+
+	// Saved filter preset routes (global or per workspace)
+	apiGroup.GET("/presets", handlers.Preset.HandleListPresets)
+	apiGroup.POST("/presets", handlers.Preset.HandleCreatePreset)
+	apiGroup.GET("/presets/:presetId", handlers.Preset.HandleGetPreset)
+	apiGroup.PUT("/presets/:presetId", handlers.Preset.HandleUpdatePreset)
+	apiGroup.DELETE("/presets/:presetId", handlers.Preset.HandleDeletePreset)
 
 	// Map Layout routes (new handlers)
 	apiGroup.GET("/map/layout", handlers.Map.HandleGetMapLayout)
@@ -239,6 +324,20 @@ func main() {
 	apiGroup.GET("/config/validation-rules", handlers.Map.HandleGetValidationRules)
 	apiGroup.PUT("/config/validation-rules", handlers.Map.HandleUpdateValidationRules)
 
+	// Administration routes
+	apiGroup.GET("/admin/config", handlers.Admin.HandleGetConfig)
+	apiGroup.PUT("/admin/config", handlers.Admin.HandleUpdateConfig)
+	apiGroup.GET("/admin/audit", handlers.Admin.HandleGetAudit)
+	apiGroup.GET("/admin/users", handlers.Auth.HandleListUsers)
+	apiGroup.POST("/admin/users", handlers.Auth.HandleCreateUser)
+	apiGroup.PUT("/admin/users/:username", handlers.Auth.HandleUpdateUser)
+	apiGroup.DELETE("/admin/users/:username", handlers.Auth.HandleDeleteUser)
+
+	// Authentication routes
+	apiGroup.POST("/auth/login", handlers.Auth.HandleLogin)
+	apiGroup.POST("/auth/logout", handlers.Auth.HandleLogout)
+	apiGroup.GET("/auth/me", handlers.Auth.HandleMe)
+
 	// Register embedded frontend if available
 	if embeddedMode {
 		if err := web.RegisterStaticRoutes(e); err != nil {
@@ -284,3 +383,70 @@ func main() {
 
 	e.Logger.Fatal(e.StartServer(s))
 }
+
+// applyResourceLimits sets the DuckDB budget shared by all sessions
+func applyResourceLimits(cfg *config.AppConfig) {
+	memoryLimit, err := config.ParseByteSize(cfg.Advanced.DuckDBMemoryLimit)
+	if err != nil {
+		fmt.Printf("Invalid DuckDBMemoryLimit, using the default: %v\n", err)
+	}
+	storeMemory, err := config.ParseByteSize(cfg.Processing.MaxMemoryPerSession)
+	if err != nil {
+		fmt.Printf("Invalid MaxMemoryPerSession, using the default: %v\n", err)
+	}
+	parser.SetResourceLimits(parser.ResourceLimits{
+		MemoryLimit:     memoryLimit,
+		MaxStoreMemory:  storeMemory,
+		Threads:         cfg.Advanced.DuckDBThreads,
+		QueriesPerStore: cfg.Advanced.DuckDBQueriesPerSession,
+	})
+}
+
+// applySessionConfig sets the processing options of the session manager
+func applySessionConfig(mgr *session.Manager, cfg *config.AppConfig) {
+	mgr.SetAutoDetectAnomalies(cfg.Processing.AutoDetectAnomalies)
+	mgr.SetBuildSearchIndex(cfg.Processing.BuildSearchIndex)
+	mgr.SetMaxConcurrentParses(cfg.Processing.MaxConcurrentParses)
+	mgr.SetMaxSessions(cfg.Processing.MaxSessions)
+}
+
+// originAllowed reports whether origin is in the comma separated list; an empty list or
+// "*" allows every origin
+func originAllowed(allowed, origin string) bool {
+	if strings.TrimSpace(allowed) == "" {
+		return true
+	}
+	for _, o := range strings.Split(allowed, ",") {
+		if o = strings.TrimSpace(o); o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// liveBodyLimit applies the current BodyLimit, rebuilding the limit middleware when it
+// changes; an empty or invalid limit does not restrict bodies
+func liveBodyLimit(live *config.Live) echo.MiddlewareFunc {
+	var mu sync.Mutex
+	var built bool
+	var limit string
+	var limiter echo.MiddlewareFunc
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			current := live.Get().Server.BodyLimit
+			mu.Lock()
+			if !built || current != limit {
+				built, limit, limiter = true, current, nil
+				if n, err := config.ParseByteSize(current); err == nil && n > 0 {
+					limiter = middleware.BodyLimit(fmt.Sprintf("%dB", n))
+				}
+			}
+			mw := limiter
+			mu.Unlock()
+			if mw == nil {
+				return next(c)
+			}
+			return mw(next)(c)
+		}
+	}
+}
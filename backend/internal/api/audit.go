@@ -0,0 +1,22 @@
+// audit.go - Recording user actions in the audit log
+package api
+
+import (
+	"fmt"
+
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/auth"
+)
+
+// recordAudit appends an entry for an action of id (auth.Anonymous when nil). A failed
+// write is logged but does not fail the request, whose action already took place.
+func recordAudit(log *audit.Log, id *auth.Identity, e audit.Entry) {
+	if id == nil {
+		id = auth.Anonymous
+	}
+	e.Actor = id.Name
+	e.Role = string(id.Role)
+	if err := log.Record(e); err != nil {
+		fmt.Printf("[Audit] Failed to record %s of %s: %v\n", e.Action, e.Target, err)
+	}
+}
@@ -45,6 +45,24 @@ func NewValidationError(field string) *APIError {
 	}
 }
 
+// NewUnauthorizedError creates a 401 Unauthorized error
+func NewUnauthorizedError(message string) *APIError {
+	return &APIError{
+		Status:  http.StatusUnauthorized,
+		Code:    "UNAUTHORIZED",
+		Message: message,
+	}
+}
+
+// NewForbiddenError creates a 403 Forbidden error
+func NewForbiddenError(message string) *APIError {
+	return &APIError{
+		Status:  http.StatusForbidden,
+		Code:    "FORBIDDEN",
+		Message: message,
+	}
+}
+
 // NewNotFoundError creates a 404 Not Found error
 func NewNotFoundError(resource string, id string) *APIError {
 	return &APIError{
@@ -76,6 +94,15 @@ func NewInternalError(message string, cause error) *APIError {
 	return err
 }
 
+// NewTimeoutError creates a 408 Request Timeout error
+func NewTimeoutError(message string) *APIError {
+	return &APIError{
+		Status:  http.StatusRequestTimeout,
+		Code:    "TIMEOUT",
+		Message: message,
+	}
+}
+
 // NewServiceUnavailableError creates a 503 Service Unavailable error
 func NewServiceUnavailableError(message string) *APIError {
 	return &APIError{
@@ -0,0 +1,135 @@
+// handlers_admin.go - Server administration handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/config"
+)
+
+// AdminHandlerImpl implements the AdminHandler interface
+type AdminHandlerImpl struct {
+	config *config.Live
+	audit  *audit.Log
+}
+
+// NewAdminHandler creates a new admin handler instance
+func NewAdminHandler(cfg *config.Live, auditLog *audit.Log) AdminHandler {
+	return &AdminHandlerImpl{
+		config: cfg,
+		audit:  auditLog,
+	}
+}
+
+// configResponse is the effective configuration with the fields that are saved but not
+// effective until the server restarts
+type configResponse struct {
+	Config          *config.AppConfig `json:"config"`
+	Applied         []string          `json:"applied,omitempty"`
+	RestartRequired []string          `json:"restartRequired"`
+}
+
+// redactedSecret replaces secrets in configuration responses. Sending it back in an
+// update keeps the current value.
+const redactedSecret = "********"
+
+// redactConfig hides the secrets of a configuration copy
+func redactConfig(cfg *config.AppConfig) *config.AppConfig {
+	if cfg.Security.AuthToken != "" {
+		cfg.Security.AuthToken = redactedSecret
+	}
+	return cfg
+}
+
+// HandleGetConfig returns the effective configuration with secrets redacted
+func (h *AdminHandlerImpl) HandleGetConfig(c echo.Context) error {
+	return c.JSON(http.StatusOK, configResponse{
+		Config:          redactConfig(h.config.Get()),
+		RestartRequired: h.config.RestartRequired(),
+	})
+}
+
+// HandleUpdateConfig validates and saves a configuration. Fields missing from the body
+// keep their current value, as does a secret sent back redacted; hot fields apply
+// immediately and the response lists those that need a restart.
+func (h *AdminHandlerImpl) HandleUpdateConfig(c echo.Context) error {
+	current := h.config.Get()
+	next := h.config.Get()
+	if err := c.Bind(next); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if next.Security.AuthToken == redactedSecret {
+		next.Security.AuthToken = current.Security.AuthToken
+	}
+
+	change, err := h.config.Update(next)
+	if errors.Is(err, config.ErrInvalidConfig) {
+		return NewBadRequestError("invalid configuration", err)
+	}
+	if err != nil {
+		return NewInternalError("failed to save configuration", err)
+	}
+
+	return c.JSON(http.StatusOK, configResponse{
+		Config:          redactConfig(h.config.Get()),
+		Applied:         change.Applied,
+		RestartRequired: change.RestartRequired,
+	})
+}
+
+// HandleGetAudit returns the audit log entries between from (inclusive) and to
+// (exclusive), optionally filtered by actor, action and target, as JSON or with
+// format=csv as a CSV download
+func (h *AdminHandlerImpl) HandleGetAudit(c echo.Context) error {
+	filter := audit.Filter{
+		Actor:  c.QueryParam("actor"),
+		Action: c.QueryParam("action"),
+		Target: c.QueryParam("target"),
+	}
+	var err error
+	if filter.From, err = parseTimeParam(c.QueryParam("from")); err != nil {
+		return NewBadRequestError("invalid from", err)
+	}
+	if filter.To, err = parseTimeParam(c.QueryParam("to")); err != nil {
+		return NewBadRequestError("invalid to", err)
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			return NewValidationError("limit")
+		}
+	}
+
+	entries, err := h.audit.Query(filter)
+	if err != nil {
+		return NewInternalError("failed to read audit log", err)
+	}
+
+	switch c.QueryParam("format") {
+	case "", "json":
+		return c.JSON(http.StatusOK, entries)
+	case "csv":
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit.csv"`)
+		res.WriteHeader(http.StatusOK)
+		return audit.WriteCSV(res, entries)
+	default:
+		return NewValidationError("format")
+	}
+}
+
+// parseTimeParam parses an RFC 3339 time or Unix milliseconds; empty means unset.
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,185 @@
+// handlers_admin_test.go - Tests for admin handlers
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/auth"
+	"github.com/plc-visualizer/backend/internal/config"
+)
+
+func TestAdminHandler_Config(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.config")
+	live := config.NewLive(config.DefaultConfig(), path)
+	var applied *config.AppConfig
+	live.OnChange(func(c *config.AppConfig) { applied = c })
+	handler := NewAdminHandler(live, nil)
+	e := echo.New()
+
+	put := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		return rec, handler.HandleUpdateConfig(e.NewContext(req, rec))
+	}
+
+	// Partial update of a hot field
+	rec, err := put(`{"Processing": {"MaxSessions": 20}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp configResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if resp.Config.Processing.MaxSessions != 20 || resp.Config.Processing.MaxConcurrentParses != 3 {
+		t.Errorf("expected MaxSessions 20 and other fields kept, got %+v", resp.Config.Processing)
+	}
+	if len(resp.Applied) != 1 || resp.Applied[0] != "Processing.MaxSessions" || len(resp.RestartRequired) != 0 {
+		t.Errorf("expected MaxSessions applied live, got %+v", resp)
+	}
+	if applied == nil || applied.Processing.MaxSessions != 20 {
+		t.Error("expected change listener to receive the new config")
+	}
+
+	// Fields that need a restart are saved and reported
+	if _, err := put(`{"Server": {"Port": 9100}}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved, err := config.LoadConfig(path)
+	if err != nil || saved.Server.Port != 9100 {
+		t.Fatalf("expected saved port 9100, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	rec = httptest.NewRecorder()
+	if err := handler.HandleGetConfig(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp = configResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.RestartRequired) != 1 || resp.RestartRequired[0] != "Server.Port" {
+		t.Errorf("expected Server.Port to need a restart, got %v", resp.RestartRequired)
+	}
+
+	// Invalid values are rejected and leave the config unchanged
+	_, err = put(`{"Server": {"Port": 0}, "Processing": {"CompressionLevel": 12}}`)
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Status != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %v", err)
+	}
+	if !strings.Contains(apiErr.Details, "Server.Port") || !strings.Contains(apiErr.Details, "Processing.CompressionLevel") {
+		t.Errorf("expected both invalid fields in details, got %q", apiErr.Details)
+	}
+	if live.Get().Server.Port != 9100 {
+		t.Error("expected rejected update to leave the config unchanged")
+	}
+
+	// Secrets are redacted in responses and kept when sent back redacted
+	rec, err = put(`{"Security": {"AuthToken": "s3cret"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Errorf("expected the token to be redacted, got %s", rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/config", nil)
+	rec = httptest.NewRecorder()
+	handler.HandleGetConfig(e.NewContext(req, rec))
+	if strings.Contains(rec.Body.String(), "s3cret") || !strings.Contains(rec.Body.String(), redactedSecret) {
+		t.Errorf("expected the token to be redacted, got %s", rec.Body.String())
+	}
+	resp = configResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	body, _ := json.Marshal(resp.Config)
+	if _, err := put(string(body)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if live.Get().Security.AuthToken != "s3cret" {
+		t.Errorf("expected the redacted token to keep its value, got %q", live.Get().Security.AuthToken)
+	}
+
+	// The log level is not applied at runtime
+	rec, err = put(`{"Advanced": {"LogLevel": "debug"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp = configResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Applied) != 0 || len(resp.RestartRequired) != 2 || resp.RestartRequired[1] != "Advanced.LogLevel" {
+		t.Errorf("expected Advanced.LogLevel to need a restart, got %+v", resp)
+	}
+}
+
+func TestAdminHandler_Audit(t *testing.T) {
+	deps, handlers, e := setupTestHandlers(t)
+	analyst := &auth.Identity{Name: "alice", Role: auth.RoleAnalyst}
+
+	call := func(h echo.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(identityKey, analyst)
+		if len(params) == 2 {
+			c.SetParamNames(params[0])
+			c.SetParamValues(params[1])
+		}
+		if err := h(c); err != nil {
+			t.Fatalf("%s %s failed: %v", method, target, err)
+		}
+		return rec
+	}
+
+	rec := call(handlers.Upload.HandleUploadFile, http.MethodPost, "/api/files/upload", `{"name":"line.log","data":"bGluZQ=="}`)
+	var info struct{ ID string }
+	json.Unmarshal(rec.Body.Bytes(), &info)
+	call(handlers.Upload.HandleRenameFile, http.MethodPut, "/api/files/"+info.ID, `{"name":"renamed.log"}`, "id", info.ID)
+	call(handlers.Map.HandleUploadMapRules, http.MethodPost, "/api/map/rules?workspace=line-1", `{"name":"rules.yaml","data":"ZGVmYXVsdENvbG9yOiAiI0ZGRkZGRiIK"}`)
+
+	entries, err := deps.Audit.Query(audit.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	rename := entries[1]
+	if rename.Action != audit.ActionFileRename || rename.Actor != "alice" || rename.Target != info.ID ||
+		rename.Before != "line.log" || rename.After != "renamed.log" {
+		t.Errorf("unexpected rename entry %+v", rename)
+	}
+	if rules := entries[2]; rules.Action != audit.ActionRulesUpload || rules.Workspace != "line-1" || rules.After == "" {
+		t.Errorf("unexpected rules entry %+v", rules)
+	}
+
+	// Time and action filters
+	rec = call(handlers.Admin.HandleGetAudit, http.MethodGet, "/api/admin/audit?action=file.&to="+strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10), "")
+	var filtered []audit.Entry
+	json.Unmarshal(rec.Body.Bytes(), &filtered)
+	if len(filtered) != 2 {
+		t.Errorf("expected the 2 file entries, got %+v", filtered)
+	}
+	rec = call(handlers.Admin.HandleGetAudit, http.MethodGet, "/api/admin/audit?from="+time.Now().Add(time.Minute).Format(time.RFC3339), "")
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected no entries after now, got %s", rec.Body.String())
+	}
+
+	// CSV export
+	rec = call(handlers.Admin.HandleGetAudit, http.MethodGet, "/api/admin/audit?format=csv&actor=alice", "")
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), "text/csv") || len(lines) != 4 ||
+		!strings.Contains(lines[2], "line.log,renamed.log") {
+		t.Errorf("unexpected CSV export:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,503 @@
+// handlers_analysis.go - Server-side analysis handlers (transitions, statistics, ...)
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/parser"
+	"github.com/plc-visualizer/backend/internal/session"
+)
+
+// AnalysisHandlerImpl implements the AnalysisHandler interface
+type AnalysisHandlerImpl struct {
+	sessionMgr SessionManager
+}
+
+// NewAnalysisHandler creates a new analysis handler instance
+func NewAnalysisHandler(sessionMgr SessionManager) AnalysisHandler {
+	return &AnalysisHandlerImpl{
+		sessionMgr: sessionMgr,
+	}
+}
+
+// HandleGetTransitions evaluates transition/cycle-time rules for a session
+func (h *AnalysisHandlerImpl) HandleGetTransitions(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req parser.TransitionQuery
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if len(req.Rules) == 0 {
+		return NewValidationError("rules")
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 1000 {
+		req.PageSize = 100
+	}
+
+	report, err := h.sessionMgr.GetTransitions(c.Request().Context(), id, req)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// HandleGetSignalStats returns per-signal statistics for the selected signals (all if none given)
+func (h *AnalysisHandlerImpl) HandleGetSignalStats(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	stats, err := h.sessionMgr.GetSignalStats(c.Request().Context(), id, c.QueryParams()["signals"])
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// HandleGetWaveform returns min/max buckets and boolean spans sized for a pixel width
+func (h *AnalysisHandlerImpl) HandleGetWaveform(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	startTs, err := parseTimestamp(c.QueryParam("start"))
+	if err != nil {
+		return NewBadRequestError("invalid start time", err)
+	}
+	endTs, err := parseTimestamp(c.QueryParam("end"))
+	if err != nil {
+		return NewBadRequestError("invalid end time", err)
+	}
+	width, err := strconv.Atoi(c.QueryParam("width"))
+	if err != nil {
+		return NewBadRequestError("invalid width", err)
+	}
+
+	waveform, err := h.sessionMgr.GetWaveform(c.Request().Context(), id, startTs, endTs, width, c.QueryParams()["signals"])
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, waveform)
+}
+
+// HandleGetEdges returns a page of edges (or value changes) of one signal
+func (h *AnalysisHandlerImpl) HandleGetEdges(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	q, err := edgeQueryFromParams(c)
+	if err != nil {
+		return err
+	}
+	if s := c.QueryParam("start"); s != "" {
+		if q.Start, err = parseInt64Param(s); err != nil {
+			return NewBadRequestError("invalid start time", err)
+		}
+	}
+	if s := c.QueryParam("end"); s != "" {
+		if q.End, err = parseInt64Param(s); err != nil {
+			return NewBadRequestError("invalid end time", err)
+		}
+	}
+	q.Page, _ = strconv.Atoi(c.QueryParam("page"))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	q.PageSize, _ = strconv.Atoi(c.QueryParam("pageSize"))
+	if q.PageSize < 1 || q.PageSize > 1000 {
+		q.PageSize = 100
+	}
+
+	edges, err := h.sessionMgr.GetEdges(c.Request().Context(), id, q)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, edges)
+}
+
+// HandleFindEdge returns the next or previous edge of one signal relative to "from" (null if none)
+func (h *AnalysisHandlerImpl) HandleFindEdge(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	direction := c.Param("direction")
+	if direction != "next" && direction != "prev" {
+		return NewBadRequestError("direction must be next or prev", nil)
+	}
+
+	q, err := edgeQueryFromParams(c)
+	if err != nil {
+		return err
+	}
+	from, err := parseInt64Param(c.QueryParam("from"))
+	if err != nil {
+		return NewBadRequestError("invalid from time", err)
+	}
+
+	edge, err := h.sessionMgr.FindEdge(c.Request().Context(), id, q, from, direction == "next")
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, edge)
+}
+
+// HandleFindSequences returns the occurrences of an ordered step pattern
+func (h *AnalysisHandlerImpl) HandleFindSequences(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req parser.SequenceQuery
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if len(req.Steps) == 0 {
+		return NewValidationError("steps")
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 1000 {
+		req.PageSize = 100
+	}
+
+	result, err := h.sessionMgr.FindSequences(c.Request().Context(), id, req)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// HandleFindCorrelations ranks signals by how consistently they change before a target signal
+func (h *AnalysisHandlerImpl) HandleFindCorrelations(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req parser.CorrelationQuery
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.Target == "" {
+		return NewValidationError("target")
+	}
+	if req.Window <= 0 {
+		req.Window = 5000
+	}
+	if req.Limit < 1 || req.Limit > 500 {
+		req.Limit = 50
+	}
+
+	result, err := h.sessionMgr.FindCorrelations(c.Request().Context(), id, req)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// HandleGetAnomalies returns a page of stuck, chattering, stopped and silent signal anomalies.
+// Detection thresholds can be overridden with query parameters named after AnomalyConfig fields.
+func (h *AnalysisHandlerImpl) HandleGetAnomalies(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	q := parser.AnomalyQuery{
+		Type:     parser.AnomalyType(c.QueryParam("type")),
+		Severity: parser.AnomalySeverity(c.QueryParam("severity")),
+		DeviceID: c.QueryParam("device"),
+	}
+	q.Config.StuckMinSamples, _ = strconv.Atoi(c.QueryParam("stuckMinSamples"))
+	q.Config.ChatterWindow, _ = strconv.ParseInt(c.QueryParam("chatterWindow"), 10, 64)
+	q.Config.ChatterMinToggles, _ = strconv.Atoi(c.QueryParam("chatterMinToggles"))
+	q.Config.HeartbeatMinChanges, _ = strconv.Atoi(c.QueryParam("heartbeatMinChanges"))
+	q.Config.DeviceMinEntries, _ = strconv.Atoi(c.QueryParam("deviceMinEntries"))
+	q.Config.MinGap, _ = strconv.ParseInt(c.QueryParam("minGap"), 10, 64)
+	q.Config.GapFactor, _ = strconv.ParseFloat(c.QueryParam("gapFactor"), 64)
+	q.Page, _ = strconv.Atoi(c.QueryParam("page"))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	q.PageSize, _ = strconv.Atoi(c.QueryParam("pageSize"))
+	if q.PageSize < 1 || q.PageSize > 1000 {
+		q.PageSize = 100
+	}
+
+	report, err := h.sessionMgr.GetAnomalies(c.Request().Context(), id, q)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// HandleDiffSessions compares every signal of a session with another session
+func (h *AnalysisHandlerImpl) HandleDiffSessions(c echo.Context) error {
+	id, otherID := c.Param("sessionId"), c.Param("otherId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+	if otherID == "" {
+		return NewValidationError("otherId")
+	}
+
+	q := parser.DiffQuery{OnlyDiffering: c.QueryParam("onlyDiffering") == "true"}
+	var err error
+	if s := c.QueryParam("offset"); s != "" {
+		if q.Offset, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return NewBadRequestError("invalid offset", err)
+		}
+	}
+	if s := c.QueryParam("tolerance"); s != "" {
+		if q.Tolerance, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return NewBadRequestError("invalid tolerance", err)
+		}
+	}
+	q.Page, _ = strconv.Atoi(c.QueryParam("page"))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	q.PageSize, _ = strconv.Atoi(c.QueryParam("pageSize"))
+	if q.PageSize < 1 || q.PageSize > 1000 {
+		q.PageSize = 100
+	}
+
+	diff, err := h.sessionMgr.DiffSessions(c.Request().Context(), id, otherID, q)
+	if err != nil {
+		return analysisError(err, id+", "+otherID)
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+// HandleDiffSessionSignal returns the time ranges where one signal differs between two sessions
+func (h *AnalysisHandlerImpl) HandleDiffSessionSignal(c echo.Context) error {
+	id, otherID := c.Param("sessionId"), c.Param("otherId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+	if otherID == "" {
+		return NewValidationError("otherId")
+	}
+	signal := c.QueryParam("signal")
+	if signal == "" {
+		return NewValidationError("signal")
+	}
+
+	var offset int64
+	var err error
+	if s := c.QueryParam("offset"); s != "" {
+		if offset, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return NewBadRequestError("invalid offset", err)
+		}
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 10000 {
+		limit = 1000
+	}
+
+	detail, err := h.sessionMgr.DiffSessionSignal(c.Request().Context(), id, otherID, signal, offset, limit)
+	if err != nil {
+		return analysisError(err, id+", "+otherID)
+	}
+
+	return c.JSON(http.StatusOK, detail)
+}
+
+// HandleRunSQL runs a read-only SELECT against a session's entries table and returns
+// the result in columnar form. A client-chosen queryId allows cancelling it via HandleCancelSQL.
+func (h *AnalysisHandlerImpl) HandleRunSQL(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req struct {
+		parser.SQLQuery
+		QueryID string `json:"queryId"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.SQL == "" {
+		return NewValidationError("sql")
+	}
+
+	if wantsArrow(c) {
+		return h.runSQLArrow(c, id, req.QueryID, req.SQLQuery)
+	}
+
+	result, err := h.sessionMgr.RunSQL(c.Request().Context(), id, req.QueryID, req.SQLQuery)
+	if err != nil {
+		return sqlError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// runSQLArrow answers a console query with an Arrow IPC stream; row count and truncation
+// are reported in the X-Row-Count and X-Truncated headers
+func (h *AnalysisHandlerImpl) runSQLArrow(c echo.Context, id, queryID string, q parser.SQLQuery) error {
+	var buf bytes.Buffer
+	n, truncated, err := h.sessionMgr.RunSQLArrow(c.Request().Context(), id, queryID, &buf, q)
+	if err != nil {
+		return sqlError(err, id)
+	}
+
+	c.Response().Header().Set("X-Row-Count", strconv.Itoa(n))
+	c.Response().Header().Set("X-Truncated", strconv.FormatBool(truncated))
+	return c.Blob(http.StatusOK, parser.ArrowStreamMIME, buf.Bytes())
+}
+
+// sqlError maps console query errors to API errors
+func sqlError(err error, id string) error {
+	switch {
+	case errors.Is(err, parser.ErrQueryTimeout):
+		return NewTimeoutError("query exceeded its timeout")
+	case errors.Is(err, context.Canceled):
+		return NewConflictError("query was cancelled")
+	case errors.Is(err, parser.ErrInvalidQuery):
+		return NewBadRequestError("invalid SQL query", err)
+	default:
+		return analysisError(err, id)
+	}
+}
+
+// HandleCancelSQL cancels a running SQL console query
+func (h *AnalysisHandlerImpl) HandleCancelSQL(c echo.Context) error {
+	id, queryID := c.Param("sessionId"), c.Param("queryId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+	if queryID == "" {
+		return NewValidationError("queryId")
+	}
+
+	if err := h.sessionMgr.CancelSQL(id, queryID); err != nil {
+		if errors.Is(err, session.ErrQueryNotFound) {
+			return NewNotFoundError("query", queryID)
+		}
+		return NewInternalError("failed to cancel query", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleListDerivedSignals returns the derived signals of a session
+func (h *AnalysisHandlerImpl) HandleListDerivedSignals(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	defs, err := h.sessionMgr.ListDerivedSignals(id)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, defs)
+}
+
+// HandleSetDerivedSignal adds or replaces a derived signal defined by an expression
+func (h *AnalysisHandlerImpl) HandleSetDerivedSignal(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req parser.DerivedSignal
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.Name == "" {
+		return NewValidationError("name")
+	}
+	if req.Expression == "" {
+		return NewValidationError("expression")
+	}
+
+	def, err := h.sessionMgr.SetDerivedSignal(c.Request().Context(), id, req)
+	if err != nil {
+		return analysisError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, def)
+}
+
+// HandleDeleteDerivedSignal removes a derived signal by key (deviceId::name)
+func (h *AnalysisHandlerImpl) HandleDeleteDerivedSignal(c echo.Context) error {
+	id := c.Param("sessionId")
+	key := c.Param("key")
+	if id == "" || key == "" {
+		return NewValidationError("sessionId/key")
+	}
+
+	if err := h.sessionMgr.DeleteDerivedSignal(id, key); err != nil {
+		if errors.Is(err, session.ErrDerivedSignalNotFound) {
+			return NewNotFoundError("derived signal", key)
+		}
+		return analysisError(err, id)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Helper functions
+
+// edgeQueryFromParams reads the signal, edge type and optional value condition of an edge query
+func edgeQueryFromParams(c echo.Context) (parser.EdgeQuery, error) {
+	q := parser.EdgeQuery{
+		Signal:    c.QueryParam("signal"),
+		Type:      parser.EdgeType(c.QueryParam("type")),
+		Condition: parser.ConditionType(c.QueryParam("condition")),
+	}
+	if q.Signal == "" {
+		return q, NewValidationError("signal")
+	}
+	if c.QueryParams().Has("value") {
+		q.Value = c.QueryParam("value")
+	}
+	return q, nil
+}
+
+// analysisError maps session and query errors to API errors
+func analysisError(err error, sessionID string) error {
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound):
+		return NewNotFoundError("session", sessionID)
+	case errors.Is(err, session.ErrSessionNotReady):
+		return NewConflictError("session is not ready for queries")
+	case errors.Is(err, parser.ErrInvalidQuery):
+		return NewBadRequestError("invalid analysis request", err)
+	default:
+		return NewInternalError("analysis query failed", err)
+	}
+}
@@ -0,0 +1,238 @@
+// handlers_annotation.go - Persistent annotation (bookmark/note) handlers
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/models"
+	"github.com/plc-visualizer/backend/internal/session"
+	"github.com/plc-visualizer/backend/internal/storage"
+)
+
+// AnnotationHandlerImpl implements the AnnotationHandler interface
+type AnnotationHandlerImpl struct {
+	store      storage.Store
+	sessionMgr *session.Manager
+}
+
+// NewAnnotationHandler creates a new annotation handler instance
+func NewAnnotationHandler(store storage.Store, sessionMgr *session.Manager) AnnotationHandler {
+	return &AnnotationHandlerImpl{
+		store:      store,
+		sessionMgr: sessionMgr,
+	}
+}
+
+// HandleListAnnotations returns the annotations of a file, optionally filtered by time range and tag
+func (h *AnnotationHandlerImpl) HandleListAnnotations(c echo.Context) error {
+	fileID := c.Param("id")
+	if err := h.requireFile(fileID); err != nil {
+		return err
+	}
+
+	filter, err := buildAnnotationFilter(c)
+	if err != nil {
+		return err
+	}
+
+	annotations, err := h.sessionMgr.ListAnnotations(fileID, filter)
+	if err != nil {
+		return NewInternalError("failed to load annotations", err)
+	}
+
+	return c.JSON(http.StatusOK, annotations)
+}
+
+// HandleCreateAnnotation adds an annotation to a file
+func (h *AnnotationHandlerImpl) HandleCreateAnnotation(c echo.Context) error {
+	fileID := c.Param("id")
+	if err := h.requireFile(fileID); err != nil {
+		return err
+	}
+
+	var req annotationRequest
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	annotation, err := h.sessionMgr.AddAnnotation(fileID, req.toModel())
+	if err != nil {
+		return NewInternalError("failed to save annotation", err)
+	}
+
+	return c.JSON(http.StatusCreated, annotation)
+}
+
+// HandleUpdateAnnotation replaces an existing annotation of a file
+func (h *AnnotationHandlerImpl) HandleUpdateAnnotation(c echo.Context) error {
+	fileID := c.Param("id")
+	annotationID := c.Param("annotationId")
+	if err := h.requireFile(fileID); err != nil {
+		return err
+	}
+	if annotationID == "" {
+		return NewValidationError("annotationId")
+	}
+
+	var req annotationRequest
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	if _, err := h.sessionMgr.GetAnnotation(fileID, annotationID); err != nil {
+		return NewNotFoundError("annotation", annotationID)
+	}
+
+	annotation, err := h.sessionMgr.UpdateAnnotation(fileID, annotationID, req.toModel())
+	if err != nil {
+		return NewInternalError("failed to save annotation", err)
+	}
+
+	return c.JSON(http.StatusOK, annotation)
+}
+
+// HandleDeleteAnnotation removes an annotation from a file
+func (h *AnnotationHandlerImpl) HandleDeleteAnnotation(c echo.Context) error {
+	fileID := c.Param("id")
+	annotationID := c.Param("annotationId")
+	if err := h.requireFile(fileID); err != nil {
+		return err
+	}
+	if annotationID == "" {
+		return NewValidationError("annotationId")
+	}
+
+	if _, err := h.sessionMgr.GetAnnotation(fileID, annotationID); err != nil {
+		return NewNotFoundError("annotation", annotationID)
+	}
+
+	if err := h.sessionMgr.DeleteAnnotation(fileID, annotationID); err != nil {
+		return NewInternalError("failed to delete annotation", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleGetSessionAnnotations returns the annotations of all source files of a session
+// within a time range, for overlaying on the waveform and log table
+func (h *AnnotationHandlerImpl) HandleGetSessionAnnotations(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	filter, err := buildAnnotationFilter(c)
+	if err != nil {
+		return err
+	}
+
+	annotations, ok := h.sessionMgr.GetSessionAnnotations(id, filter)
+	if !ok {
+		return NewNotFoundError("session", id)
+	}
+
+	return c.JSON(http.StatusOK, annotations)
+}
+
+// Request types
+
+type annotationRequest struct {
+	Kind       models.AnnotationKind `json:"kind"`
+	StartTime  int64                 `json:"startTime"`
+	EndTime    int64                 `json:"endTime"`
+	DeviceID   string                `json:"deviceId"`
+	SignalName string                `json:"signalName"`
+	Text       string                `json:"text"`
+	Author     string                `json:"author"`
+	Tags       []string              `json:"tags"`
+	Color      string                `json:"color"`
+}
+
+func (r *annotationRequest) validate() error {
+	if r.Kind == "" {
+		r.Kind = models.AnnotationKindPoint
+		if r.EndTime > r.StartTime {
+			r.Kind = models.AnnotationKindRange
+		}
+	}
+	if r.StartTime <= 0 {
+		return NewValidationError("startTime")
+	}
+
+	switch r.Kind {
+	case models.AnnotationKindPoint:
+		r.EndTime = 0
+	case models.AnnotationKindRange:
+		if r.EndTime < r.StartTime {
+			return NewValidationError("endTime")
+		}
+	case models.AnnotationKindEntry:
+		if r.DeviceID == "" {
+			return NewValidationError("deviceId")
+		}
+		if r.SignalName == "" {
+			return NewValidationError("signalName")
+		}
+		r.EndTime = 0
+	default:
+		return NewValidationError("kind")
+	}
+	return nil
+}
+
+func (r *annotationRequest) toModel() models.Annotation {
+	return models.Annotation{
+		Kind:       r.Kind,
+		StartTime:  r.StartTime,
+		EndTime:    r.EndTime,
+		DeviceID:   r.DeviceID,
+		SignalName: r.SignalName,
+		Text:       r.Text,
+		Author:     r.Author,
+		Tags:       r.Tags,
+		Color:      r.Color,
+	}
+}
+
+// Helper functions
+
+// requireFile checks that fileID names an uploaded file, so annotations are never stored
+// for (or read from sidecars of) files that do not exist.
+func (h *AnnotationHandlerImpl) requireFile(fileID string) error {
+	if fileID == "" {
+		return NewValidationError("id")
+	}
+	if _, err := h.store.Get(fileID); err != nil {
+		return NewNotFoundError("file", fileID)
+	}
+	return nil
+}
+
+func buildAnnotationFilter(c echo.Context) (session.AnnotationFilter, error) {
+	filter := session.AnnotationFilter{
+		Tag:    c.QueryParam("tag"),
+		Author: c.QueryParam("author"),
+	}
+	if s := c.QueryParam("start"); s != "" {
+		start, err := parseInt64Param(s)
+		if err != nil {
+			return filter, NewBadRequestError("invalid start time", err)
+		}
+		filter.Start = start
+	}
+	if s := c.QueryParam("end"); s != "" {
+		end, err := parseInt64Param(s)
+		if err != nil {
+			return filter, NewBadRequestError("invalid end time", err)
+		}
+		filter.End = end
+	}
+	return filter, nil
+}
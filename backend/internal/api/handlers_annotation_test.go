@@ -0,0 +1,89 @@
+// handlers_annotation_test.go - Tests for annotation handlers
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+func TestAnnotationHandlers(t *testing.T) {
+	t.Setenv("PARSED_DB_DIR", filepath.Join(t.TempDir(), "parsed"))
+	deps, handlers, e := setupTestHandlers(t)
+	info, err := deps.Store.Save("line.log", bytes.NewBufferString("log"))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	call := func(handler echo.HandlerFunc, method, body string, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/files/annotations", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "annotationId")
+		c.SetParamValues(append(params, "")[:2]...)
+		if err := handler(c); err != nil {
+			ErrorHandler(err, c)
+		}
+		return rec
+	}
+	h := handlers.Annotation
+
+	t.Run("create on an unknown file", func(t *testing.T) {
+		rec := call(h.HandleCreateAnnotation, http.MethodPost, `{"startTime":1000,"text":"x"}`, "missing")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("create rejects invalid annotations", func(t *testing.T) {
+		rec := call(h.HandleCreateAnnotation, http.MethodPost, `{"kind":"entry","startTime":1000}`, info.ID)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", rec.Code)
+		}
+	})
+
+	var created models.Annotation
+	t.Run("create and list", func(t *testing.T) {
+		rec := call(h.HandleCreateAnnotation, http.MethodPost, `{"startTime":1000,"endTime":2000,"text":"jam","tags":["jam"]}`, info.ID)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if created.ID == "" || created.Kind != models.AnnotationKindRange {
+			t.Fatalf("Expected a range annotation with an ID, got %+v", created)
+		}
+
+		rec = call(h.HandleListAnnotations, http.MethodGet, "", info.ID)
+		var list []models.Annotation
+		json.Unmarshal(rec.Body.Bytes(), &list)
+		if rec.Code != http.StatusOK || len(list) != 1 || list[0].ID != created.ID {
+			t.Errorf("Expected the created annotation, got %d %+v", rec.Code, list)
+		}
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		rec := call(h.HandleUpdateAnnotation, http.MethodPut, `{"startTime":1500,"text":"cleared"}`, info.ID, created.ID)
+		var updated models.Annotation
+		json.Unmarshal(rec.Body.Bytes(), &updated)
+		if rec.Code != http.StatusOK || updated.Text != "cleared" || updated.CreatedAt.IsZero() {
+			t.Errorf("Expected the updated annotation, got %d %+v", rec.Code, updated)
+		}
+
+		if rec := call(h.HandleDeleteAnnotation, http.MethodDelete, "", "missing", created.ID); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 deleting from an unknown file, got %d", rec.Code)
+		}
+		if rec := call(h.HandleDeleteAnnotation, http.MethodDelete, "", info.ID, created.ID); rec.Code != http.StatusNoContent {
+			t.Errorf("Expected 204, got %d", rec.Code)
+		}
+		if rec := call(h.HandleDeleteAnnotation, http.MethodDelete, "", info.ID, created.ID); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 deleting twice, got %d", rec.Code)
+		}
+	})
+}
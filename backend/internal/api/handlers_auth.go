@@ -0,0 +1,142 @@
+// handlers_auth.go - Login and user management handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/auth"
+)
+
+// AuthHandlerImpl implements the AuthHandler interface
+type AuthHandlerImpl struct {
+	auth *auth.Authenticator
+}
+
+// NewAuthHandler creates a new auth handler instance
+func NewAuthHandler(a *auth.Authenticator) AuthHandler {
+	return &AuthHandlerImpl{
+		auth: a,
+	}
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token     string         `json:"token"`
+	Identity  *auth.Identity `json:"identity"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+type userRequest struct {
+	Username string    `json:"username"`
+	Password string    `json:"password"`
+	Role     auth.Role `json:"role"`
+}
+
+// HandleLogin checks a local user's password and returns a token for later requests
+func (h *AuthHandlerImpl) HandleLogin(c echo.Context) error {
+	var req loginRequest
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.Username == "" {
+		return NewValidationError("username")
+	}
+
+	token, id, expires, ok := h.auth.Login(req.Username, req.Password)
+	if !ok {
+		return NewUnauthorizedError("invalid username or password")
+	}
+	return c.JSON(http.StatusOK, loginResponse{Token: token, Identity: id, ExpiresAt: expires})
+}
+
+// HandleLogout invalidates the token of the request
+func (h *AuthHandlerImpl) HandleLogout(c echo.Context) error {
+	h.auth.Logout(RequestToken(c))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleMe returns the identity of the caller and whether authentication is required
+func (h *AuthHandlerImpl) HandleMe(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"identity":     IdentityFrom(c),
+		"authRequired": h.auth.Required(),
+	})
+}
+
+// HandleListUsers returns the local users without password hashes
+func (h *AuthHandlerImpl) HandleListUsers(c echo.Context) error {
+	users, err := h.auth.Users().List()
+	if err != nil {
+		return NewInternalError("failed to load users", err)
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+// HandleCreateUser adds a local user
+func (h *AuthHandlerImpl) HandleCreateUser(c echo.Context) error {
+	var req userRequest
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.Username == "" {
+		return NewValidationError("username")
+	}
+	if req.Password == "" {
+		return NewValidationError("password")
+	}
+	if !req.Role.Valid() {
+		return NewValidationError("role")
+	}
+
+	user, err := h.auth.Users().Add(req.Username, req.Password, req.Role)
+	if errors.Is(err, auth.ErrUserExists) {
+		return NewConflictError("user already exists: " + req.Username)
+	}
+	if err != nil {
+		return NewInternalError("failed to create user", err)
+	}
+	return c.JSON(http.StatusCreated, user)
+}
+
+// HandleUpdateUser changes the role and/or password of a user; its login tokens are revoked
+func (h *AuthHandlerImpl) HandleUpdateUser(c echo.Context) error {
+	username := c.Param("username")
+	var req userRequest
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if req.Role != "" && !req.Role.Valid() {
+		return NewValidationError("role")
+	}
+
+	user, err := h.auth.Users().Update(username, req.Password, req.Role)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		return NewNotFoundError("user", username)
+	}
+	if err != nil {
+		return NewInternalError("failed to update user", err)
+	}
+	h.auth.LogoutUser(username)
+	return c.JSON(http.StatusOK, user)
+}
+
+// HandleDeleteUser removes a user and revokes its login tokens
+func (h *AuthHandlerImpl) HandleDeleteUser(c echo.Context) error {
+	username := c.Param("username")
+	err := h.auth.Users().Delete(username)
+	if errors.Is(err, auth.ErrUserNotFound) {
+		return NewNotFoundError("user", username)
+	}
+	if err != nil {
+		return NewInternalError("failed to delete user", err)
+	}
+	h.auth.LogoutUser(username)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+// handlers_auth_test.go - Tests for authentication middleware and handlers
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/auth"
+)
+
+func newAuthTestServer(t *testing.T) (*echo.Echo, *auth.Authenticator) {
+	t.Helper()
+	a := auth.NewAuthenticator(auth.NewUserStore(t.TempDir()))
+	a.Configure(true, "admin-token, view-token:viewer")
+
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	e.Use(AuthMiddleware(a))
+	ok := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, IdentityFrom(c))
+	}
+	e.GET("/api/health", ok)
+	e.GET("/api/files/recent", ok)
+	e.DELETE("/api/files/:id", ok)
+	e.POST("/api/files/upload", ok)
+	e.GET("/api/ws/uploads", ok)
+
+	h := NewAuthHandler(a)
+	e.POST("/api/auth/login", h.HandleLogin)
+	e.GET("/api/auth/me", h.HandleMe)
+	e.POST("/api/admin/users", h.HandleCreateUser)
+	return e, a
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	e, _ := newAuthTestServer(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		wantStatus int
+	}{
+		{"public health", http.MethodGet, "/api/health", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/api/files/recent", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/api/files/recent", "Bearer nope", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "/api/files/recent", "Bearer view-token", http.StatusOK},
+		{"viewer cannot upload", http.MethodPost, "/api/files/upload", "Bearer view-token", http.StatusForbidden},
+		{"viewer cannot delete", http.MethodDelete, "/api/files/f1", "Bearer view-token", http.StatusForbidden},
+		{"admin deletes", http.MethodDelete, "/api/files/f1", "Bearer admin-token", http.StatusOK},
+		{"websocket token in query", http.MethodGet, "/api/ws/uploads?token=admin-token", "", http.StatusOK},
+		{"websocket viewer refused", http.MethodGet, "/api/ws/uploads?token=view-token", "", http.StatusForbidden},
+		{"query token only on websocket", http.MethodGet, "/api/files/recent?token=admin-token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedactedURI(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/ws/uploads?token=secret&x=1", nil)
+	if uri := RedactedURI(req); strings.Contains(uri, "secret") || !strings.Contains(uri, "x=1") {
+		t.Errorf("Expected the token to be redacted, got %s", uri)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/files/recent?limit=5", nil)
+	if uri := RedactedURI(req); uri != "/api/files/recent?limit=5" {
+		t.Errorf("Expected the URI unchanged, got %s", uri)
+	}
+}
+
+func TestAuthHandler_LoginAndUsers(t *testing.T) {
+	e, _ := newAuthTestServer(t)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Auth-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Only admins manage users
+	if rec := do(http.MethodPost, "/api/admin/users", "view-token", `{"username":"dana","password":"pw","role":"analyst"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/admin/users", "admin-token", `{"username":"dana","password":"pw","role":"boss"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid role, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/admin/users", "admin-token", `{"username":"dana","password":"pw","role":"analyst"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/admin/users", "admin-token", `{"username":"dana","password":"pw","role":"analyst"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/api/auth/login", "", `{"username":"dana","password":"wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/auth/login", "", `{"username":"dana","password":"pw"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var login loginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil || login.Token == "" {
+		t.Fatalf("expected a token, got %s", rec.Body.String())
+	}
+
+	// The issued token carries the analyst role
+	if rec := do(http.MethodPost, "/api/files/upload", login.Token, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("expected analyst to upload, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/files/f1", login.Token, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected analyst delete to be refused, got %d", rec.Code)
+	}
+	rec = do(http.MethodGet, "/api/auth/me", login.Token, "")
+	if !strings.Contains(rec.Body.String(), `"name":"dana"`) {
+		t.Errorf("expected identity of dana, got %s", rec.Body.String())
+	}
+}
@@ -5,39 +5,106 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/storage"
 )
 
-// CarrierHandlerImpl implements the CarrierHandler interface
+// carrierState is the active carrier log of one workspace
+type carrierState struct {
+	sessionID string
+	entries   []models.CarrierEntry
+	used      workspaceUse
+}
+
+// CarrierHandlerImpl implements the CarrierHandler interface. The active carrier log is
+// kept per workspace; a workspace that has not loaded its own uses the default one's.
 type CarrierHandlerImpl struct {
-	store            storage.Store
-	carrierSessionID string
-	carrierEntries   []models.CarrierEntry
+	store      storage.Store
+	audit      *audit.Log
+	mu         sync.RWMutex
+	workspaces map[string]*carrierState
 }
 
 // NewCarrierHandler creates a new carrier handler instance
-func NewCarrierHandler(store storage.Store) CarrierHandler {
+func NewCarrierHandler(store storage.Store, auditLog *audit.Log) CarrierHandler {
 	return &CarrierHandlerImpl{
-		store:          store,
-		carrierEntries: make([]models.CarrierEntry, 0),
+		store: store,
+		audit: auditLog,
+		workspaces: map[string]*carrierState{
+			DefaultWorkspace: {entries: make([]models.CarrierEntry, 0)},
+		},
 	}
 }
 
-// GetCarrierSessionID returns the current carrier session ID
-func (h *CarrierHandlerImpl) GetCarrierSessionID() string {
-	return h.carrierSessionID
+// GetCarrierSessionID returns the carrier session ID of a workspace
+func (h *CarrierHandlerImpl) GetCarrierSessionID(workspace string) string {
+	sessionID, _ := h.state(workspace)
+	return sessionID
+}
+
+// SetCarrierSessionID sets the carrier session ID of a workspace
+func (h *CarrierHandlerImpl) SetCarrierSessionID(workspace, sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stateLocked(workspace).sessionID = sessionID
 }
 
-// SetCarrierSessionID sets the current carrier session ID
-func (h *CarrierHandlerImpl) SetCarrierSessionID(sessionID string) {
-	h.carrierSessionID = sessionID
+// state returns the session ID and entries a workspace sees
+func (h *CarrierHandlerImpl) state(workspace string) (string, []models.CarrierEntry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if st := h.workspaces[workspace]; st != nil {
+		st.used.touch()
+		if st.sessionID != "" {
+			return st.sessionID, st.entries
+		}
+	}
+	st := h.workspaces[DefaultWorkspace]
+	return st.sessionID, st.entries
+}
+
+// stateLocked returns the state of a workspace, creating it on first use. Caller must
+// hold h.mu (write lock).
+func (h *CarrierHandlerImpl) stateLocked(workspace string) *carrierState {
+	st := h.workspaces[workspace]
+	if st == nil {
+		st = &carrierState{entries: make([]models.CarrierEntry, 0)}
+		h.workspaces[workspace] = st
+	}
+	st.used.touch()
+	return st
+}
+
+// ExpireWorkspaces drops the carrier logs of workspaces unused for longer than maxIdle,
+// which then see the default workspace's log again. It returns the number of workspaces
+// dropped.
+func (h *CarrierHandlerImpl) ExpireWorkspaces(maxIdle time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxIdle)
+	expired := 0
+	for workspace, st := range h.workspaces {
+		if workspace != DefaultWorkspace && st.used.idleSince(cutoff) {
+			delete(h.workspaces, workspace)
+			expired++
+		}
+	}
+	return expired
 }
 
-// HandleUploadCarrierLog uploads and processes a carrier log file
+// HandleUploadCarrierLog uploads and processes a carrier log file for the request's workspace
 func (h *CarrierHandlerImpl) HandleUploadCarrierLog(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
 	var req uploadCarrierLogRequest
 	if err := c.Bind(&req); err != nil {
 		return NewBadRequestError("invalid JSON body", err)
@@ -59,15 +126,22 @@ func (h *CarrierHandlerImpl) HandleUploadCarrierLog(c echo.Context) error {
 		return NewInternalError("failed to save carrier log", err)
 	}
 
-	h.carrierSessionID = info.ID
-
 	// Parse carrier entries from the log
 	entries, err := h.parseCarrierLog(decoded)
 	if err != nil {
 		return NewInternalError("failed to parse carrier log", err)
 	}
 
-	h.carrierEntries = entries
+	before := h.GetCarrierSessionID(workspace)
+	h.mu.Lock()
+	st := h.stateLocked(workspace)
+	st.sessionID = info.ID
+	st.entries = entries
+	h.mu.Unlock()
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionCarrierUpload, Target: info.ID, Workspace: workspace,
+		Before: before, After: info.ID, Detail: info.Name,
+	})
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"file":    info,
@@ -75,29 +149,39 @@ func (h *CarrierHandlerImpl) HandleUploadCarrierLog(c echo.Context) error {
 	})
 }
 
-// HandleGetCarrierLog returns carrier log file metadata
+// HandleGetCarrierLog returns the carrier log file metadata of the request's workspace
 func (h *CarrierHandlerImpl) HandleGetCarrierLog(c echo.Context) error {
-	if h.carrierSessionID == "" {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+	sessionID, entries := h.state(workspace)
+	if sessionID == "" {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"hasCarrierLog": false,
 		})
 	}
 
-	info, err := h.store.Get(h.carrierSessionID)
+	info, err := h.store.Get(sessionID)
 	if err != nil {
-		return NewNotFoundError("carrier log", h.carrierSessionID)
+		return NewNotFoundError("carrier log", sessionID)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"hasCarrierLog": true,
 		"file":          info,
-		"entryCount":    len(h.carrierEntries),
+		"entryCount":    len(entries),
 	})
 }
 
-// HandleGetCarrierEntries returns carrier position entries
+// HandleGetCarrierEntries returns the carrier position entries of the request's workspace
 func (h *CarrierHandlerImpl) HandleGetCarrierEntries(c echo.Context) error {
-	if h.carrierSessionID == "" {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+	sessionID, entries := h.state(workspace)
+	if sessionID == "" {
 		return c.JSON(http.StatusOK, []models.CarrierEntry{})
 	}
 
@@ -111,13 +195,13 @@ func (h *CarrierHandlerImpl) HandleGetCarrierEntries(c echo.Context) error {
 		startMs := parseInt64Default(startTimeStr, 0)
 		endMs := parseInt64Default(endTimeStr, 0)
 
-		for _, entry := range h.carrierEntries {
+		for _, entry := range entries {
 			if entry.TimestampMs >= startMs && entry.TimestampMs <= endMs {
 				filteredEntries = append(filteredEntries, entry)
 			}
 		}
 	} else {
-		filteredEntries = h.carrierEntries
+		filteredEntries = entries
 	}
 
 	return c.JSON(http.StatusOK, filteredEntries)
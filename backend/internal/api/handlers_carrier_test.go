@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/plc-visualizer/backend/internal/models"
@@ -66,7 +67,7 @@ func TestCarrierHandler_HandleUploadCarrierLog(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := testutil.NewMockStorage()
-			handler := NewCarrierHandler(store)
+			handler := NewCarrierHandler(store, nil)
 
 			e := echo.New()
 			body, _ := json.Marshal(tt.request)
@@ -100,7 +101,7 @@ func TestCarrierHandler_HandleUploadCarrierLog(t *testing.T) {
 				}
 
 				// Verify session ID was set
-				if handler.GetCarrierSessionID() == "" {
+				if handler.GetCarrierSessionID(DefaultWorkspace) == "" {
 					t.Error("expected carrier session ID to be set")
 				}
 
@@ -142,12 +143,12 @@ func TestCarrierHandler_HandleGetCarrierLog(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := testutil.NewMockStorage()
-			handler := NewCarrierHandler(store)
+			handler := NewCarrierHandler(store, nil)
 
 			if tt.setupSession {
 				// Upload a carrier log first
 				store.AddFile("carrier-123", "carriers.csv", []byte("data"))
-				handler.SetCarrierSessionID("carrier-123")
+				handler.SetCarrierSessionID(DefaultWorkspace, "carrier-123")
 			}
 
 			e := echo.New()
@@ -215,11 +216,11 @@ func TestCarrierHandler_HandleGetCarrierEntries(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := testutil.NewMockStorage()
-			handler := NewCarrierHandler(store)
+			handler := NewCarrierHandler(store, nil)
 
 			if tt.setupSession {
 				store.AddFile("carrier-123", "carriers.csv", []byte("data"))
-				handler.SetCarrierSessionID("carrier-123")
+				handler.SetCarrierSessionID(DefaultWorkspace, "carrier-123")
 			}
 
 			e := echo.New()
@@ -261,26 +262,69 @@ func TestCarrierHandler_HandleGetCarrierEntries(t *testing.T) {
 
 func TestCarrierHandler_SessionManagement(t *testing.T) {
 	store := testutil.NewMockStorage()
-	handler := NewCarrierHandler(store)
+	handler := NewCarrierHandler(store, nil)
 
 	// Test initial state
-	if handler.GetCarrierSessionID() != "" {
+	if handler.GetCarrierSessionID(DefaultWorkspace) != "" {
 		t.Error("expected empty session ID initially")
 	}
 
 	// Test setting session ID
-	handler.SetCarrierSessionID("test-session-456")
-	if handler.GetCarrierSessionID() != "test-session-456" {
+	handler.SetCarrierSessionID(DefaultWorkspace, "test-session-456")
+	if handler.GetCarrierSessionID(DefaultWorkspace) != "test-session-456" {
 		t.Error("session ID not set correctly")
 	}
 
 	// Test overwriting session ID
-	handler.SetCarrierSessionID("new-session-789")
-	if handler.GetCarrierSessionID() != "new-session-789" {
+	handler.SetCarrierSessionID(DefaultWorkspace, "new-session-789")
+	if handler.GetCarrierSessionID(DefaultWorkspace) != "new-session-789" {
 		t.Error("session ID not updated correctly")
 	}
 }
 
+func TestCarrierHandler_Workspaces(t *testing.T) {
+	handler := NewCarrierHandler(testutil.NewMockStorage(), nil)
+
+	handler.SetCarrierSessionID(DefaultWorkspace, "shared-session")
+	handler.SetCarrierSessionID("line-1", "line-1-session")
+
+	if got := handler.GetCarrierSessionID("line-1"); got != "line-1-session" {
+		t.Errorf("expected line-1-session, got %s", got)
+	}
+	if got := handler.GetCarrierSessionID("line-2"); got != "shared-session" {
+		t.Errorf("expected line-2 to fall back to shared-session, got %s", got)
+	}
+	if got := handler.GetCarrierSessionID(DefaultWorkspace); got != "shared-session" {
+		t.Errorf("expected the default workspace to keep shared-session, got %s", got)
+	}
+}
+
+func TestCarrierHandler_ExpireWorkspaces(t *testing.T) {
+	handler := NewCarrierHandler(testutil.NewMockStorage(), nil).(*CarrierHandlerImpl)
+
+	handler.SetCarrierSessionID(DefaultWorkspace, "shared-session")
+	handler.SetCarrierSessionID("stale", "stale-session")
+	handler.SetCarrierSessionID("active", "active-session")
+	handler.workspaces["stale"].used.last.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	handler.workspaces[DefaultWorkspace].used.last.Store(0)
+
+	if n := handler.ExpireWorkspaces(time.Hour); n != 1 {
+		t.Errorf("expected one workspace to expire, got %d", n)
+	}
+	if _, ok := handler.workspaces["stale"]; ok {
+		t.Error("expected the stale workspace to be dropped")
+	}
+	if got := handler.GetCarrierSessionID("stale"); got != "shared-session" {
+		t.Errorf("expected the stale workspace to fall back to shared-session, got %s", got)
+	}
+	if got := handler.GetCarrierSessionID("active"); got != "active-session" {
+		t.Errorf("expected the active workspace to be kept, got %s", got)
+	}
+	if _, ok := handler.workspaces[DefaultWorkspace]; !ok {
+		t.Error("expected the default workspace never to expire")
+	}
+}
+
 func TestUploadCarrierLogRequest_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
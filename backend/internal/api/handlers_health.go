@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/parser"
 )
 
 // HealthHandlerImpl implements the HealthHandler interface
@@ -26,3 +27,9 @@ func (h *HealthHandlerImpl) HandleHealth(c echo.Context) error {
 		"version": h.version,
 	})
 }
+
+// HandleResources returns the DuckDB memory and thread budget and how it is shared
+// among the open sessions
+func (h *HealthHandlerImpl) HandleResources(c echo.Context) error {
+	return c.JSON(http.StatusOK, parser.GetResourceStatus())
+}
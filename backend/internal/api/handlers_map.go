@@ -8,52 +8,119 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/parser"
 	"github.com/plc-visualizer/backend/internal/storage"
 )
 
-// MapHandlerImpl implements the MapHandler interface
+// mapState is the active map and rules of one workspace
+type mapState struct {
+	mapID   string
+	rulesID string
+	rules   *models.MapRules
+	used    workspaceUse
+}
+
+// MapHandlerImpl implements the MapHandler interface. The active map and rules are
+// kept per workspace; a workspace that has not chosen its own uses the default one's.
 type MapHandlerImpl struct {
-	store          storage.Store
-	dataDir        string
-	currentMapID   string
-	currentRulesID string
-	currentRules   *models.MapRules
+	store      storage.Store
+	dataDir    string
+	audit      *audit.Log
+	mu         sync.RWMutex
+	workspaces map[string]*mapState
 }
 
 // NewMapHandler creates a new map handler instance
-func NewMapHandler(store storage.Store, dataDir string) MapHandler {
+func NewMapHandler(store storage.Store, dataDir string, auditLog *audit.Log) MapHandler {
 	return &MapHandlerImpl{
-		store:   store,
-		dataDir: dataDir,
+		store:      store,
+		dataDir:    dataDir,
+		audit:      auditLog,
+		workspaces: map[string]*mapState{DefaultWorkspace: {}},
+	}
+}
+
+// SetCurrentMap sets the active map of a workspace (used by other handlers)
+func (h *MapHandlerImpl) SetCurrentMap(workspace, mapID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stateLocked(workspace).mapID = mapID
+}
+
+// GetCurrentMap returns the active map ID of a workspace
+func (h *MapHandlerImpl) GetCurrentMap(workspace string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if st := h.workspaces[workspace]; st != nil {
+		st.used.touch()
+		if st.mapID != "" {
+			return st.mapID
+		}
 	}
+	return h.workspaces[DefaultWorkspace].mapID
 }
 
-// SetCurrentMap sets the currently active map (used by other handlers)
-func (h *MapHandlerImpl) SetCurrentMap(mapID string) {
-	h.currentMapID = mapID
+// SetCurrentRules sets the active rules of a workspace
+func (h *MapHandlerImpl) SetCurrentRules(workspace, rulesID string, rules *models.MapRules) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.stateLocked(workspace)
+	st.rulesID = rulesID
+	st.rules = rules
 }
 
-// GetCurrentMap returns the currently active map ID
-func (h *MapHandlerImpl) GetCurrentMap() string {
-	return h.currentMapID
+// GetCurrentRules returns the active rules of a workspace
+func (h *MapHandlerImpl) GetCurrentRules(workspace string) (string, *models.MapRules) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if st := h.workspaces[workspace]; st != nil {
+		st.used.touch()
+		if st.rules != nil {
+			return st.rulesID, st.rules
+		}
+	}
+	st := h.workspaces[DefaultWorkspace]
+	return st.rulesID, st.rules
 }
 
-// SetCurrentRules sets the currently active rules
-func (h *MapHandlerImpl) SetCurrentRules(rulesID string, rules *models.MapRules) {
-	h.currentRulesID = rulesID
-	h.currentRules = rules
+// stateLocked returns the state of a workspace, creating it on first use. Caller must
+// hold h.mu (write lock).
+func (h *MapHandlerImpl) stateLocked(workspace string) *mapState {
+	st := h.workspaces[workspace]
+	if st == nil {
+		st = &mapState{}
+		h.workspaces[workspace] = st
+	}
+	st.used.touch()
+	return st
 }
 
-// GetCurrentRules returns the currently active rules
-func (h *MapHandlerImpl) GetCurrentRules() (string, *models.MapRules) {
-	return h.currentRulesID, h.currentRules
+// ExpireWorkspaces drops the state of workspaces unused for longer than maxIdle, which
+// then fall back to the default workspace's map and rules again. It returns the number
+// of workspaces dropped.
+func (h *MapHandlerImpl) ExpireWorkspaces(maxIdle time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxIdle)
+	expired := 0
+	for workspace, st := range h.workspaces {
+		if workspace != DefaultWorkspace && st.used.idleSince(cutoff) {
+			delete(h.workspaces, workspace)
+			expired++
+		}
+	}
+	return expired
 }
 
-// LoadDefaultRules loads the default rules.yaml file if it exists
+// LoadDefaultRules loads the default rules.yaml file, if it exists, into the default
+// workspace
 func (h *MapHandlerImpl) LoadDefaultRules() error {
 	rulesPath := filepath.Join(h.dataDir, "defaults", "rules.yaml")
 	if _, err := os.Stat(rulesPath); os.IsNotExist(err) {
@@ -71,20 +138,25 @@ func (h *MapHandlerImpl) LoadDefaultRules() error {
 		return fmt.Errorf("failed to parse default rules: %w", err)
 	}
 
-	h.currentRulesID = "default:rules.yaml"
-	h.currentRules = rules
+	h.SetCurrentRules(DefaultWorkspace, "default:rules.yaml", rules)
 	return nil
 }
 
-// HandleGetMapLayout returns the currently active map layout
+// HandleGetMapLayout returns the active map layout of the request's workspace
 func (h *MapHandlerImpl) HandleGetMapLayout(c echo.Context) error {
-	if h.currentMapID == "" {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+	mapID := h.GetCurrentMap(workspace)
+	rulesID, _ := h.GetCurrentRules(workspace)
+	if mapID == "" {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"objects": map[string]interface{}{},
 		})
 	}
 
-	path, mapName, err := h.resolveMapPath(h.currentMapID)
+	path, mapName, err := h.resolveMapPath(mapID)
 	if err != nil {
 		return NewInternalError("failed to resolve map path", err)
 	}
@@ -97,13 +169,18 @@ func (h *MapHandlerImpl) HandleGetMapLayout(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"layout":  layout,
 		"name":    mapName,
-		"mapId":   h.currentMapID,
-		"rulesId": h.currentRulesID,
+		"mapId":   mapID,
+		"rulesId": rulesID,
 	})
 }
 
-// HandleUploadMapLayout uploads and activates a new map layout
+// HandleUploadMapLayout uploads a new map layout and activates it in the request's workspace
 func (h *MapHandlerImpl) HandleUploadMapLayout(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
 	var req uploadMapRequest
 	if err := c.Bind(&req); err != nil {
 		return NewBadRequestError("invalid JSON body", err)
@@ -126,13 +203,23 @@ func (h *MapHandlerImpl) HandleUploadMapLayout(c echo.Context) error {
 	}
 
 	// Set as current map
-	h.currentMapID = info.ID
+	before := h.GetCurrentMap(workspace)
+	h.SetCurrentMap(workspace, info.ID)
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionMapUpload, Target: info.ID, Workspace: workspace,
+		Before: before, After: info.ID, Detail: info.Name,
+	})
 
 	return c.JSON(http.StatusCreated, info)
 }
 
-// HandleSetActiveMap sets the currently active map by ID
+// HandleSetActiveMap sets the active map of the request's workspace by ID
 func (h *MapHandlerImpl) HandleSetActiveMap(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
 	var req setActiveMapRequest
 	if err := c.Bind(&req); err != nil {
 		return NewBadRequestError("invalid request body", err)
@@ -157,12 +244,22 @@ func (h *MapHandlerImpl) HandleSetActiveMap(c echo.Context) error {
 		}
 	}
 
-	h.currentMapID = req.MapID
-	return c.JSON(http.StatusOK, map[string]string{"mapId": h.currentMapID})
+	before := h.GetCurrentMap(workspace)
+	h.SetCurrentMap(workspace, req.MapID)
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionMapActivate, Target: req.MapID, Workspace: workspace,
+		Before: before, After: req.MapID,
+	})
+	return c.JSON(http.StatusOK, map[string]string{"mapId": req.MapID})
 }
 
-// HandleUploadMapRules uploads and activates map rules
+// HandleUploadMapRules uploads map rules and activates them in the request's workspace
 func (h *MapHandlerImpl) HandleUploadMapRules(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
 	var req uploadRulesRequest
 	if err := c.Bind(&req); err != nil {
 		return NewBadRequestError("invalid JSON body", err)
@@ -191,23 +288,32 @@ func (h *MapHandlerImpl) HandleUploadMapRules(c echo.Context) error {
 	}
 
 	// Set as current rules
-	h.currentRulesID = info.ID
-	h.currentRules = rules
+	before, _ := h.GetCurrentRules(workspace)
+	h.SetCurrentRules(workspace, info.ID, rules)
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionRulesUpload, Target: info.ID, Workspace: workspace,
+		Before: before, After: info.ID, Detail: info.Name,
+	})
 
 	return c.JSON(http.StatusCreated, info)
 }
 
-// HandleGetMapRules returns the currently active map rules
+// HandleGetMapRules returns the active map rules of the request's workspace
 func (h *MapHandlerImpl) HandleGetMapRules(c echo.Context) error {
-	if h.currentRules == nil {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+	rulesID, rules := h.GetCurrentRules(workspace)
+	if rules == nil {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"rules": nil,
 		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"rules":   h.currentRules,
-		"rulesId": h.currentRulesID,
+		"rules":   rules,
+		"rulesId": rulesID,
 	})
 }
 
@@ -244,8 +350,13 @@ func (h *MapHandlerImpl) HandleGetDefaultMaps(c echo.Context) error {
 	return c.JSON(http.StatusOK, maps)
 }
 
-// HandleLoadDefaultMap loads a default map by name
+// HandleLoadDefaultMap activates a default map by name in the request's workspace
 func (h *MapHandlerImpl) HandleLoadDefaultMap(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
 	var req struct {
 		Name string `json:"name"`
 	}
@@ -258,7 +369,12 @@ func (h *MapHandlerImpl) HandleLoadDefaultMap(c echo.Context) error {
 	}
 
 	mapID := "default:" + req.Name
-	h.currentMapID = mapID
+	before := h.GetCurrentMap(workspace)
+	h.SetCurrentMap(workspace, mapID)
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionMapActivate, Target: mapID, Workspace: workspace,
+		Before: before, After: mapID,
+	})
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"mapId": mapID,
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/plc-visualizer/backend/internal/models"
@@ -67,7 +68,7 @@ func TestMapHandler_HandleUploadMapLayout(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := testutil.NewMockStorage()
-			handler := NewMapHandler(store, "./data")
+			handler := NewMapHandler(store, "./data", nil)
 
 			e := echo.New()
 			body, _ := json.Marshal(tt.request)
@@ -101,7 +102,7 @@ func TestMapHandler_HandleUploadMapLayout(t *testing.T) {
 				}
 
 				// Verify map was set as current
-				if handler.GetCurrentMap() == "" {
+				if handler.GetCurrentMap(DefaultWorkspace) == "" {
 					t.Error("expected current map to be set")
 				}
 			}
@@ -140,7 +141,7 @@ func TestMapHandler_HandleGetMapLayout(t *testing.T) {
 			defer os.RemoveAll(tempDir)
 
 			store := testutil.NewMockStorageWithTempDir(tempDir)
-			handler := NewMapHandler(store, tempDir)
+			handler := NewMapHandler(store, tempDir, nil)
 
 			if tt.setupMapID != "" {
 				// Add a mock map file with proper ConveyorMap XML format
@@ -153,7 +154,7 @@ func TestMapHandler_HandleGetMapLayout(t *testing.T) {
   </Object>
 </ConveyorMap>`
 				store.AddFile(tt.setupMapID, "test.xml", []byte(validMapXML))
-				handler.SetCurrentMap(tt.setupMapID)
+				handler.SetCurrentMap(DefaultWorkspace, tt.setupMapID)
 			}
 
 			e := echo.New()
@@ -240,7 +241,7 @@ func TestMapHandler_HandleSetActiveMap(t *testing.T) {
 			for id, data := range tt.setupFiles {
 				store.AddFile(id, "test.xml", data)
 			}
-			handler := NewMapHandler(store, "./data")
+			handler := NewMapHandler(store, "./data", nil)
 
 			e := echo.New()
 			body, _ := json.Marshal(setActiveMapRequest{MapID: tt.mapID})
@@ -311,13 +312,13 @@ func TestMapHandler_HandleGetMapRules(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			store := testutil.NewMockStorage()
-			handler := NewMapHandler(store, "./data")
+			handler := NewMapHandler(store, "./data", nil)
 
 			if tt.setupRules {
 				rules := &models.MapRules{
 					DefaultColor: "#D3D3D3",
 				}
-				handler.SetCurrentRules("rules-123", rules)
+				handler.SetCurrentRules(DefaultWorkspace, "rules-123", rules)
 			}
 
 			e := echo.New()
@@ -393,7 +394,7 @@ func TestMapHandler_HandleRecentMapFiles(t *testing.T) {
 			for id, filename := range tt.files {
 				store.AddFile(id, filename, []byte("content"))
 			}
-			handler := NewMapHandler(store, "./data")
+			handler := NewMapHandler(store, "./data", nil)
 
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodGet, "/api/map/files/recent", nil)
@@ -420,6 +421,82 @@ func TestMapHandler_HandleRecentMapFiles(t *testing.T) {
 	}
 }
 
+func TestMapHandler_Workspaces(t *testing.T) {
+	handler := NewMapHandler(testutil.NewMockStorage(), "./data", nil)
+	e := echo.New()
+
+	loadMap := func(target, header, name string) error {
+		body := bytes.NewBufferString(`{"name":"` + name + `"}`)
+		req := httptest.NewRequest(http.MethodPost, target, body)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if header != "" {
+			req.Header.Set(WorkspaceHeader, header)
+		}
+		return handler.HandleLoadDefaultMap(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	defaultRules := &models.MapRules{DefaultColor: "#D3D3D3"}
+	handler.SetCurrentRules(DefaultWorkspace, "default:rules.yaml", defaultRules)
+
+	if err := loadMap("/api/map/defaults/load", "", "shared.xml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loadMap("/api/map/defaults/load?workspace=line-1", "", "line1.xml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loadMap("/api/map/defaults/load", "line-2", "line2.xml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for workspace, want := range map[string]string{
+		DefaultWorkspace: "default:shared.xml",
+		"line-1":         "default:line1.xml",
+		"line-2":         "default:line2.xml",
+		"line-3":         "default:shared.xml", // Falls back to the default workspace
+	} {
+		if got := handler.GetCurrentMap(workspace); got != want {
+			t.Errorf("workspace %q: expected map %s, got %s", workspace, want, got)
+		}
+	}
+
+	// Rules set in one workspace do not leak into the others
+	handler.SetCurrentRules("line-1", "rules-line-1", &models.MapRules{DefaultColor: "#FF0000"})
+	if id, _ := handler.GetCurrentRules("line-1"); id != "rules-line-1" {
+		t.Errorf("expected line-1 rules, got %s", id)
+	}
+	if id, rules := handler.GetCurrentRules("line-2"); id != "default:rules.yaml" || rules != defaultRules {
+		t.Errorf("expected line-2 to use the default rules, got %s", id)
+	}
+
+	err := loadMap("/api/map/defaults/load?workspace=../etc", "", "x.xml")
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid workspace, got %v", err)
+	}
+}
+
+func TestMapHandler_ExpireWorkspaces(t *testing.T) {
+	handler := NewMapHandler(testutil.NewMockStorage(), "./data", nil).(*MapHandlerImpl)
+
+	handler.SetCurrentMap(DefaultWorkspace, "shared-map")
+	handler.SetCurrentMap("stale", "stale-map")
+	handler.SetCurrentMap("active", "active-map")
+	handler.workspaces["stale"].used.last.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+
+	// Reading a workspace keeps it alive
+	handler.workspaces["active"].used.last.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	handler.GetCurrentMap("active")
+
+	if n := handler.ExpireWorkspaces(time.Hour); n != 1 {
+		t.Errorf("expected one workspace to expire, got %d", n)
+	}
+	if got := handler.GetCurrentMap("stale"); got != "shared-map" {
+		t.Errorf("expected the stale workspace to fall back to shared-map, got %s", got)
+	}
+	if got := handler.GetCurrentMap("active"); got != "active-map" {
+		t.Errorf("expected the active workspace to be kept, got %s", got)
+	}
+}
 func TestUploadMapRequest_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
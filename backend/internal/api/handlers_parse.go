@@ -2,15 +2,20 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/parser"
+	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/storage"
 )
 
@@ -48,7 +53,10 @@ func (h *ParseHandlerImpl) HandleStartParse(c echo.Context) error {
 	}
 
 	// Start parsing session
-	sess, err := h.sessionMgr.StartMultiSession(validFileIDs, filePaths)
+	sess, err := h.sessionMgr.StartMultiSession(validFileIDs, filePaths, req.Priority)
+	if errors.Is(err, parser.ErrResourceBudget) {
+		return NewServiceUnavailableError("DuckDB memory budget exhausted, close a session and retry")
+	}
 	if err != nil {
 		return NewInternalError("failed to start session", err)
 	}
@@ -88,6 +96,89 @@ func (h *ParseHandlerImpl) HandleSessionKeepAlive(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// HandleCancelParse stops a running parse and removes its partial data, or closes a
+// finished session
+func (h *ParseHandlerImpl) HandleCancelParse(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	if err := h.sessionMgr.CancelSession(id); err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			return NewNotFoundError("session", id)
+		}
+		return NewInternalError("failed to cancel session", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandlePauseParse pauses a running parse
+func (h *ParseHandlerImpl) HandlePauseParse(c echo.Context) error {
+	return h.setParsePaused(c, true)
+}
+
+// HandleResumeParse resumes a paused parse
+func (h *ParseHandlerImpl) HandleResumeParse(c echo.Context) error {
+	return h.setParsePaused(c, false)
+}
+
+func (h *ParseHandlerImpl) setParsePaused(c echo.Context, paused bool) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var err error
+	if paused {
+		err = h.sessionMgr.PauseSession(id)
+	} else {
+		err = h.sessionMgr.ResumeSession(id)
+	}
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound):
+		return NewNotFoundError("session", id)
+	case errors.Is(err, session.ErrSessionState):
+		if paused {
+			return NewConflictError("session is not parsing")
+		}
+		return NewConflictError("session is not paused")
+	case err != nil:
+		return NewInternalError("failed to change session state", err)
+	}
+
+	sess, _ := h.sessionMgr.GetSession(id)
+	return c.JSON(http.StatusOK, sess)
+}
+
+// HandleParsePriority changes the priority of a session; queued sessions with a higher
+// priority start parsing first
+func (h *ParseHandlerImpl) HandleParsePriority(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	var req struct {
+		Priority int `json:"priority"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+
+	err := h.sessionMgr.SetPriority(id, req.Priority)
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound):
+		return NewNotFoundError("session", id)
+	case err != nil:
+		return NewInternalError("failed to set priority", err)
+	}
+
+	sess, _ := h.sessionMgr.GetSession(id)
+	return c.JSON(http.StatusOK, sess)
+}
+
 // HandleParseProgressStream streams parsing progress via SSE
 func (h *ParseHandlerImpl) HandleParseProgressStream(c echo.Context) error {
 	id := c.Param("sessionId")
@@ -143,13 +234,18 @@ func (h *ParseHandlerImpl) HandleParseProgressStream(c echo.Context) error {
 	}
 }
 
-// HandleParseEntries returns paginated log entries for a session
+// HandleParseEntries returns paginated log entries for a session.
+// With a "cursor" or "direction" parameter it pages by opaque cursor instead of page number.
 func (h *ParseHandlerImpl) HandleParseEntries(c echo.Context) error {
 	id := c.Param("sessionId")
 	if id == "" {
 		return NewValidationError("sessionId")
 	}
 
+	if c.QueryParams().Has("cursor") || c.QueryParams().Has("direction") {
+		return h.handleParseEntriesCursor(c, id)
+	}
+
 	// Parse pagination params
 	page, _ := strconv.Atoi(c.QueryParam("page"))
 	if page < 1 {
@@ -161,7 +257,10 @@ func (h *ParseHandlerImpl) HandleParseEntries(c echo.Context) error {
 	}
 
 	// Build query params from filters
-	params := h.buildQueryParams(c)
+	params, err := h.buildQueryParams(c)
+	if err != nil {
+		return err
+	}
 
 	ctx := c.Request().Context()
 	entries, total, ok := h.sessionMgr.QueryEntries(ctx, id, params, page, pageSize)
@@ -169,6 +268,15 @@ func (h *ParseHandlerImpl) HandleParseEntries(c echo.Context) error {
 		return NewNotFoundError("session", id)
 	}
 
+	if wantsArrow(c) {
+		c.Response().Header().Set("X-Total-Count", strconv.Itoa(total))
+		return arrowBlob(c, func(w io.Writer) error {
+			return parser.WriteArrowEntries(w, entries, map[string]string{
+				"page": strconv.Itoa(page), "pageSize": strconv.Itoa(pageSize), "total": strconv.Itoa(total),
+			})
+		})
+	}
+
 	return c.JSON(http.StatusOK, entriesResponse{
 		Entries:  entries,
 		Page:     page,
@@ -177,6 +285,53 @@ func (h *ParseHandlerImpl) HandleParseEntries(c echo.Context) error {
 	})
 }
 
+// handleParseEntriesCursor returns the entries after the cursor ("direction=next", the
+// default) or before it ("direction=prev"), with the cursors of the adjacent pages
+func (h *ParseHandlerImpl) handleParseEntriesCursor(c echo.Context, id string) error {
+	limit, _ := strconv.Atoi(c.QueryParam("pageSize"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+	direction := c.QueryParam("direction")
+	if direction != "" && direction != "next" && direction != "prev" {
+		return NewValidationError("direction")
+	}
+
+	params, err := h.buildQueryParams(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.sessionMgr.QueryEntriesCursor(c.Request().Context(), id, params, c.QueryParam("cursor"), direction == "prev", limit)
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound):
+		return NewNotFoundError("session", id)
+	case errors.Is(err, session.ErrSessionNotReady):
+		return NewConflictError("session is not ready for queries")
+	case errors.Is(err, parser.ErrInvalidQuery):
+		return NewBadRequestError("invalid cursor", err)
+	case err != nil:
+		return NewInternalError("entries query failed", err)
+	}
+
+	if wantsArrow(c) {
+		// Cursors go in headers and schema metadata, next to the columnar entries
+		meta := map[string]string{"total": strconv.Itoa(page.Total)}
+		c.Response().Header().Set("X-Total-Count", meta["total"])
+		if page.Next != "" {
+			meta["next"] = page.Next
+			c.Response().Header().Set("X-Next-Cursor", page.Next)
+		}
+		if page.Prev != "" {
+			meta["prev"] = page.Prev
+			c.Response().Header().Set("X-Prev-Cursor", page.Prev)
+		}
+		return arrowBlob(c, func(w io.Writer) error { return parser.WriteArrowEntries(w, page.Entries, meta) })
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
 // HandleParseEntriesMsgpack returns entries in MessagePack format
 func (h *ParseHandlerImpl) HandleParseEntriesMsgpack(c echo.Context) error {
 	// Implementation similar to HandleParseEntries but with msgpack encoding
@@ -184,44 +339,108 @@ func (h *ParseHandlerImpl) HandleParseEntriesMsgpack(c echo.Context) error {
 	return h.HandleParseEntries(c)
 }
 
-// HandleParseStream streams entries via SSE for real-time updates
+// liveBatchSize is the most entries sent in one stream message
+const liveBatchSize = 1000
+
+// liveKeepAlive is how often an idle stream sends a comment to keep proxies from closing it
+const liveKeepAlive = 15 * time.Second
+
+// HandleParseStream streams a session via SSE while it is parsed. Entries are sent in
+// insertion order as soon as the parser has committed them, interleaved with progress
+// messages carrying the growing time range; a final "done" message follows once the
+// session is complete. "from" resumes the stream at an entry index.
 func (h *ParseHandlerImpl) HandleParseStream(c echo.Context) error {
 	id := c.Param("sessionId")
 	if id == "" {
 		return NewValidationError("sessionId")
 	}
+	sent, _ := strconv.Atoi(c.QueryParam("from"))
+	if sent < 0 {
+		sent = 0
+	}
+
+	updates, unsubscribe, ok := h.sessionMgr.SubscribeSession(id)
+	if !ok {
+		return NewNotFoundError("session", id)
+	}
+	defer unsubscribe()
 
 	// Set SSE headers
 	c.Response().Header().Set("Content-Type", "text/event-stream")
 	c.Response().Header().Set("Cache-Control", "no-cache")
 	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().Header().Set("X-Accel-Buffering", "no")
 	c.Response().WriteHeader(http.StatusOK)
 
-	// Stream entries in chunks
-	page := 1
-	pageSize := 100
+	ctx := c.Request().Context()
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
 
 	for {
-		ctx := c.Request().Context()
-		entries, total, ok := h.sessionMgr.GetEntries(ctx, id, page, pageSize)
+		// Read the status before the entries, so that a complete session is only
+		// reported done after everything committed before completion has been sent
+		sess, ok := h.sessionMgr.GetSession(id)
 		if !ok {
 			h.sendSSEError(c, "session not found")
 			return nil
 		}
+		status, progress := sess.Status, sess.Progress
+
+		var committed parser.CommitInfo
+		for {
+			entries, info, err := h.sessionMgr.GetLiveEntries(ctx, id, sent, liveBatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				h.sendSSEError(c, err.Error())
+				return nil
+			}
+			committed = info
+			if len(entries) == 0 {
+				break
+			}
+			h.sendSSEData(c, liveEntriesMessage{
+				Entries:  entries,
+				From:     sent,
+				Progress: progress,
+				Total:    info.Count,
+			})
+			sent += len(entries)
+		}
 
-		h.sendSSEData(c, map[string]interface{}{
-			"entries": entries,
-			"page":    page,
-			"total":   total,
+		switch status {
+		case models.SessionStatusComplete:
+			h.sendSSEData(c, map[string]interface{}{"done": true, "total": sent})
+			return nil
+		case models.SessionStatusCancelled:
+			h.sendSSEError(c, "parse cancelled")
+			return nil
+		case models.SessionStatusError:
+			msg := "parse failed"
+			if n := len(sess.Errors); n > 0 {
+				msg = sess.Errors[n-1].Reason
+			}
+			h.sendSSEError(c, msg)
+			return nil
+		}
+		h.sendSSEData(c, liveProgressMessage{
+			Status:    status,
+			Progress:  progress,
+			Lines:     sess.EntryCount,
+			Committed: committed.Count,
+			StartTime: committed.StartTime,
+			EndTime:   committed.EndTime,
 		})
 
-		// Check if we've sent all entries
-		if len(entries) == 0 || page*pageSize >= total {
+		select {
+		case <-updates:
+		case <-keepAlive.C:
+			fmt.Fprint(c.Response(), ": keep-alive\n\n")
+			c.Response().Flush()
+		case <-ctx.Done():
 			return nil
 		}
-
-		page++
-		c.Response().Flush()
 	}
 }
 
@@ -232,18 +451,35 @@ func (h *ParseHandlerImpl) HandleParseChunk(c echo.Context) error {
 		return NewValidationError("sessionId")
 	}
 
-	// Parse time range
-	startTs, err := parseTimestamp(c.QueryParam("start"))
+	preset, err := h.lookupPreset(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse time range; a preset supplies the window and signals the request leaves out
+	start, end := c.QueryParam("start"), c.QueryParam("end")
+	if preset != nil {
+		if start == "" && preset.StartTime > 0 {
+			start = strconv.FormatInt(preset.StartTime, 10)
+		}
+		if end == "" && preset.EndTime > 0 {
+			end = strconv.FormatInt(preset.EndTime, 10)
+		}
+	}
+	startTs, err := parseTimestamp(start)
 	if err != nil {
 		return NewBadRequestError("invalid start time", err)
 	}
-	endTs, err := parseTimestamp(c.QueryParam("end"))
+	endTs, err := parseTimestamp(end)
 	if err != nil {
 		return NewBadRequestError("invalid end time", err)
 	}
 
 	// Parse signal filter
 	signals := c.QueryParams()["signals"]
+	if len(signals) == 0 && preset != nil {
+		signals = preset.Signals
+	}
 
 	ctx := c.Request().Context()
 	entries, ok := h.sessionMgr.GetChunk(ctx, id, startTs, endTs, signals)
@@ -251,6 +487,9 @@ func (h *ParseHandlerImpl) HandleParseChunk(c echo.Context) error {
 		return NewNotFoundError("session", id)
 	}
 
+	if wantsArrow(c) {
+		return arrowBlob(c, func(w io.Writer) error { return parser.WriteArrowEntries(w, entries, nil) })
+	}
 	return c.JSON(http.StatusOK, entries)
 }
 
@@ -279,6 +518,9 @@ func (h *ParseHandlerImpl) HandleParseChunkBoundaries(c echo.Context) error {
 		return NewNotFoundError("session", id)
 	}
 
+	if wantsArrow(c) {
+		return arrowBlob(c, func(w io.Writer) error { return parser.WriteArrowBoundaries(w, boundaries) })
+	}
 	return c.JSON(http.StatusOK, boundaries)
 }
 
@@ -340,7 +582,10 @@ func (h *ParseHandlerImpl) HandleGetIndexByTime(c echo.Context) error {
 		return NewBadRequestError("invalid timestamp", err)
 	}
 
-	params := h.buildQueryParams(c)
+	params, err := h.buildQueryParams(c)
+	if err != nil {
+		return err
+	}
 
 	ctx := c.Request().Context()
 	index, ok := h.sessionMgr.GetIndexByTime(ctx, id, params, ts)
@@ -348,7 +593,12 @@ func (h *ParseHandlerImpl) HandleGetIndexByTime(c echo.Context) error {
 		return NewNotFoundError("session", id)
 	}
 
-	return c.JSON(http.StatusOK, map[string]int{"index": index})
+	// The cursor at the same position lets clients continue with cursor pagination
+	resp := map[string]interface{}{"index": index}
+	if cursor, err := h.sessionMgr.CursorAtTime(ctx, id, params, ts); err == nil && cursor != "" {
+		resp["cursor"] = cursor
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 // HandleGetTimeTree returns a time-based tree structure for navigation
@@ -358,7 +608,10 @@ func (h *ParseHandlerImpl) HandleGetTimeTree(c echo.Context) error {
 		return NewValidationError("sessionId")
 	}
 
-	params := h.buildQueryParams(c)
+	params, err := h.buildQueryParams(c)
+	if err != nil {
+		return err
+	}
 
 	ctx := c.Request().Context()
 	tree, ok := h.sessionMgr.GetTimeTree(ctx, id, params)
@@ -389,14 +642,150 @@ func (h *ParseHandlerImpl) HandleGetValuesAtTime(c echo.Context) error {
 		return NewNotFoundError("session", id)
 	}
 
+	if wantsArrow(c) {
+		return arrowBlob(c, func(w io.Writer) error { return parser.WriteArrowEntries(w, entries, nil) })
+	}
 	return c.JSON(http.StatusOK, entries)
 }
 
+// HandleExportEntries streams every entry matching the entry filters and an optional
+// start/end time range as csv, ndjson or xlsx. Pass an exportId to follow the progress
+// of large exports with HandleExportProgress; X-Total-Count carries the row count.
+func (h *ParseHandlerImpl) HandleExportEntries(c echo.Context) error {
+	id := c.Param("sessionId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+
+	params, err := h.buildQueryParams(c)
+	if err != nil {
+		return err
+	}
+	if s := c.QueryParam("start"); s != "" {
+		if params.StartTs, err = parseInt64Param(s); err != nil {
+			return NewBadRequestError("invalid start time", err)
+		}
+	}
+	if s := c.QueryParam("end"); s != "" {
+		if params.EndTs, err = parseInt64Param(s); err != nil {
+			return NewBadRequestError("invalid end time", err)
+		}
+	}
+
+	format := parser.ExportFormat(c.QueryParam("format"))
+	if format == "" {
+		format = parser.ExportCSV
+	}
+	var columns []string
+	for _, v := range c.QueryParams()["columns"] {
+		for _, col := range strings.Split(v, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				columns = append(columns, col)
+			}
+		}
+	}
+
+	res := c.Response()
+	q := parser.ExportQuery{
+		Params:   params,
+		Format:   format,
+		Columns:  columns,
+		NoHeader: c.QueryParam("header") == "false",
+		OnStart: func(total int) {
+			res.Header().Set(echo.HeaderContentType, format.ContentType())
+			res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, id, format))
+			res.Header().Set("X-Total-Count", strconv.Itoa(total))
+			res.Header().Set("X-Accel-Buffering", "no")
+			res.WriteHeader(http.StatusOK)
+		},
+		Progress: func(int) {
+			if f, ok := res.Writer.(http.Flusher); ok {
+				f.Flush()
+			}
+		},
+	}
+
+	_, err = h.sessionMgr.ExportEntries(c.Request().Context(), id, c.QueryParam("exportId"), res, q)
+	switch {
+	case err == nil:
+		return nil
+	case res.Committed:
+		// Too late for an error response; the client sees a truncated download
+		fmt.Printf("[Export] Session %s export failed after start: %v\n", id, err)
+		return nil
+	case errors.Is(err, session.ErrSessionNotFound):
+		return NewNotFoundError("session", id)
+	case errors.Is(err, session.ErrSessionNotReady):
+		return NewConflictError("session is not ready for queries")
+	case errors.Is(err, parser.ErrInvalidQuery):
+		return NewBadRequestError("invalid export request", err)
+	default:
+		return NewInternalError("export failed", err)
+	}
+}
+
+// HandleExportProgress returns the progress of a running or recently finished export
+func (h *ParseHandlerImpl) HandleExportProgress(c echo.Context) error {
+	id, exportID := c.Param("sessionId"), c.Param("exportId")
+	if id == "" {
+		return NewValidationError("sessionId")
+	}
+	if exportID == "" {
+		return NewValidationError("exportId")
+	}
+
+	progress, ok := h.sessionMgr.GetExportProgress(id, exportID)
+	if !ok {
+		return NewNotFoundError("export", exportID)
+	}
+
+	return c.JSON(http.StatusOK, progress)
+}
+
+// wantsArrow reports whether the client asked for an Arrow IPC stream, by Accept header
+// or with "format=arrow" for clients that cannot set headers
+func wantsArrow(c echo.Context) bool {
+	return c.QueryParam("format") == "arrow" ||
+		strings.Contains(c.Request().Header.Get(echo.HeaderAccept), parser.ArrowStreamMIME)
+}
+
+// arrowBlob encodes a response with write and sends it as an Arrow IPC stream.
+// The stream is buffered so encoding errors still get a JSON error response.
+func arrowBlob(c echo.Context, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return NewInternalError("arrow encoding failed", err)
+	}
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	return c.Blob(http.StatusOK, parser.ArrowStreamMIME, buf.Bytes())
+}
+
 // Request/Response types
 
+// liveEntriesMessage is a stream message with the entries from index From on.
+// Total is the number of entries committed so far.
+type liveEntriesMessage struct {
+	Entries  []models.LogEntry `json:"entries"`
+	From     int               `json:"from"`
+	Progress float64           `json:"progress"`
+	Total    int               `json:"total"`
+}
+
+// liveProgressMessage is a stream message reporting parse progress. Lines counts the
+// lines read, Committed the entries queryable so far, spanning StartTime..EndTime (ms).
+type liveProgressMessage struct {
+	Status    models.SessionStatus `json:"status"`
+	Progress  float64              `json:"progress"`
+	Lines     int                  `json:"lines"`
+	Committed int                  `json:"committed"`
+	StartTime int64                `json:"startTime"`
+	EndTime   int64                `json:"endTime"`
+}
+
 type startParseRequest struct {
-	FileID  string   `json:"fileId"`
-	FileIDs []string `json:"fileIds"`
+	FileID   string   `json:"fileId"`
+	FileIDs  []string `json:"fileIds"`
+	Priority int      `json:"priority"`
 }
 
 func (r *startParseRequest) normalizeFileIDs() []string {
@@ -440,18 +829,91 @@ func (h *ParseHandlerImpl) resolveFilePaths(fileIDs []string) ([]string, []strin
 	return filePaths, validFileIDs, nil
 }
 
-func (h *ParseHandlerImpl) buildQueryParams(c echo.Context) parser.QueryParams {
+// buildQueryParams reads the entry filters of a request. A "filter" expression that
+// does not compile is a bad request; its details carry the error column.
+// With a "preset" ID, the preset supplies every filter the request leaves out, and the
+// filter expressions of both must match.
+func (h *ParseHandlerImpl) buildQueryParams(c echo.Context) (parser.QueryParams, error) {
+	preset, err := h.lookupPreset(c)
+	if err != nil {
+		return parser.QueryParams{}, err
+	}
+	if preset == nil {
+		preset = &models.FilterPreset{}
+	}
+
+	filter, err := parser.CompileFilter(c.QueryParam("filter"))
+	if err != nil {
+		return parser.QueryParams{}, NewBadRequestError("invalid filter", err)
+	}
+	presetFilter, err := parser.CompileFilter(preset.Filter)
+	if err != nil {
+		return parser.QueryParams{}, NewBadRequestError("invalid preset filter", err)
+	}
+	switch {
+	case filter == nil:
+		filter = presetFilter
+	case presetFilter != nil:
+		filter = &parser.Filter{
+			Source: presetFilter.Source + " AND " + filter.Source,
+			SQL:    "(" + presetFilter.SQL + ") AND (" + filter.SQL + ")",
+			Args:   append(append([]interface{}{}, presetFilter.Args...), filter.Args...),
+		}
+	}
+
+	q := c.QueryParams()
+	str := func(name, fallback string) string {
+		if q.Has(name) {
+			return q.Get(name)
+		}
+		return fallback
+	}
+	flag := func(name string, fallback bool) bool {
+		if q.Has(name) {
+			return q.Get(name) == "true"
+		}
+		return fallback
+	}
+	list := func(name string, fallback []string) []string {
+		if values, ok := q[name]; ok {
+			return values
+		}
+		return fallback
+	}
+
 	return parser.QueryParams{
-		Search:              c.QueryParam("search"),
-		SearchRegex:         c.QueryParam("regex") == "true",
-		SearchCaseSensitive: c.QueryParam("caseSensitive") == "true",
-		ShowChanged:         c.QueryParam("showChangedOnly") == "true",
-		Categories:          c.QueryParams()["categories"],
-		Signals:             c.QueryParams()["signals"],
-		SignalType:          c.QueryParam("signalType"),
-		SortColumn:          c.QueryParam("sortColumn"),
-		SortDirection:       c.QueryParam("sortDirection"),
+		Search:              str("search", preset.Search),
+		SearchRegex:         flag("regex", preset.Regex),
+		SearchCaseSensitive: flag("caseSensitive", preset.CaseSensitive),
+		SearchMode:          str("searchMode", preset.SearchMode),
+		ShowChanged:         flag("showChangedOnly", preset.ShowChangedOnly),
+		Categories:          list("categories", preset.Categories),
+		Signals:             list("signals", preset.Signals),
+		SignalType:          str("signalType", preset.SignalType),
+		SortColumn:          str("sortColumn", preset.SortColumn),
+		SortDirection:       str("sortDirection", preset.SortDirection),
+		Filter:              filter,
+		StartTs:             preset.StartTime,
+		EndTs:               preset.EndTime,
+	}, nil
+}
+
+// lookupPreset returns the filter preset named by the "preset" query parameter, or nil
+// if there is none. Workspace presets are only found from their own workspace.
+func (h *ParseHandlerImpl) lookupPreset(c echo.Context) (*models.FilterPreset, error) {
+	id := c.QueryParam("preset")
+	if id == "" {
+		return nil, nil
+	}
+	ws, err := requestWorkspace(c)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := h.sessionMgr.GetPreset(id)
+	if err != nil || !preset.VisibleIn(ws) {
+		return nil, NewNotFoundError("preset", id)
 	}
+	return preset, nil
 }
 
 func (h *ParseHandlerImpl) sendSSEData(c echo.Context, data interface{}) {
@@ -5,33 +5,43 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/parser"
+	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/testutil"
 )
 
 // MockSessionManager is a mock implementation for testing
 type MockSessionManager struct {
 	sessions map[string]*models.ParseSession
+	presets  map[string]*models.FilterPreset
+
+	// liveEntries is the number of committed entries GetLiveEntries reports
+	liveEntries int
 }
 
 func NewMockSessionManager() *MockSessionManager {
 	return &MockSessionManager{
 		sessions: make(map[string]*models.ParseSession),
+		presets:  make(map[string]*models.FilterPreset),
 	}
 }
 
-func (m *MockSessionManager) StartMultiSession(fileIDs []string, filePaths []string) (*models.ParseSession, error) {
+func (m *MockSessionManager) StartMultiSession(fileIDs []string, filePaths []string, priority int) (*models.ParseSession, error) {
 	session := &models.ParseSession{
-		ID:      "test-session-123",
-		FileIDs: fileIDs,
-		Status:  models.SessionStatusPending,
+		ID:       "test-session-123",
+		FileIDs:  fileIDs,
+		Status:   models.SessionStatusPending,
+		Priority: priority,
 	}
 	m.sessions[session.ID] = session
 	return session, nil
@@ -59,6 +69,16 @@ func (m *MockSessionManager) QueryEntries(ctx context.Context, id string, params
 	return []models.LogEntry{}, 0, true
 }
 
+func (m *MockSessionManager) QueryEntriesCursor(ctx context.Context, id string, params parser.QueryParams, cursor string, backward bool, limit int) (*parser.EntryPage, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	if cursor == "bad" {
+		return nil, parser.ErrInvalidQuery
+	}
+	return &parser.EntryPage{Entries: []models.LogEntry{}}, nil
+}
+
 func (m *MockSessionManager) GetSignals(id string) ([]string, bool) {
 	return []string{}, true
 }
@@ -83,6 +103,10 @@ func (m *MockSessionManager) GetIndexByTime(ctx context.Context, id string, para
 	return 0, true
 }
 
+func (m *MockSessionManager) CursorAtTime(ctx context.Context, id string, params parser.QueryParams, ts int64) (string, error) {
+	return "", nil
+}
+
 func (m *MockSessionManager) GetTimeTree(ctx context.Context, id string, params parser.QueryParams) ([]parser.TimeTreeEntry, bool) {
 	return []parser.TimeTreeEntry{}, true
 }
@@ -91,6 +115,213 @@ func (m *MockSessionManager) GetValuesAtTime(ctx context.Context, id string, ts
 	return []models.LogEntry{}, true
 }
 
+func (m *MockSessionManager) GetTransitions(ctx context.Context, id string, q parser.TransitionQuery) (*parser.TransitionReport, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.TransitionReport{Results: []parser.TransitionResult{}, Stats: []parser.TransitionStats{}}, nil
+}
+
+func (m *MockSessionManager) GetSignalStats(ctx context.Context, id string, signals []string) ([]parser.SignalStats, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return []parser.SignalStats{}, nil
+}
+
+func (m *MockSessionManager) GetWaveform(ctx context.Context, id string, startTs, endTs time.Time, width int, signals []string) (*parser.Waveform, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.Waveform{Signals: []parser.SignalWaveform{}}, nil
+}
+
+func (m *MockSessionManager) GetEdges(ctx context.Context, id string, q parser.EdgeQuery) (*parser.EdgeList, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.EdgeList{Edges: []parser.Edge{}, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+func (m *MockSessionManager) FindEdge(ctx context.Context, id string, q parser.EdgeQuery, from int64, forward bool) (*parser.Edge, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return nil, nil
+}
+
+func (m *MockSessionManager) FindSequences(ctx context.Context, id string, q parser.SequenceQuery) (*parser.SequenceResult, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.SequenceResult{Matches: []parser.SequenceMatch{}, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+func (m *MockSessionManager) FindCorrelations(ctx context.Context, id string, q parser.CorrelationQuery) (*parser.CorrelationResult, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.CorrelationResult{Target: q.Target, Window: q.Window, Candidates: []parser.CorrelationCandidate{}}, nil
+}
+
+func (m *MockSessionManager) GetAnomalies(ctx context.Context, id string, q parser.AnomalyQuery) (*parser.AnomalyReport, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.AnomalyReport{Anomalies: []parser.Anomaly{}, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+func (m *MockSessionManager) DiffSessions(ctx context.Context, id, otherID string, q parser.DiffQuery) (*parser.SessionDiff, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	if _, ok := m.sessions[otherID]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.SessionDiff{Offset: q.Offset, Signals: []parser.SignalDiff{}, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+func (m *MockSessionManager) DiffSessionSignal(ctx context.Context, id, otherID, key string, offset int64, limit int) (*parser.SignalDiffDetail, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	if _, ok := m.sessions[otherID]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.SignalDiffDetail{Key: key, Offset: offset, Ranges: []parser.DiffRange{}}, nil
+}
+
+func (m *MockSessionManager) RunSQL(ctx context.Context, id, queryID string, q parser.SQLQuery) (*parser.SQLResult, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return &parser.SQLResult{Columns: []parser.SQLColumn{}, Data: [][]interface{}{}}, nil
+}
+
+func (m *MockSessionManager) RunSQLArrow(ctx context.Context, id, queryID string, w io.Writer, q parser.SQLQuery) (int, bool, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return 0, false, session.ErrSessionNotFound
+	}
+	return 0, false, parser.WriteArrowEntries(w, nil, nil)
+}
+
+func (m *MockSessionManager) CancelSQL(id, queryID string) error {
+	return session.ErrQueryNotFound
+}
+
+func (m *MockSessionManager) GetPreset(id string) (*models.FilterPreset, error) {
+	p, ok := m.presets[id]
+	if !ok {
+		return nil, session.ErrPresetNotFound
+	}
+	return p, nil
+}
+
+func (m *MockSessionManager) ExportEntries(ctx context.Context, id, exportID string, w io.Writer, q parser.ExportQuery) (int, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return 0, session.ErrSessionNotFound
+	}
+	if q.Format != parser.ExportCSV && q.Format != parser.ExportNDJSON {
+		return 0, parser.ErrInvalidQuery
+	}
+	q.OnStart(1)
+	_, err := io.WriteString(w, "timestamp,deviceId\n")
+	return 1, err
+}
+
+func (m *MockSessionManager) GetExportProgress(id, exportID string) (*models.ExportProgress, bool) {
+	return nil, false
+}
+
+func (m *MockSessionManager) CancelSession(id string) error {
+	sess, ok := m.sessions[id]
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	if sess.Status.Finished() {
+		delete(m.sessions, id)
+	} else {
+		sess.Status = models.SessionStatusCancelled
+	}
+	return nil
+}
+
+func (m *MockSessionManager) PauseSession(id string) error {
+	sess, ok := m.sessions[id]
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	if sess.Status != models.SessionStatusParsing {
+		return session.ErrSessionState
+	}
+	sess.Status = models.SessionStatusPaused
+	return nil
+}
+
+func (m *MockSessionManager) SetPriority(id string, priority int) error {
+	sess, ok := m.sessions[id]
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	sess.Priority = priority
+	return nil
+}
+
+func (m *MockSessionManager) ResumeSession(id string) error {
+	sess, ok := m.sessions[id]
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	if sess.Status != models.SessionStatusPaused {
+		return session.ErrSessionState
+	}
+	sess.Status = models.SessionStatusParsing
+	return nil
+}
+
+func (m *MockSessionManager) SubscribeSession(id string) (<-chan struct{}, func(), bool) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, nil, false
+	}
+	return make(chan struct{}), func() {}, true
+}
+
+func (m *MockSessionManager) GetLiveEntries(ctx context.Context, id string, from, limit int) ([]models.LogEntry, parser.CommitInfo, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, parser.CommitInfo{}, session.ErrSessionNotFound
+	}
+	total := m.liveEntries
+	if from >= total {
+		return []models.LogEntry{}, parser.CommitInfo{Count: total}, nil
+	}
+	n := total - from
+	if n > limit {
+		n = limit
+	}
+	return make([]models.LogEntry, n), parser.CommitInfo{Count: total}, nil
+}
+
+func (m *MockSessionManager) SetDerivedSignal(ctx context.Context, id string, def parser.DerivedSignal) (parser.DerivedSignal, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return def, session.ErrSessionNotFound
+	}
+	return def, nil
+}
+
+func (m *MockSessionManager) ListDerivedSignals(id string) ([]parser.DerivedSignal, error) {
+	if _, ok := m.sessions[id]; !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return []parser.DerivedSignal{}, nil
+}
+
+func (m *MockSessionManager) DeleteDerivedSignal(id string, key string) error {
+	if _, ok := m.sessions[id]; !ok {
+		return session.ErrSessionNotFound
+	}
+	return session.ErrDerivedSignalNotFound
+}
+
 func TestParseHandler_HandleStartParse(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -362,6 +593,316 @@ func TestParseHandler_HandleSessionKeepAlive(t *testing.T) {
 	}
 }
 
+func TestParseHandler_HandleParseEntriesFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr bool
+	}{
+		{name: "valid filter", filter: "device:B1* AND value>3"},
+		{name: "no filter", filter: ""},
+		{name: "syntax error", filter: "signal:Error AND (", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewParseHandler(testutil.NewMockStorage(), NewMockSessionManager())
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/parse/s1/entries?filter="+url.QueryEscape(tt.filter), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("sessionId")
+			c.SetParamValues("s1")
+
+			err := handler.HandleParseEntries(c)
+			if !tt.wantErr {
+				if err != nil || rec.Code != http.StatusOK {
+					t.Errorf("expected 200, got %d (%v)", rec.Code, err)
+				}
+				return
+			}
+			apiErr, ok := err.(*APIError)
+			if !ok || apiErr.Code != "BAD_REQUEST" {
+				t.Fatalf("expected a BAD_REQUEST APIError, got %v", err)
+			}
+			if !strings.Contains(apiErr.Details, "column 19") {
+				t.Errorf("expected the error column in the details, got %q", apiErr.Details)
+			}
+		})
+	}
+}
+
+func TestParseHandler_HandleParseEntriesCursor(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.sessions["s1"] = &models.ParseSession{ID: "s1", Status: models.SessionStatusComplete}
+	handler := NewParseHandler(testutil.NewMockStorage(), mgr)
+
+	tests := []struct {
+		name     string
+		session  string
+		query    string
+		wantCode string
+	}{
+		{name: "first page", session: "s1", query: "direction=next"},
+		{name: "from cursor", session: "s1", query: "cursor=abc&direction=prev"},
+		{name: "bad cursor", session: "s1", query: "cursor=bad", wantCode: "BAD_REQUEST"},
+		{name: "bad direction", session: "s1", query: "direction=up", wantCode: "VALIDATION_ERROR"},
+		{name: "unknown session", session: "s2", query: "cursor=", wantCode: "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/parse/"+tt.session+"/entries?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetParamNames("sessionId")
+			c.SetParamValues(tt.session)
+
+			err := handler.HandleParseEntries(c)
+			if tt.wantCode == "" {
+				if err != nil || rec.Code != http.StatusOK {
+					t.Fatalf("expected 200, got %d (%v)", rec.Code, err)
+				}
+				var page parser.EntryPage
+				if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || page.Entries == nil {
+					t.Errorf("expected an entry page, got %s", rec.Body.String())
+				}
+				return
+			}
+			if apiErr, ok := err.(*APIError); !ok || apiErr.Code != tt.wantCode {
+				t.Errorf("expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestParseHandler_HandleExportEntries(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.sessions["s1"] = &models.ParseSession{ID: "s1", Status: models.SessionStatusComplete}
+	handler := NewParseHandler(testutil.NewMockStorage(), mgr)
+
+	newContext := func(session, query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/parse/"+session+"/export?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("sessionId")
+		c.SetParamValues(session)
+		return c, rec
+	}
+
+	c, rec := newContext("s1", "format=csv&start=1000")
+	if err := handler.HandleExportEntries(c); err != nil {
+		t.Fatalf("HandleExportEntries failed: %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != "1" ||
+		!strings.Contains(rec.Header().Get(echo.HeaderContentDisposition), `filename="s1.csv"`) {
+		t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	c, _ = newContext("s1", "format=pdf")
+	if apiErr, ok := handler.HandleExportEntries(c).(*APIError); !ok || apiErr.Code != "BAD_REQUEST" {
+		t.Errorf("expected BAD_REQUEST for an unknown format")
+	}
+	c, _ = newContext("s1", "start=soon")
+	if apiErr, ok := handler.HandleExportEntries(c).(*APIError); !ok || apiErr.Code != "BAD_REQUEST" {
+		t.Errorf("expected BAD_REQUEST for an invalid start time")
+	}
+	c, _ = newContext("s2", "")
+	if apiErr, ok := handler.HandleExportEntries(c).(*APIError); !ok || apiErr.Code != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND for an unknown session")
+	}
+}
+
+func TestParseHandler_ArrowResponses(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.sessions["s1"] = &models.ParseSession{ID: "s1", Status: models.SessionStatusComplete}
+	handler := NewParseHandler(testutil.NewMockStorage(), mgr)
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		handle func(echo.Context) error
+	}{
+		{name: "entries by accept", path: "entries?page=1", accept: parser.ArrowStreamMIME, handle: handler.HandleParseEntries},
+		{name: "entries by cursor", path: "entries?direction=next&format=arrow", handle: handler.HandleParseEntries},
+		{name: "chunk", path: "chunk?start=0&end=1000", accept: parser.ArrowStreamMIME + ", application/json", handle: handler.HandleParseChunk},
+		{name: "boundaries", path: "chunk-boundaries?start=0&end=1000&format=arrow", handle: handler.HandleParseChunkBoundaries},
+		{name: "values at time", path: "values-at-time?timestamp=1000&format=arrow", handle: handler.HandleGetValuesAtTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/parse/s1/"+tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetParamNames("sessionId")
+			c.SetParamValues("s1")
+
+			if err := tt.handle(c); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d (%v)", rec.Code, err)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != parser.ArrowStreamMIME {
+				t.Errorf("expected an Arrow stream, got %s", ct)
+			}
+			// An IPC stream starts with the continuation marker of its schema message
+			if !bytes.HasPrefix(rec.Body.Bytes(), []byte{0xff, 0xff, 0xff, 0xff}) {
+				t.Errorf("body is not an Arrow IPC stream")
+			}
+		})
+	}
+}
+
+func TestParseHandler_HandleParseStream(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.sessions["s1"] = &models.ParseSession{ID: "s1", Status: models.SessionStatusComplete}
+	mgr.liveEntries = 2500
+	handler := NewParseHandler(testutil.NewMockStorage(), mgr)
+
+	newContext := func(session, query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/parse/"+session+"/stream?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("sessionId")
+		c.SetParamValues(session)
+		return c, rec
+	}
+
+	c, rec := newContext("s1", "from=500")
+	if err := handler.HandleParseStream(c); err != nil {
+		t.Fatalf("HandleParseStream failed: %v", err)
+	}
+	var froms []int
+	var done map[string]interface{}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg); err != nil {
+			t.Fatalf("invalid message %q", line)
+		}
+		if msg["entries"] != nil {
+			froms = append(froms, int(msg["from"].(float64)))
+		}
+		if msg["done"] != nil {
+			done = msg
+		}
+	}
+	if len(froms) != 2 || froms[0] != 500 || froms[1] != 1500 {
+		t.Errorf("expected batches from 500 and 1500, got %v", froms)
+	}
+	if done == nil || done["total"].(float64) != 2500 {
+		t.Errorf("expected a done message with total 2500, got %v", done)
+	}
+
+	c, _ = newContext("s2", "")
+	if apiErr, ok := handler.HandleParseStream(c).(*APIError); !ok || apiErr.Code != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND for an unknown session")
+	}
+}
+
+func TestParseHandler_PauseResumeCancel(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.sessions["s1"] = &models.ParseSession{ID: "s1", Status: models.SessionStatusParsing}
+	handler := NewParseHandler(testutil.NewMockStorage(), mgr)
+
+	call := func(handle func(echo.Context) error, session string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/parse/"+session, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("sessionId")
+		c.SetParamValues(session)
+		return rec, handle(c)
+	}
+
+	steps := []struct {
+		name       string
+		handle     func(echo.Context) error
+		session    string
+		wantCode   string
+		wantStatus models.SessionStatus
+	}{
+		{name: "resume running", handle: handler.HandleResumeParse, session: "s1", wantCode: "CONFLICT"},
+		{name: "pause", handle: handler.HandlePauseParse, session: "s1", wantStatus: models.SessionStatusPaused},
+		{name: "pause paused", handle: handler.HandlePauseParse, session: "s1", wantCode: "CONFLICT"},
+		{name: "resume", handle: handler.HandleResumeParse, session: "s1", wantStatus: models.SessionStatusParsing},
+		{name: "cancel", handle: handler.HandleCancelParse, session: "s1", wantStatus: models.SessionStatusCancelled},
+		{name: "remove", handle: handler.HandleCancelParse, session: "s1"},
+		{name: "unknown", handle: handler.HandleCancelParse, session: "s1", wantCode: "NOT_FOUND"},
+	}
+	for _, step := range steps {
+		rec, err := call(step.handle, step.session)
+		if step.wantCode != "" {
+			if apiErr, ok := err.(*APIError); !ok || apiErr.Code != step.wantCode {
+				t.Errorf("%s: expected %s, got %v", step.name, step.wantCode, err)
+			}
+			continue
+		}
+		if err != nil || rec.Code >= 300 {
+			t.Fatalf("%s: unexpected %d (%v)", step.name, rec.Code, err)
+		}
+		if step.wantStatus != "" && mgr.sessions["s1"].Status != step.wantStatus {
+			t.Errorf("%s: expected status %s, got %s", step.name, step.wantStatus, mgr.sessions["s1"].Status)
+		}
+	}
+}
+
+func TestParseHandler_BuildQueryParamsPreset(t *testing.T) {
+	mgr := NewMockSessionManager()
+	mgr.presets["alarms"] = &models.FilterPreset{
+		ID:         "alarms",
+		Name:       "Line 3 alarms",
+		Workspace:  "line3",
+		Search:     "alarm",
+		Categories: []string{"OUTPUT"},
+		Signals:    []string{"B3::Alarm"},
+		Filter:     "device:B3*",
+		StartTime:  1000,
+		EndTime:    2000,
+	}
+	handler := &ParseHandlerImpl{store: testutil.NewMockStorage(), sessionMgr: mgr}
+
+	newContext := func(query string) echo.Context {
+		req := httptest.NewRequest(http.MethodGet, "/api/parse/s1/entries?"+query, nil)
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+
+	params, err := handler.buildQueryParams(newContext("preset=alarms&workspace=line3&search=fault&filter=" + url.QueryEscape("value>3")))
+	if err != nil {
+		t.Fatalf("buildQueryParams failed: %v", err)
+	}
+	if params.Search != "fault" {
+		t.Errorf("expected the request search to override the preset, got %q", params.Search)
+	}
+	if len(params.Categories) != 1 || params.Categories[0] != "OUTPUT" || len(params.Signals) != 1 {
+		t.Errorf("expected the preset categories and signals, got %v %v", params.Categories, params.Signals)
+	}
+	if params.StartTs != 1000 || params.EndTs != 2000 {
+		t.Errorf("expected the preset time window, got %d..%d", params.StartTs, params.EndTs)
+	}
+	if params.Filter == nil || len(params.Filter.Args) != 2 || !strings.Contains(params.Filter.SQL, ") AND (") {
+		t.Errorf("expected the preset and request filters combined, got %+v", params.Filter)
+	}
+
+	// Workspace presets are not visible from other workspaces
+	_, err = handler.buildQueryParams(newContext("preset=alarms&workspace=line1"))
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Code != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND for a preset of another workspace, got %v", err)
+	}
+	c := newContext("preset=alarms")
+	c.Request().Header.Set(WorkspaceHeader, "line3")
+	if _, err := handler.buildQueryParams(c); err != nil {
+		t.Errorf("expected the preset to be found from the X-Workspace header, got %v", err)
+	}
+	_, err = handler.buildQueryParams(newContext("preset=missing"))
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Code != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND for an unknown preset, got %v", err)
+	}
+}
+
 func TestStartParseRequest_NormalizeFileIDs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,141 @@
+// handlers_preset.go - Saved filter preset handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/models"
+	"github.com/plc-visualizer/backend/internal/parser"
+	"github.com/plc-visualizer/backend/internal/session"
+)
+
+// PresetHandlerImpl implements the PresetHandler interface
+type PresetHandlerImpl struct {
+	sessionMgr *session.Manager
+}
+
+// NewPresetHandler creates a new preset handler instance
+func NewPresetHandler(sessionMgr *session.Manager) PresetHandler {
+	return &PresetHandlerImpl{
+		sessionMgr: sessionMgr,
+	}
+}
+
+// HandleListPresets returns the global presets and those of the requested workspace
+// (every preset with all=true)
+func (h *PresetHandlerImpl) HandleListPresets(c echo.Context) error {
+	ws, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
+	presets, err := h.sessionMgr.ListPresets(ws, c.QueryParam("all") == "true")
+	if err != nil {
+		return NewInternalError("failed to load presets", err)
+	}
+
+	return c.JSON(http.StatusOK, presets)
+}
+
+// HandleGetPreset returns a single preset global or of the requested workspace
+func (h *PresetHandlerImpl) HandleGetPreset(c echo.Context) error {
+	id := c.Param("presetId")
+	if id == "" {
+		return NewValidationError("presetId")
+	}
+	ws, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+
+	preset, err := h.sessionMgr.GetPreset(id)
+	if err != nil {
+		return presetError(err, id)
+	}
+	if !preset.VisibleIn(ws) {
+		return NewNotFoundError("preset", id)
+	}
+
+	return c.JSON(http.StatusOK, preset)
+}
+
+// HandleCreatePreset saves a new preset
+func (h *PresetHandlerImpl) HandleCreatePreset(c echo.Context) error {
+	var req models.FilterPreset
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if err := validatePreset(&req); err != nil {
+		return err
+	}
+
+	preset, err := h.sessionMgr.AddPreset(req)
+	if err != nil {
+		return NewInternalError("failed to save preset", err)
+	}
+
+	return c.JSON(http.StatusCreated, preset)
+}
+
+// HandleUpdatePreset replaces an existing preset
+func (h *PresetHandlerImpl) HandleUpdatePreset(c echo.Context) error {
+	id := c.Param("presetId")
+	if id == "" {
+		return NewValidationError("presetId")
+	}
+
+	var req models.FilterPreset
+	if err := c.Bind(&req); err != nil {
+		return NewBadRequestError("invalid request body", err)
+	}
+	if err := validatePreset(&req); err != nil {
+		return err
+	}
+
+	preset, err := h.sessionMgr.UpdatePreset(id, req)
+	if err != nil {
+		return presetError(err, id)
+	}
+
+	return c.JSON(http.StatusOK, preset)
+}
+
+// HandleDeletePreset removes a preset
+func (h *PresetHandlerImpl) HandleDeletePreset(c echo.Context) error {
+	id := c.Param("presetId")
+	if id == "" {
+		return NewValidationError("presetId")
+	}
+
+	if err := h.sessionMgr.DeletePreset(id); err != nil {
+		return presetError(err, id)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Helper functions
+
+// validatePreset checks a preset before it is saved, so broken presets cannot fail every
+// request that applies them later.
+func validatePreset(p *models.FilterPreset) error {
+	if p.Name == "" {
+		return NewValidationError("name")
+	}
+	if _, err := parser.CompileFilter(p.Filter); err != nil {
+		return NewBadRequestError("invalid filter", err)
+	}
+	if p.EndTime > 0 && p.EndTime < p.StartTime {
+		return NewValidationError("endTime")
+	}
+	return nil
+}
+
+func presetError(err error, id string) error {
+	if errors.Is(err, session.ErrPresetNotFound) {
+		return NewNotFoundError("preset", id)
+	}
+	return NewInternalError("preset operation failed", err)
+}
@@ -7,10 +7,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/plc-visualizer/backend/internal/models"
+	"github.com/plc-visualizer/backend/internal/audit"
 	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/storage"
 	"github.com/plc-visualizer/backend/internal/upload"
@@ -31,6 +37,7 @@ func setupTestHandlers(t *testing.T) (*Dependencies, *Handlers, *echo.Echo) {
 		UploadMgr:  uploadMgr,
 		DataDir:    tmpDir,
 		Version:    "test",
+		Audit:      audit.NewLog(tmpDir),
 	}
 	handlers := NewHandlers(deps)
 	return deps, handlers, e
@@ -197,8 +204,7 @@ func TestSetActiveMap(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 	}
 
-	// 3. Verify active map via GetMapLayout (mocking currentMapID behavior)
-	// Since HandleGetMapLayout uses h.currentMapID which we just set
+	// 3. Verify active map via GetMapLayout in the same (default) workspace
 	reqGet := httptest.NewRequest(http.MethodGet, "/api/map/layout", nil)
 	recGet := httptest.NewRecorder()
 	cGet := e.NewContext(reqGet, recGet)
@@ -207,3 +213,108 @@ func TestSetActiveMap(t *testing.T) {
 		assert.Contains(t, recGet.Body.String(), info.ID)
 	}
 }
+
+func TestExportThroughMiddleware(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+	deps, handlers, e := setupTestHandlers(t)
+
+	// More rows than one progress interval, so the export flushes mid-stream
+	const rows = 12000
+	var log strings.Builder
+	base := time.Date(2025, 9, 22, 13, 0, 0, 0, time.UTC)
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&log, "%s [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Integer) : %d\n",
+			base.Add(time.Duration(i)*time.Millisecond).Format("2006-01-02 15:04:05.000"), i)
+	}
+	path := filepath.Join(tmpDir, "export.log")
+	if err := os.WriteFile(path, []byte(log.String()), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	sess, err := deps.SessionMgr.StartSession("file-export", path, 0)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s, _ := deps.SessionMgr.GetSession(sess.ID)
+		if s.Status == models.SessionStatusComplete {
+			break
+		}
+		if s.Status == models.SessionStatusError || time.Now().After(deadline) {
+			t.Fatalf("Parse did not complete, status %s", s.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The middleware of the server, with a timeout shorter than the export
+	SetupMiddleware(e)
+	e.Use(middleware.Recover())
+	e.Use(TimeoutMiddleware(time.Nanosecond))
+	e.Use(middleware.Gzip())
+	e.GET("/api/parse/:sessionId/export", handlers.Parse.HandleExportEntries)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/parse/" + sess.ID + "/export?format=ndjson")
+	if err != nil {
+		t.Fatalf("Export request failed: %v", err)
+	}
+	defer res.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(res.Body)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.StatusCode, body.String())
+	}
+	if lines := strings.Count(body.String(), "\n"); lines != rows {
+		t.Errorf("Expected %d exported rows, got %d", rows, lines)
+	}
+}
+
+func TestGetPresetWorkspace(t *testing.T) {
+	t.Setenv("PARSED_DB_DIR", filepath.Join(t.TempDir(), "parsed"))
+	deps, handlers, e := setupTestHandlers(t)
+	preset, err := deps.SessionMgr.AddPreset(models.FilterPreset{Name: "Alarms", Workspace: "line3"})
+	if err != nil {
+		t.Fatalf("AddPreset failed: %v", err)
+	}
+
+	get := func(workspace string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/presets/"+preset.ID, nil)
+		req.Header.Set(WorkspaceHeader, workspace)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("presetId")
+		c.SetParamValues(preset.ID)
+		if err := handlers.Preset.HandleGetPreset(c); err != nil {
+			ErrorHandler(err, c)
+		}
+		return rec
+	}
+	if rec := get("line3"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 from the preset's workspace, got %d", rec.Code)
+	}
+	if rec := get("line1"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 from another workspace, got %d", rec.Code)
+	}
+}
+
+func TestListPresetsWorkspaceHeader(t *testing.T) {
+	t.Setenv("PARSED_DB_DIR", filepath.Join(t.TempDir(), "parsed"))
+	deps, handlers, e := setupTestHandlers(t)
+	deps.SessionMgr.AddPreset(models.FilterPreset{Name: "Global"})
+	deps.SessionMgr.AddPreset(models.FilterPreset{Name: "Line 3", Workspace: "line3"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/presets", nil)
+	req.Header.Set(WorkspaceHeader, "line3")
+	rec := httptest.NewRecorder()
+	if err := handlers.Preset.HandleListPresets(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("HandleListPresets failed: %v", err)
+	}
+	var presets []models.FilterPreset
+	json.Unmarshal(rec.Body.Bytes(), &presets)
+	if len(presets) != 2 {
+		t.Errorf("Expected the global and the line3 preset, got %+v", presets)
+	}
+}
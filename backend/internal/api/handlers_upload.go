@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/storage"
@@ -19,14 +20,16 @@ type UploadHandlerImpl struct {
 	store         storage.Store
 	sessionMgr    *session.Manager
 	uploadManager *upload.Manager
+	audit         *audit.Log
 }
 
 // NewUploadHandler creates a new upload handler instance
-func NewUploadHandler(store storage.Store, sessionMgr *session.Manager, uploadMgr *upload.Manager) UploadHandler {
+func NewUploadHandler(store storage.Store, sessionMgr *session.Manager, uploadMgr *upload.Manager, auditLog *audit.Log) UploadHandler {
 	return &UploadHandlerImpl{
 		store:         store,
 		sessionMgr:    sessionMgr,
 		uploadManager: uploadMgr,
+		audit:         auditLog,
 	}
 }
 
@@ -52,6 +55,9 @@ func (h *UploadHandlerImpl) HandleUploadFile(c echo.Context) error {
 	if err != nil {
 		return NewInternalError("failed to save file", err)
 	}
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionFileUpload, Target: info.ID, After: info.Name, Detail: "http",
+	})
 
 	return c.JSON(http.StatusCreated, info)
 }
@@ -92,7 +98,8 @@ func (h *UploadHandlerImpl) HandleCompleteUpload(c echo.Context) error {
 		return err
 	}
 
-	// Start async processing job
+	// Start async processing job; the upload is audited once the file exists
+	id := IdentityFrom(c)
 	job := h.uploadManager.StartJob(
 		req.UploadID,
 		req.Name,
@@ -100,6 +107,15 @@ func (h *UploadHandlerImpl) HandleCompleteUpload(c echo.Context) error {
 		req.OriginalSize,
 		req.CompressedSize,
 		req.Encoding,
+		func(job *upload.Job) {
+			if job.FileInfo == nil {
+				return
+			}
+			recordAudit(h.audit, id, audit.Entry{
+				Action: audit.ActionFileUpload, Target: job.FileInfo.ID, After: job.FileInfo.Name,
+				Detail: "http chunked, job " + job.ID,
+			})
+		},
 	)
 
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
@@ -128,6 +144,9 @@ func (h *UploadHandlerImpl) HandleUploadBinary(c echo.Context) error {
 	if err != nil {
 		return NewInternalError("failed to save file", err)
 	}
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionFileUpload, Target: info.ID, After: info.Name, Detail: "http binary",
+	})
 
 	return c.JSON(http.StatusCreated, info)
 }
@@ -172,9 +191,14 @@ func (h *UploadHandlerImpl) HandleDeleteFile(c echo.Context) error {
 		return NewValidationError("id")
 	}
 
+	var name string
+	if info, err := h.store.Get(id); err == nil {
+		name = info.Name
+	}
 	if err := h.store.Delete(id); err != nil {
 		return NewNotFoundError("file", id)
 	}
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{Action: audit.ActionFileDelete, Target: id, Before: name})
 
 	// Clean up associated parsed data
 	if h.sessionMgr != nil {
@@ -200,10 +224,17 @@ func (h *UploadHandlerImpl) HandleRenameFile(c echo.Context) error {
 		return NewValidationError("name")
 	}
 
+	var before string
+	if old, err := h.store.Get(id); err == nil {
+		before = old.Name
+	}
 	info, err := h.store.Rename(id, req.Name)
 	if err != nil {
 		return NewNotFoundError("file", id)
 	}
+	recordAudit(h.audit, IdentityFrom(c), audit.Entry{
+		Action: audit.ActionFileRename, Target: id, Before: before, After: info.Name,
+	})
 
 	return c.JSON(http.StatusOK, info)
 }
@@ -3,6 +3,7 @@ package api
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -31,6 +32,10 @@ type ParseHandler interface {
 	HandleParseEntries(c echo.Context) error
 	HandleParseEntriesMsgpack(c echo.Context) error
 	HandleParseStream(c echo.Context) error
+	HandleCancelParse(c echo.Context) error
+	HandlePauseParse(c echo.Context) error
+	HandleResumeParse(c echo.Context) error
+	HandleParsePriority(c echo.Context) error
 	HandleParseChunk(c echo.Context) error
 	HandleParseChunkBoundaries(c echo.Context) error
 	HandleGetSignals(c echo.Context) error
@@ -40,6 +45,8 @@ type ParseHandler interface {
 	HandleGetTimeTree(c echo.Context) error
 	HandleGetValuesAtTime(c echo.Context) error
 	HandleSessionKeepAlive(c echo.Context) error
+	HandleExportEntries(c echo.Context) error
+	HandleExportProgress(c echo.Context) error
 }
 
 // MapHandler handles map configuration operations
@@ -54,11 +61,12 @@ type MapHandler interface {
 	HandleLoadDefaultMap(c echo.Context) error
 	HandleGetValidationRules(c echo.Context) error
 	HandleUpdateValidationRules(c echo.Context) error
-	GetCurrentMap() string
-	SetCurrentMap(mapID string)
-	GetCurrentRules() (string, *models.MapRules)
-	SetCurrentRules(rulesID string, rules *models.MapRules)
+	GetCurrentMap(workspace string) string
+	SetCurrentMap(workspace, mapID string)
+	GetCurrentRules(workspace string) (string, *models.MapRules)
+	SetCurrentRules(workspace, rulesID string, rules *models.MapRules)
 	LoadDefaultRules() error
+	ExpireWorkspaces(maxIdle time.Duration) int
 }
 
 // CarrierHandler handles carrier tracking operations
@@ -66,13 +74,70 @@ type CarrierHandler interface {
 	HandleUploadCarrierLog(c echo.Context) error
 	HandleGetCarrierLog(c echo.Context) error
 	HandleGetCarrierEntries(c echo.Context) error
-	GetCarrierSessionID() string
-	SetCarrierSessionID(sessionID string)
+	GetCarrierSessionID(workspace string) string
+	SetCarrierSessionID(workspace, sessionID string)
+	ExpireWorkspaces(maxIdle time.Duration) int
+}
+
+// AnnotationHandler handles persistent annotations on log files
+type AnnotationHandler interface {
+	HandleListAnnotations(c echo.Context) error
+	HandleCreateAnnotation(c echo.Context) error
+	HandleUpdateAnnotation(c echo.Context) error
+	HandleDeleteAnnotation(c echo.Context) error
+	HandleGetSessionAnnotations(c echo.Context) error
+}
+
+// PresetHandler handles saved filter presets
+type PresetHandler interface {
+	HandleListPresets(c echo.Context) error
+	HandleGetPreset(c echo.Context) error
+	HandleCreatePreset(c echo.Context) error
+	HandleUpdatePreset(c echo.Context) error
+	HandleDeletePreset(c echo.Context) error
+}
+
+// AnalysisHandler handles server-side analysis of parsed sessions
+type AnalysisHandler interface {
+	HandleGetTransitions(c echo.Context) error
+	HandleGetSignalStats(c echo.Context) error
+	HandleGetWaveform(c echo.Context) error
+	HandleGetEdges(c echo.Context) error
+	HandleFindEdge(c echo.Context) error
+	HandleFindSequences(c echo.Context) error
+	HandleFindCorrelations(c echo.Context) error
+	HandleGetAnomalies(c echo.Context) error
+	HandleDiffSessions(c echo.Context) error
+	HandleDiffSessionSignal(c echo.Context) error
+	HandleRunSQL(c echo.Context) error
+	HandleCancelSQL(c echo.Context) error
+	HandleListDerivedSignals(c echo.Context) error
+	HandleSetDerivedSignal(c echo.Context) error
+	HandleDeleteDerivedSignal(c echo.Context) error
+}
+
+// AuthHandler handles login and local user management
+type AuthHandler interface {
+	HandleLogin(c echo.Context) error
+	HandleLogout(c echo.Context) error
+	HandleMe(c echo.Context) error
+	HandleListUsers(c echo.Context) error
+	HandleCreateUser(c echo.Context) error
+	HandleUpdateUser(c echo.Context) error
+	HandleDeleteUser(c echo.Context) error
+}
+
+// AdminHandler handles server administration
+type AdminHandler interface {
+	HandleGetConfig(c echo.Context) error
+	HandleUpdateConfig(c echo.Context) error
+	HandleGetAudit(c echo.Context) error
 }
 
 // HealthHandler handles health check operations
 type HealthHandler interface {
 	HandleHealth(c echo.Context) error
+	HandleResources(c echo.Context) error
 }
 
 // UploadJobHandler handles upload job streaming
@@ -83,20 +148,47 @@ type UploadJobHandler interface {
 // SessionManager defines the interface for session management
 // This allows mocking in tests
 type SessionManager interface {
-	StartMultiSession(fileIDs []string, filePaths []string) (*models.ParseSession, error)
+	StartMultiSession(fileIDs []string, filePaths []string, priority int) (*models.ParseSession, error)
 	GetSession(id string) (*models.ParseSession, bool)
 	TouchSession(id string) bool
 	DeleteParsedFile(fileID string) error
 	GetEntries(ctx context.Context, id string, page, pageSize int) ([]models.LogEntry, int, bool)
 	QueryEntries(ctx context.Context, id string, params parser.QueryParams, page, pageSize int) ([]models.LogEntry, int, bool)
+	QueryEntriesCursor(ctx context.Context, id string, params parser.QueryParams, cursor string, backward bool, limit int) (*parser.EntryPage, error)
 	GetChunk(ctx context.Context, id string, start, end time.Time, signals []string) ([]models.LogEntry, bool)
 	GetBoundaryValues(ctx context.Context, id string, start, end time.Time, signals []string) (*parser.BoundaryValues, bool)
 	GetSignals(id string) ([]string, bool)
 	GetSignalTypes(id string) (map[string]string, bool)
 	GetCategories(ctx context.Context, id string) ([]string, bool)
 	GetIndexByTime(ctx context.Context, id string, params parser.QueryParams, ts int64) (int, bool)
+	CursorAtTime(ctx context.Context, id string, params parser.QueryParams, ts int64) (string, error)
 	GetTimeTree(ctx context.Context, id string, params parser.QueryParams) ([]parser.TimeTreeEntry, bool)
 	GetValuesAtTime(ctx context.Context, id string, ts time.Time, signals []string) ([]models.LogEntry, bool)
+	GetTransitions(ctx context.Context, id string, q parser.TransitionQuery) (*parser.TransitionReport, error)
+	GetSignalStats(ctx context.Context, id string, signals []string) ([]parser.SignalStats, error)
+	GetWaveform(ctx context.Context, id string, startTs, endTs time.Time, width int, signals []string) (*parser.Waveform, error)
+	GetEdges(ctx context.Context, id string, q parser.EdgeQuery) (*parser.EdgeList, error)
+	FindEdge(ctx context.Context, id string, q parser.EdgeQuery, from int64, forward bool) (*parser.Edge, error)
+	FindSequences(ctx context.Context, id string, q parser.SequenceQuery) (*parser.SequenceResult, error)
+	FindCorrelations(ctx context.Context, id string, q parser.CorrelationQuery) (*parser.CorrelationResult, error)
+	GetAnomalies(ctx context.Context, id string, q parser.AnomalyQuery) (*parser.AnomalyReport, error)
+	DiffSessions(ctx context.Context, id, otherID string, q parser.DiffQuery) (*parser.SessionDiff, error)
+	DiffSessionSignal(ctx context.Context, id, otherID, key string, offset int64, limit int) (*parser.SignalDiffDetail, error)
+	RunSQL(ctx context.Context, id, queryID string, q parser.SQLQuery) (*parser.SQLResult, error)
+	RunSQLArrow(ctx context.Context, id, queryID string, w io.Writer, q parser.SQLQuery) (int, bool, error)
+	CancelSQL(id, queryID string) error
+	SetDerivedSignal(ctx context.Context, id string, def parser.DerivedSignal) (parser.DerivedSignal, error)
+	ListDerivedSignals(id string) ([]parser.DerivedSignal, error)
+	DeleteDerivedSignal(id string, key string) error
+	GetPreset(id string) (*models.FilterPreset, error)
+	CancelSession(id string) error
+	PauseSession(id string) error
+	ResumeSession(id string) error
+	SetPriority(id string, priority int) error
+	SubscribeSession(id string) (<-chan struct{}, func(), bool)
+	GetLiveEntries(ctx context.Context, id string, from, limit int) ([]models.LogEntry, parser.CommitInfo, error)
+	ExportEntries(ctx context.Context, id, exportID string, w io.Writer, q parser.ExportQuery) (int, error)
+	GetExportProgress(id, exportID string) (*models.ExportProgress, bool)
 }
 
 
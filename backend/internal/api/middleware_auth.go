@@ -0,0 +1,82 @@
+// middleware_auth.go - Authentication and role checks
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/auth"
+)
+
+const identityKey = "auth.identity"
+
+// AuthMiddleware resolves the request token to an identity and enforces the role that
+// auth.RequiredRole demands for the matched route. The token is read from a Bearer
+// Authorization header or the X-Auth-Token header (see RequestToken).
+func AuthMiddleware(a *auth.Authenticator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method == http.MethodOptions {
+				return next(c)
+			}
+
+			id, ok := a.Authenticate(RequestToken(c))
+			if ok {
+				c.Set(identityKey, id)
+			}
+
+			required := auth.RequiredRole(req.Method, c.Path())
+			if required == "" {
+				return next(c)
+			}
+			if !ok {
+				return NewUnauthorizedError("authentication required")
+			}
+			if !id.Role.Allows(required) {
+				return NewForbiddenError(fmt.Sprintf("%s role required", required))
+			}
+			return next(c)
+		}
+	}
+}
+
+// queryTokenRoute is the only route that accepts the token as a query parameter: browsers
+// cannot set headers on WebSocket upgrades. Elsewhere a token in the URL would leak into
+// logs, browser history and Referer headers.
+const queryTokenRoute = "/api/ws/uploads"
+
+// RequestToken returns the auth token sent with a request, if any
+func RequestToken(c echo.Context) string {
+	if h := c.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	if h := c.Request().Header.Get("X-Auth-Token"); h != "" {
+		return h
+	}
+	if c.Path() == queryTokenRoute {
+		return c.QueryParam("token")
+	}
+	return ""
+}
+
+// RedactedURI returns the request URI with the value of a token query parameter
+// replaced, for request logs.
+func RedactedURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("token") {
+		return r.RequestURI
+	}
+	query.Set("token", "REDACTED")
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// IdentityFrom returns the identity AuthMiddleware attached to a request, or nil
+func IdentityFrom(c echo.Context) *auth.Identity {
+	id, _ := c.Get(identityKey).(*auth.Identity)
+	return id
+}
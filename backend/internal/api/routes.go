@@ -3,7 +3,14 @@
 package api
 
 import (
+	"strings"
+	"time"
+
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/auth"
+	"github.com/plc-visualizer/backend/internal/config"
 	"github.com/plc-visualizer/backend/internal/session"
 	"github.com/plc-visualizer/backend/internal/storage"
 	"github.com/plc-visualizer/backend/internal/upload"
@@ -16,26 +23,39 @@ type Dependencies struct {
 	UploadMgr  *upload.Manager
 	DataDir    string
 	Version    string
+	Config     *config.Live
+	Auth       *auth.Authenticator
+	Audit      *audit.Log
 }
 
 // Handlers holds all handler instances
 type Handlers struct {
-	Health    HealthHandler
-	Upload    UploadHandler
-	Parse     ParseHandler
-	Map       MapHandler
-	Carrier   CarrierHandler
-	UploadJob UploadJobHandler
+	Health     HealthHandler
+	Upload     UploadHandler
+	Parse      ParseHandler
+	Map        MapHandler
+	Carrier    CarrierHandler
+	Annotation AnnotationHandler
+	Preset     PresetHandler
+	Analysis   AnalysisHandler
+	UploadJob  UploadJobHandler
+	Admin      AdminHandler
+	Auth       AuthHandler
 }
 
 // NewHandlers creates all handler instances
 func NewHandlers(deps *Dependencies) *Handlers {
 	return &Handlers{
-		Health:  NewHealthHandler(deps.Version),
-		Upload:  NewUploadHandler(deps.Store, deps.SessionMgr, deps.UploadMgr),
-		Parse:   NewParseHandler(deps.Store, deps.SessionMgr),
-		Map:     NewMapHandler(deps.Store, deps.DataDir),
-		Carrier: NewCarrierHandler(deps.Store),
+		Health:     NewHealthHandler(deps.Version),
+		Upload:     NewUploadHandler(deps.Store, deps.SessionMgr, deps.UploadMgr, deps.Audit),
+		Parse:      NewParseHandler(deps.Store, deps.SessionMgr),
+		Map:        NewMapHandler(deps.Store, deps.DataDir, deps.Audit),
+		Carrier:    NewCarrierHandler(deps.Store, deps.Audit),
+		Annotation: NewAnnotationHandler(deps.Store, deps.SessionMgr),
+		Preset:     NewPresetHandler(deps.SessionMgr),
+		Analysis:   NewAnalysisHandler(deps.SessionMgr),
+		Admin:      NewAdminHandler(deps.Config, deps.Audit),
+		Auth:       NewAuthHandler(deps.Auth),
 		// UploadJob handler would be created here if needed
 	}
 }
@@ -44,6 +64,7 @@ func NewHandlers(deps *Dependencies) *Handlers {
 func RegisterRoutes(e *echo.Echo, handlers *Handlers) {
 	// Health check
 	e.GET("/health", handlers.Health.HandleHealth)
+	e.GET("/health/resources", handlers.Health.HandleResources)
 
 	// File upload routes
 	uploadGroup := e.Group("/api/files")
@@ -56,10 +77,20 @@ func RegisterRoutes(e *echo.Echo, handlers *Handlers) {
 	uploadGroup.DELETE("/:id", handlers.Upload.HandleDeleteFile)
 	uploadGroup.PUT("/:id", handlers.Upload.HandleRenameFile)
 
+	// Annotation routes (keyed by file ID)
+	uploadGroup.GET("/:id/annotations", handlers.Annotation.HandleListAnnotations)
+	uploadGroup.POST("/:id/annotations", handlers.Annotation.HandleCreateAnnotation)
+	uploadGroup.PUT("/:id/annotations/:annotationId", handlers.Annotation.HandleUpdateAnnotation)
+	uploadGroup.DELETE("/:id/annotations/:annotationId", handlers.Annotation.HandleDeleteAnnotation)
+
 	// Parse session routes
 	parseGroup := e.Group("/api/parse")
 	parseGroup.POST("", handlers.Parse.HandleStartParse)
 	parseGroup.GET("/:sessionId/status", handlers.Parse.HandleParseStatus)
+	parseGroup.DELETE("/:sessionId", handlers.Parse.HandleCancelParse)
+	parseGroup.POST("/:sessionId/pause", handlers.Parse.HandlePauseParse)
+	parseGroup.POST("/:sessionId/resume", handlers.Parse.HandleResumeParse)
+	parseGroup.PUT("/:sessionId/priority", handlers.Parse.HandleParsePriority)
 	parseGroup.POST("/:sessionId/keepalive", handlers.Parse.HandleSessionKeepAlive)
 	parseGroup.GET("/:sessionId/progress", handlers.Parse.HandleParseProgressStream)
 	parseGroup.GET("/:sessionId/entries", handlers.Parse.HandleParseEntries)
@@ -73,6 +104,34 @@ func RegisterRoutes(e *echo.Echo, handlers *Handlers) {
 	parseGroup.GET("/:sessionId/index", handlers.Parse.HandleGetIndexByTime)
 	parseGroup.GET("/:sessionId/timetree", handlers.Parse.HandleGetTimeTree)
 	parseGroup.GET("/:sessionId/values", handlers.Parse.HandleGetValuesAtTime)
+	parseGroup.GET("/:sessionId/annotations", handlers.Annotation.HandleGetSessionAnnotations)
+	parseGroup.GET("/:sessionId/export", handlers.Parse.HandleExportEntries)
+	parseGroup.GET("/:sessionId/export/:exportId", handlers.Parse.HandleExportProgress)
+
+	// Analysis routes
+	parseGroup.POST("/:sessionId/transitions", handlers.Analysis.HandleGetTransitions)
+	parseGroup.GET("/:sessionId/signals/stats", handlers.Analysis.HandleGetSignalStats)
+	parseGroup.GET("/:sessionId/waveform", handlers.Analysis.HandleGetWaveform)
+	parseGroup.GET("/:sessionId/edges", handlers.Analysis.HandleGetEdges)
+	parseGroup.GET("/:sessionId/edges/:direction", handlers.Analysis.HandleFindEdge)
+	parseGroup.POST("/:sessionId/sequences", handlers.Analysis.HandleFindSequences)
+	parseGroup.POST("/:sessionId/correlations", handlers.Analysis.HandleFindCorrelations)
+	parseGroup.GET("/:sessionId/anomalies", handlers.Analysis.HandleGetAnomalies)
+	parseGroup.GET("/:sessionId/diff/:otherId", handlers.Analysis.HandleDiffSessions)
+	parseGroup.GET("/:sessionId/diff/:otherId/ranges", handlers.Analysis.HandleDiffSessionSignal)
+	parseGroup.POST("/:sessionId/sql", handlers.Analysis.HandleRunSQL)
+	parseGroup.DELETE("/:sessionId/sql/:queryId", handlers.Analysis.HandleCancelSQL)
+	parseGroup.GET("/:sessionId/derived", handlers.Analysis.HandleListDerivedSignals)
+	parseGroup.POST("/:sessionId/derived", handlers.Analysis.HandleSetDerivedSignal)
+	parseGroup.DELETE("/:sessionId/derived/:key", handlers.Analysis.HandleDeleteDerivedSignal)
+
+	// Saved filter preset routes
+	presetGroup := e.Group("/api/presets")
+	presetGroup.GET("", handlers.Preset.HandleListPresets)
+	presetGroup.POST("", handlers.Preset.HandleCreatePreset)
+	presetGroup.GET("/:presetId", handlers.Preset.HandleGetPreset)
+	presetGroup.PUT("/:presetId", handlers.Preset.HandleUpdatePreset)
+	presetGroup.DELETE("/:presetId", handlers.Preset.HandleDeletePreset)
 
 	// Map configuration routes
 	mapGroup := e.Group("/api/map")
@@ -92,6 +151,22 @@ func RegisterRoutes(e *echo.Echo, handlers *Handlers) {
 	carrierGroup.POST("", handlers.Carrier.HandleUploadCarrierLog)
 	carrierGroup.GET("", handlers.Carrier.HandleGetCarrierLog)
 	carrierGroup.GET("/entries", handlers.Carrier.HandleGetCarrierEntries)
+
+	// Administration routes
+	adminGroup := e.Group("/api/admin")
+	adminGroup.GET("/config", handlers.Admin.HandleGetConfig)
+	adminGroup.PUT("/config", handlers.Admin.HandleUpdateConfig)
+	adminGroup.GET("/audit", handlers.Admin.HandleGetAudit)
+	adminGroup.GET("/users", handlers.Auth.HandleListUsers)
+	adminGroup.POST("/users", handlers.Auth.HandleCreateUser)
+	adminGroup.PUT("/users/:username", handlers.Auth.HandleUpdateUser)
+	adminGroup.DELETE("/users/:username", handlers.Auth.HandleDeleteUser)
+
+	// Authentication routes
+	authGroup := e.Group("/api/auth")
+	authGroup.POST("/login", handlers.Auth.HandleLogin)
+	authGroup.POST("/logout", handlers.Auth.HandleLogout)
+	authGroup.GET("/me", handlers.Auth.HandleMe)
 }
 
 // RegisterWebSocketRoutes registers WebSocket routes
@@ -114,3 +189,22 @@ func SetupMiddleware(e *echo.Echo) {
 	// Add recovery
 	// e.Use(middleware.Recover())
 }
+
+// TimeoutMiddleware ends requests that take longer than timeout. Streams, uploads, entry
+// pages, exports and SQL console queries are exempt: they run as long as they need (SQL
+// queries enforce their own timeout), and the timeout writer cannot flush partial output.
+func TimeoutMiddleware(timeout time.Duration) echo.MiddlewareFunc {
+	return middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		Timeout: timeout,
+		Skipper: func(c echo.Context) bool {
+			path := c.Request().URL.Path
+			return strings.Contains(path, "/stream") ||
+				strings.Contains(path, "/upload") ||
+				strings.Contains(path, "/entries") ||
+				strings.HasSuffix(path, "/export") ||
+				strings.HasSuffix(path, "/sql") ||
+				c.Request().Header.Get("Accept") == "text/event-stream"
+		},
+		ErrorMessage: "Request timeout - query took too long",
+	})
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+	"github.com/plc-visualizer/backend/internal/audit"
+	"github.com/plc-visualizer/backend/internal/auth"
 	"github.com/plc-visualizer/backend/internal/models"
 	"github.com/plc-visualizer/backend/internal/parser"
 	"github.com/plc-visualizer/backend/internal/session"
@@ -123,6 +125,7 @@ type WebSocketHandler struct {
 	sessionMgr     *session.Manager
 	mapHandler     MapHandler
 	carrierHandler CarrierHandler
+	audit          *audit.Log
 	upgrader       websocket.Upgrader
 	sessions       map[string]*UploadSession
 	sessionsMu     sync.RWMutex
@@ -135,6 +138,7 @@ func NewWebSocketHandler(deps *Dependencies, handlers *Handlers) *WebSocketHandl
 		sessionMgr:     deps.SessionMgr,
 		mapHandler:     handlers.Map,
 		carrierHandler: handlers.Carrier,
+		audit:          deps.Audit,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				// Allow connections from dev server
@@ -147,8 +151,22 @@ func NewWebSocketHandler(deps *Dependencies, handlers *Handlers) *WebSocketHandl
 	}
 }
 
-// HandleWebSocket upgrades HTTP connection to WebSocket and handles upload protocol
+// wsClient is who is connected and the workspace their uploads activate in
+type wsClient struct {
+	identity  *auth.Identity
+	workspace string
+}
+
+// HandleWebSocket upgrades HTTP connection to WebSocket and handles upload protocol.
+// Maps, rules and carrier logs uploaded over the connection are activated in the
+// workspace named on the upgrade request.
 func (wsh *WebSocketHandler) HandleWebSocket(c echo.Context) error {
+	workspace, err := requestWorkspace(c)
+	if err != nil {
+		return err
+	}
+	client := wsClient{identity: IdentityFrom(c), workspace: workspace}
+
 	ws, err := wsh.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
@@ -184,13 +202,13 @@ func (wsh *WebSocketHandler) HandleWebSocket(c echo.Context) error {
 		case MsgTypeUploadChunk:
 			wsh.handleUploadChunk(ws, msg)
 		case MsgTypeUploadComplete:
-			wsh.handleUploadComplete(ws, msg)
+			wsh.handleUploadComplete(ws, client, msg)
 		case MsgTypeMapUpload:
-			wsh.handleMapUpload(ws, msg)
+			wsh.handleMapUpload(ws, client, msg)
 		case MsgTypeRulesUpload:
-			wsh.handleRulesUpload(ws, msg)
+			wsh.handleRulesUpload(ws, client, msg)
 		case MsgTypeCarrierUpload:
-			wsh.handleCarrierUpload(ws, msg)
+			wsh.handleCarrierUpload(ws, client, msg)
 		default:
 			wsh.sendError(ws, "Unknown message type: "+msg.Type, "INVALID_TYPE")
 		}
@@ -299,7 +317,7 @@ func (wsh *WebSocketHandler) handleUploadChunk(ws *websocket.Conn, msg WSMessage
 // handleUploadComplete assembles chunks from disk and processes the file
 // Uses streaming to minimize memory usage for large files
 // Sends granular progress updates during assembly, decompression, and saving
-func (wsh *WebSocketHandler) handleUploadComplete(ws *websocket.Conn, msg WSMessage) {
+func (wsh *WebSocketHandler) handleUploadComplete(ws *websocket.Conn, client wsClient, msg WSMessage) {
 	var payload UploadCompletePayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		wsh.sendError(ws, "Invalid complete payload: "+err.Error(), "INVALID_PAYLOAD")
@@ -394,6 +412,10 @@ func (wsh *WebSocketHandler) handleUploadComplete(ws *websocket.Conn, msg WSMess
 		return
 	}
 
+	recordAudit(wsh.audit, client.identity, audit.Entry{
+		Action: audit.ActionFileUpload, Target: info.ID, After: info.Name, Detail: "websocket",
+	})
+
 	// Clean up temp directory
 	os.RemoveAll(session.TempDir)
 
@@ -434,7 +456,7 @@ func (wsh *WebSocketHandler) sendProcessingProgress(ws *websocket.Conn, uploadID
 }
 
 // handleMapUpload handles single-message map XML upload
-func (wsh *WebSocketHandler) handleMapUpload(ws *websocket.Conn, msg WSMessage) {
+func (wsh *WebSocketHandler) handleMapUpload(ws *websocket.Conn, client wsClient, msg WSMessage) {
 	var payload FileUploadPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		wsh.sendError(ws, "Invalid map upload payload: "+err.Error(), "INVALID_PAYLOAD")
@@ -457,7 +479,12 @@ func (wsh *WebSocketHandler) handleMapUpload(ws *websocket.Conn, msg WSMessage)
 
 	// Set as active map via map handler
 	if h, ok := wsh.mapHandler.(*MapHandlerImpl); ok {
-		h.SetCurrentMap(info.ID)
+		before := h.GetCurrentMap(client.workspace)
+		h.SetCurrentMap(client.workspace, info.ID)
+		recordAudit(wsh.audit, client.identity, audit.Entry{
+			Action: audit.ActionMapUpload, Target: info.ID, Workspace: client.workspace,
+			Before: before, After: info.ID, Detail: info.Name + " (websocket)",
+		})
 	}
 
 	// Send completion
@@ -478,7 +505,7 @@ func (wsh *WebSocketHandler) handleMapUpload(ws *websocket.Conn, msg WSMessage)
 }
 
 // handleRulesUpload handles single-message rules YAML upload
-func (wsh *WebSocketHandler) handleRulesUpload(ws *websocket.Conn, msg WSMessage) {
+func (wsh *WebSocketHandler) handleRulesUpload(ws *websocket.Conn, client wsClient, msg WSMessage) {
 	var payload FileUploadPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		wsh.sendError(ws, "Invalid rules upload payload: "+err.Error(), "INVALID_PAYLOAD")
@@ -508,7 +535,12 @@ func (wsh *WebSocketHandler) handleRulesUpload(ws *websocket.Conn, msg WSMessage
 
 	// Set as active rules via map handler
 	if h, ok := wsh.mapHandler.(*MapHandlerImpl); ok {
-		h.SetCurrentRules(info.ID, rules)
+		before, _ := h.GetCurrentRules(client.workspace)
+		h.SetCurrentRules(client.workspace, info.ID, rules)
+		recordAudit(wsh.audit, client.identity, audit.Entry{
+			Action: audit.ActionRulesUpload, Target: info.ID, Workspace: client.workspace,
+			Before: before, After: info.ID, Detail: info.Name + " (websocket)",
+		})
 	}
 
 	// Send completion with rules info
@@ -532,7 +564,7 @@ func (wsh *WebSocketHandler) handleRulesUpload(ws *websocket.Conn, msg WSMessage
 }
 
 // handleCarrierUpload handles single-message carrier log upload
-func (wsh *WebSocketHandler) handleCarrierUpload(ws *websocket.Conn, msg WSMessage) {
+func (wsh *WebSocketHandler) handleCarrierUpload(ws *websocket.Conn, client wsClient, msg WSMessage) {
 	var payload FileUploadPayload
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 		wsh.sendError(ws, "Invalid carrier upload payload: "+err.Error(), "INVALID_PAYLOAD")
@@ -573,7 +605,7 @@ func (wsh *WebSocketHandler) handleCarrierUpload(ws *websocket.Conn, msg WSMessa
 	})
 
 	// Start parsing session
-	sess, err := wsh.sessionMgr.StartSession(info.ID, path)
+	sess, err := wsh.sessionMgr.StartSession(info.ID, path, 0)
 	if err != nil {
 		wsh.sendError(ws, "Failed to start parsing: "+err.Error(), "PARSE_ERROR")
 		return
@@ -597,7 +629,12 @@ func (wsh *WebSocketHandler) handleCarrierUpload(ws *websocket.Conn, msg WSMessa
 
 	// Set carrier session via carrier handler
 	if h, ok := wsh.carrierHandler.(*CarrierHandlerImpl); ok {
-		h.SetCarrierSessionID(sess.ID)
+		before := h.GetCarrierSessionID(client.workspace)
+		h.SetCarrierSessionID(client.workspace, sess.ID)
+		recordAudit(wsh.audit, client.identity, audit.Entry{
+			Action: audit.ActionCarrierUpload, Target: info.ID, Workspace: client.workspace,
+			Before: before, After: sess.ID, Detail: info.Name + " (websocket)",
+		})
 	}
 
 	// Send completion
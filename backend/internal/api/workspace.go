@@ -0,0 +1,47 @@
+// workspace.go - Per-client workspace addressing
+package api
+
+import (
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultWorkspace is the workspace of requests that do not name one, so single-user
+// setups never have to.
+const DefaultWorkspace = ""
+
+// WorkspaceHeader names the workspace of a request when the workspace query parameter
+// is not set.
+const WorkspaceHeader = "X-Workspace"
+
+var workspacePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// workspaceUse records when the state of a workspace was last read or written, so the
+// handlers can drop workspaces that have been idle for longer than the session timeout.
+type workspaceUse struct {
+	last atomic.Int64 // Unix nanoseconds
+}
+
+func (u *workspaceUse) touch() {
+	u.last.Store(time.Now().UnixNano())
+}
+
+func (u *workspaceUse) idleSince(cutoff time.Time) bool {
+	return u.last.Load() < cutoff.UnixNano()
+}
+
+// requestWorkspace returns the workspace a request addresses: the workspace query
+// parameter, else the X-Workspace header, else DefaultWorkspace.
+func requestWorkspace(c echo.Context) (string, error) {
+	ws := c.QueryParam("workspace")
+	if ws == "" {
+		ws = c.Request().Header.Get(WorkspaceHeader)
+	}
+	if ws != DefaultWorkspace && !workspacePattern.MatchString(ws) {
+		return "", NewBadRequestError("workspace must be 1-64 letters, digits, '.', '_' or '-'", nil)
+	}
+	return ws, nil
+}
@@ -0,0 +1,235 @@
+// Package audit keeps an append-only record of user actions on files, maps and rules.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the log
+const (
+	ActionFileUpload    = "file.upload"
+	ActionFileRename    = "file.rename"
+	ActionFileDelete    = "file.delete"
+	ActionMapUpload     = "map.upload"
+	ActionMapActivate   = "map.activate"
+	ActionRulesUpload   = "rules.upload"
+	ActionCarrierUpload = "carrier.upload"
+)
+
+// Entry is one recorded action. Before and After hold the previous and new value of
+// changes such as renames and rules replacements.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Role      string    `json:"role,omitempty"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`    // ID of the file, map or rules acted on
+	Workspace string    `json:"workspace,omitempty"` // Workspace of map, rules and carrier changes
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	Detail    string    `json:"detail,omitempty"` // e.g. the upload path or related IDs
+}
+
+// Filter selects entries; zero fields match everything.
+type Filter struct {
+	From   time.Time // Inclusive
+	To     time.Time // Exclusive
+	Actor  string
+	Action string // Exact action or a prefix ending in "." (e.g. "file.")
+	Target string
+	Limit  int // Keep only the newest Limit entries
+}
+
+func (f *Filter) match(e *Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action &&
+		!(strings.HasSuffix(f.Action, ".") && strings.HasPrefix(e.Action, f.Action)) {
+		return false
+	}
+	return f.Target == "" || e.Target == f.Target
+}
+
+// Log appends entries as JSON lines to audit.jsonl in the data directory. Entries are
+// never rewritten or removed. A nil *Log records nothing, so components can run
+// without one.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLog creates an audit log rooted at dir.
+func NewLog(dir string) *Log {
+	os.MkdirAll(dir, 0755)
+	return &Log{path: filepath.Join(dir, "audit.jsonl")}
+}
+
+// Record appends an entry, stamping it with the current time if it has none.
+func (l *Log) Record(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// Query returns the entries matching f, oldest first. With a Limit the log is read
+// backwards from its end and stops once Limit entries match, so listing recent
+// activity does not scan the whole history.
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	entries := []Entry{}
+	if l == nil {
+		return entries, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if f.Limit > 0 {
+		return queryNewest(file, f, entries)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if e, ok := parseLine(scanner.Bytes()); ok && f.match(&e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// queryBlockSize is the read size of queryNewest.
+const queryBlockSize = 64 * 1024
+
+// queryNewest collects the newest f.Limit matching entries reading the log from its end
+// in blocks, then returns them oldest first.
+func queryNewest(file *os.File, f Filter, entries []Entry) ([]Entry, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	pos := info.Size()
+	var rest []byte // Start of a line continuing past the current block
+	for pos > 0 && len(entries) < f.Limit {
+		n := int64(queryBlockSize)
+		if n > pos {
+			n = pos
+		}
+		pos -= n
+		block := make([]byte, int(n)+len(rest))
+		if _, err := file.ReadAt(block[:n], pos); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		copy(block[n:], rest)
+
+		lines := bytes.Split(block, []byte{'\n'})
+		rest = nil
+		if pos > 0 {
+			// The first line may start in an earlier block
+			rest, lines = lines[0], lines[1:]
+		}
+		for i := len(lines) - 1; i >= 0 && len(entries) < f.Limit; i-- {
+			if e, ok := parseLine(lines[i]); ok && f.match(&e) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// parseLine decodes one line of the log. A line torn by a crash mid-write, or an empty
+// one, is skipped.
+func parseLine(line []byte) (Entry, bool) {
+	var e Entry
+	if len(line) == 0 || json.Unmarshal(line, &e) != nil {
+		return e, false
+	}
+	return e, true
+}
+
+// WriteCSV writes entries as CSV with a header row. Cells that a spreadsheet would
+// evaluate as a formula are prefixed with a quote.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "actor", "role", "action", "target", "workspace", "before", "after", "detail"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err := cw.Write([]string{
+			e.Time.Format(time.RFC3339Nano), csvCell(e.Actor), csvCell(e.Role), csvCell(e.Action), csvCell(e.Target),
+			csvCell(e.Workspace), csvCell(e.Before), csvCell(e.After), csvCell(e.Detail),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell neutralizes a value starting with =, +, - or @, which spreadsheets would
+// otherwise run as a formula (e.g. a file renamed to "=HYPERLINK(...)").
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
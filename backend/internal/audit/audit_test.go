@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLog(dir)
+	start := time.Now().Add(-time.Hour)
+
+	l.Record(Entry{Time: start, Actor: "alice", Action: ActionFileUpload, Target: "f1"})
+	l.Record(Entry{Time: start.Add(time.Minute), Actor: "bob", Action: ActionFileRename, Target: "f1", Before: "a", After: "b"})
+	l.Record(Entry{Actor: "alice", Action: ActionRulesUpload, Target: "r1"})
+
+	// A line torn by a crash is skipped, later entries still count
+	f, _ := os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"time":"2026-`)
+	f.WriteString("\n")
+	f.Close()
+	l.Record(Entry{Actor: "bob", Action: ActionFileDelete, Target: "f1"})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 4},
+		{"actor", Filter{Actor: "alice"}, 2},
+		{"action prefix", Filter{Action: "file."}, 3},
+		{"exact action", Filter{Action: ActionFileRename}, 1},
+		{"target", Filter{Target: "f1"}, 3},
+		{"time range", Filter{From: start, To: start.Add(time.Minute)}, 1},
+		{"newest", Filter{Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(entries) != tt.want {
+				t.Errorf("Expected %d entries, got %+v", tt.want, entries)
+			}
+		})
+	}
+
+	newest, _ := l.Query(Filter{Limit: 1})
+	if len(newest) != 1 || newest[0].Action != ActionFileDelete {
+		t.Errorf("Expected the latest entry, got %+v", newest)
+	}
+
+	var nilLog *Log
+	if err := nilLog.Record(Entry{Action: ActionFileUpload}); err != nil {
+		t.Errorf("Expected a nil log to ignore entries, got %v", err)
+	}
+}
+
+func TestLog_QueryNewestAcrossBlocks(t *testing.T) {
+	l := NewLog(t.TempDir())
+	start := time.Now().Add(-time.Hour)
+	// Several read blocks of entries, with a long line straddling block boundaries
+	for i := 0; i < 3000; i++ {
+		e := Entry{Time: start.Add(time.Duration(i) * time.Millisecond), Actor: "alice", Action: ActionFileUpload, Target: fmt.Sprintf("f%d", i)}
+		if i%2 == 1 {
+			e.Actor = "bob"
+		}
+		if i == 2500 {
+			e.Detail = strings.Repeat("x", 3*queryBlockSize)
+		}
+		l.Record(e)
+	}
+
+	entries, err := l.Query(Filter{Actor: "bob", Limit: 300})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 300 {
+		t.Fatalf("Expected 300 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if want := fmt.Sprintf("f%d", 2400+2*i+1); e.Target != want || e.Actor != "bob" {
+			t.Fatalf("Entry %d: expected %s of bob, got %s of %s", i, want, e.Target, e.Actor)
+		}
+	}
+
+	all, _ := l.Query(Filter{Limit: 5000})
+	if len(all) != 3000 || all[0].Target != "f0" {
+		t.Errorf("Expected every entry oldest first, got %d starting with %+v", len(all), all[0])
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteCSV(t *testing.T) {
+	entries := []Entry{{Time: time.Unix(0, 0), Actor: "alice", Action: ActionFileRename, Before: "a.log", After: "=HYPERLINK(\"x\")"}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"'=HYPERLINK(""x"")"`) {
+		t.Errorf("Expected the formula to be neutralized, got %s", buf.String())
+	}
+
+	for i := 0; i < 200; i++ {
+		entries = append(entries, entries[0])
+	}
+	if err := WriteCSV(failingWriter{}, entries); err == nil {
+		t.Error("Expected the write error to be returned")
+	}
+}
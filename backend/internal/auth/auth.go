@@ -0,0 +1,175 @@
+// Package auth provides token authentication and role checks for the API.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role grants access to a set of API operations. Each role includes the ones below it.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"  // Browse files, sessions and analyses
+	RoleAnalyst Role = "analyst" // Also upload files and change maps, rules and presets
+	RoleAdmin   Role = "admin"   // Also delete files, manage users and configuration
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleAnalyst: 2, RoleAdmin: 3}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether r includes the required role. The empty role is public.
+func (r Role) Allows(required Role) bool {
+	return required == "" || roleRank[r] >= roleRank[required]
+}
+
+// SessionTTL is how long a login token stays valid.
+const SessionTTL = 12 * time.Hour
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+// Anonymous is the identity of requests while authentication is not required.
+var Anonymous = &Identity{Name: "anonymous", Role: RoleAdmin}
+
+type loginSession struct {
+	identity Identity
+	expires  time.Time
+}
+
+// Authenticator resolves request tokens to identities. Tokens are either static ones from
+// the configuration or issued by Login for local users.
+type Authenticator struct {
+	users *UserStore
+
+	mu       sync.RWMutex
+	required bool
+	static   map[string]Role
+	sessions map[string]*loginSession
+}
+
+// NewAuthenticator creates an authenticator for the users in store. Authentication is
+// not required until Configure enables it.
+func NewAuthenticator(users *UserStore) *Authenticator {
+	return &Authenticator{
+		users:    users,
+		static:   make(map[string]Role),
+		sessions: make(map[string]*loginSession),
+	}
+}
+
+// Users returns the local user store.
+func (a *Authenticator) Users() *UserStore {
+	return a.users
+}
+
+// Configure sets whether requests need a token and the static tokens, a comma separated
+// list of "token" or "token:role" entries (admin when no role is given).
+func (a *Authenticator) Configure(required bool, tokens string) {
+	static := make(map[string]Role)
+	for _, entry := range strings.Split(tokens, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		token, role := entry, RoleAdmin
+		if i := strings.LastIndex(entry, ":"); i > 0 && Role(entry[i+1:]).Valid() {
+			token, role = entry[:i], Role(entry[i+1:])
+		}
+		static[token] = role
+	}
+
+	a.mu.Lock()
+	a.required = required
+	a.static = static
+	a.mu.Unlock()
+}
+
+// Required reports whether requests need a token.
+func (a *Authenticator) Required() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.required
+}
+
+// Login checks a user's password and issues a token valid for SessionTTL. Expired tokens
+// are dropped on each login, so tokens that are never used again do not pile up.
+func (a *Authenticator) Login(username, password string) (string, *Identity, time.Time, bool) {
+	role, ok := a.users.Verify(username, password)
+	if !ok {
+		return "", nil, time.Time{}, false
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, time.Time{}, false
+	}
+	token := hex.EncodeToString(buf)
+	sess := &loginSession{identity: Identity{Name: username, Role: role}, expires: time.Now().Add(SessionTTL)}
+
+	a.mu.Lock()
+	now := time.Now()
+	for t, other := range a.sessions {
+		if !now.Before(other.expires) {
+			delete(a.sessions, t)
+		}
+	}
+	a.sessions[token] = sess
+	a.mu.Unlock()
+	return token, &sess.identity, sess.expires, true
+}
+
+// Logout invalidates a login token.
+func (a *Authenticator) Logout(token string) {
+	a.mu.Lock()
+	delete(a.sessions, token)
+	a.mu.Unlock()
+}
+
+// LogoutUser invalidates every login token of a user, e.g. after deleting it or
+// changing its role.
+func (a *Authenticator) LogoutUser(username string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for token, sess := range a.sessions {
+		if sess.identity.Name == username {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+// Authenticate resolves a token. Without required authentication every request is
+// Anonymous.
+func (a *Authenticator) Authenticate(token string) (*Identity, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if token != "" {
+		if sess, ok := a.sessions[token]; ok {
+			if time.Now().Before(sess.expires) {
+				id := sess.identity
+				return &id, true
+			}
+			delete(a.sessions, token)
+		}
+		for static, role := range a.static {
+			if subtle.ConstantTimeCompare([]byte(static), []byte(token)) == 1 {
+				return &Identity{Name: "token", Role: role}, true
+			}
+		}
+	}
+	if !a.required {
+		return Anonymous, true
+	}
+	return nil, false
+}
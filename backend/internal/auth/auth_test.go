@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	hashCost = bcrypt.MinCost
+}
+
+func TestRoleAllows(t *testing.T) {
+	if !RoleAdmin.Allows(RoleAnalyst) || !RoleAnalyst.Allows(RoleViewer) || !RoleViewer.Allows("") {
+		t.Error("Expected higher roles to include lower ones")
+	}
+	if RoleViewer.Allows(RoleAnalyst) || RoleAnalyst.Allows(RoleAdmin) || Role("guest").Allows(RoleViewer) {
+		t.Error("Expected lower or unknown roles to be refused")
+	}
+}
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         Role
+	}{
+		{http.MethodGet, "/api/health", ""},
+		{http.MethodPost, "/api/auth/login", ""},
+		{http.MethodGet, "/", ""},
+		{http.MethodGet, "/api/files/recent", RoleViewer},
+		{http.MethodPost, "/api/parse", RoleViewer},
+		{http.MethodPost, "/api/files/upload/chunk", RoleAnalyst},
+		{http.MethodGet, "/api/ws/uploads", RoleAnalyst},
+		{http.MethodDelete, "/api/files/:id", RoleAdmin},
+		{http.MethodDelete, "/api/files/:id/annotations/:annotationId", RoleAnalyst},
+		{http.MethodGet, "/api/map/rules", RoleViewer},
+		{http.MethodPost, "/api/map/rules", RoleAnalyst},
+		{http.MethodPut, "/api/config/validation-rules", RoleAnalyst},
+		{http.MethodGet, "/api/admin/config", RoleAdmin},
+		{http.MethodDelete, "/api/parse/:sessionId", RoleAnalyst},
+		{http.MethodPost, "/api/parse/:sessionId/pause", RoleAnalyst},
+		{http.MethodPut, "/api/parse/:sessionId/priority", RoleAnalyst},
+		{http.MethodDelete, "/api/parse/:sessionId/sql/:queryId", RoleViewer},
+		{http.MethodDelete, "/api/parse/:sessionId/derived/:key", RoleAnalyst},
+		{http.MethodPost, "/api/parse/:sessionId/chunk", RoleViewer},
+	}
+	for _, tt := range tests {
+		if got := RequiredRole(tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestUserStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUserStore(dir)
+
+	if _, err := store.Add("alice", "secret", RoleAnalyst); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := store.Add("alice", "other", RoleViewer); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+	if _, err := store.Add("bob", "secret", Role("root")); err == nil {
+		t.Error("Expected invalid role to be rejected")
+	}
+
+	// Reload from disk: only the hash is stored, and the file is private
+	store = NewUserStore(dir)
+	if role, ok := store.Verify("alice", "secret"); !ok || role != RoleAnalyst {
+		t.Errorf("Expected alice to verify as analyst, got %q %v", role, ok)
+	}
+	if _, ok := store.Verify("alice", "wrong"); ok {
+		t.Error("Expected wrong password to fail")
+	}
+	// Unknown users are checked against the dummy hash, which never verifies
+	if _, ok := store.Verify("nobody", "dummy password"); ok {
+		t.Error("Expected an unknown user to fail")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "users.json"))
+	if len(data) == 0 || bytes.Contains(data, []byte("secret")) {
+		t.Error("Expected only the password hash to be stored")
+	}
+	if info, err := os.Stat(filepath.Join(dir, "users.json")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected users.json with mode 0600, got %v", err)
+	}
+	users, _ := store.List()
+	if len(users) != 1 || users[0].PasswordHash != "" {
+		t.Errorf("Expected one user without hash, got %+v", users)
+	}
+
+	if _, err := store.Update("alice", "changed", RoleAdmin); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if role, ok := store.Verify("alice", "changed"); !ok || role != RoleAdmin {
+		t.Errorf("Expected updated password and role, got %q %v", role, ok)
+	}
+	if err := store.Delete("alice"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestUserStore_EnsureAdmin(t *testing.T) {
+	store := NewUserStore(t.TempDir())
+	if _, err := store.EnsureAdmin("admin", ""); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials without a password, got %v", err)
+	}
+	if created, err := store.EnsureAdmin("admin", "pw"); err != nil || !created {
+		t.Fatalf("Expected the admin to be created, got %v %v", created, err)
+	}
+	if role, ok := store.Verify("admin", "pw"); !ok || role != RoleAdmin {
+		t.Errorf("Expected an admin, got %q %v", role, ok)
+	}
+	// Existing users are left alone
+	if created, err := store.EnsureAdmin("root", ""); err != nil || created {
+		t.Errorf("Expected nothing to be created, got %v %v", created, err)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	a := NewAuthenticator(NewUserStore(t.TempDir()))
+
+	// Without required authentication every request is anonymous
+	if id, ok := a.Authenticate(""); !ok || id != Anonymous {
+		t.Errorf("Expected anonymous access, got %v %v", id, ok)
+	}
+
+	a.Configure(true, "master, view-token:viewer")
+	if _, ok := a.Authenticate(""); ok {
+		t.Error("Expected missing token to be refused")
+	}
+	if id, ok := a.Authenticate("master"); !ok || id.Role != RoleAdmin {
+		t.Errorf("Expected static admin token, got %v", id)
+	}
+	if id, ok := a.Authenticate("view-token"); !ok || id.Role != RoleViewer {
+		t.Errorf("Expected static viewer token, got %v", id)
+	}
+
+	a.Users().Add("carol", "pw", RoleAnalyst)
+	if _, _, _, ok := a.Login("carol", "nope"); ok {
+		t.Error("Expected login with wrong password to fail")
+	}
+	token, id, _, ok := a.Login("carol", "pw")
+	if !ok || id.Role != RoleAnalyst {
+		t.Fatalf("Login failed: %v", id)
+	}
+	if got, ok := a.Authenticate(token); !ok || got.Name != "carol" {
+		t.Errorf("Expected token of carol, got %v", got)
+	}
+	a.LogoutUser("carol")
+	if _, ok := a.Authenticate(token); ok {
+		t.Error("Expected token to be revoked")
+	}
+
+	// Expired tokens are swept on the next login
+	a.sessions["stale"] = &loginSession{identity: Identity{Name: "carol"}, expires: time.Now().Add(-time.Minute)}
+	if _, _, _, ok := a.Login("carol", "pw"); !ok {
+		t.Fatal("Login failed")
+	}
+	if _, ok := a.sessions["stale"]; ok || len(a.sessions) != 1 {
+		t.Errorf("Expected only the new token to be kept, got %d tokens", len(a.sessions))
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// rule requires role for routes starting with prefix. Rules with writes only apply to
+// methods other than GET and HEAD.
+type rule struct {
+	prefix string
+	writes bool
+	method string
+	role   Role
+}
+
+// policy is checked in order against the route pattern; the first match wins. Routes
+// under /api without a match need RoleViewer, everything else is public.
+var policy = []rule{
+	{prefix: "/api/health", role: ""},
+	{prefix: "/api/auth/login", role: ""},
+	{prefix: "/api/admin", role: RoleAdmin},
+	{prefix: "/api/ws/uploads", role: RoleAnalyst},
+	{prefix: "/api/files/:id/annotations", writes: true, role: RoleAnalyst},
+	{prefix: "/api/files/:id", method: http.MethodDelete, role: RoleAdmin},
+	{prefix: "/api/files", writes: true, role: RoleAnalyst},
+	{prefix: "/api/map", writes: true, role: RoleAnalyst},
+	{prefix: "/api/config", writes: true, role: RoleAnalyst},
+	{prefix: "/api/presets", writes: true, role: RoleAnalyst},
+	{prefix: "/api/parse/:sessionId/derived", writes: true, role: RoleAnalyst},
+	// Viewers may cancel their own SQL queries, but not stop, delete or reorder parses
+	{prefix: "/api/parse/:sessionId/sql", role: RoleViewer},
+	{prefix: "/api/parse/:sessionId/pause", writes: true, role: RoleAnalyst},
+	{prefix: "/api/parse/:sessionId/resume", writes: true, role: RoleAnalyst},
+	{prefix: "/api/parse/:sessionId/priority", writes: true, role: RoleAnalyst},
+	{prefix: "/api/parse/:sessionId", method: http.MethodDelete, role: RoleAnalyst},
+}
+
+// RequiredRole returns the role needed for a request to the route pattern path (e.g.
+// "/api/files/:id"); the empty role means public.
+func RequiredRole(method, path string) Role {
+	write := method != http.MethodGet && method != http.MethodHead
+	for _, r := range policy {
+		if !strings.HasPrefix(path, r.prefix) {
+			continue
+		}
+		if (r.writes && !write) || (r.method != "" && r.method != method) {
+			continue
+		}
+		return r.role
+	}
+	if strings.HasPrefix(path, "/api/") {
+		return RoleViewer
+	}
+	return ""
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUserNotFound is returned for unknown user names.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when adding a user name that is taken.
+	ErrUserExists = errors.New("user already exists")
+	// ErrNoCredentials is returned by EnsureAdmin when there are no users and no
+	// password to create the first one with.
+	ErrNoCredentials = errors.New("no users and no initial admin password")
+)
+
+// hashCost is the bcrypt cost of stored password hashes.
+var hashCost = bcrypt.DefaultCost
+
+// dummyHash is compared against when a user is unknown, so a failed login takes as long
+// whether or not the user name exists.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), hashCost)
+	return hash
+})
+
+// User is a locally stored account. Only the bcrypt hash of the password is kept.
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"passwordHash,omitempty"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// UserStore persists users in a single JSON file (users.json) in the data directory.
+// The list is small, so it is kept in memory and rewritten on change.
+type UserStore struct {
+	path   string
+	mu     sync.RWMutex
+	users  []*User
+	loaded bool
+}
+
+// NewUserStore creates a user store rooted at dir.
+func NewUserStore(dir string) *UserStore {
+	os.MkdirAll(dir, 0755)
+	return &UserStore{path: filepath.Join(dir, "users.json")}
+}
+
+// load reads the users from disk on first access. Caller must hold us.mu (write lock).
+func (us *UserStore) load() error {
+	if us.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(us.path)
+	if os.IsNotExist(err) {
+		us.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	if err := json.Unmarshal(data, &us.users); err != nil {
+		return fmt.Errorf("failed to decode users: %w", err)
+	}
+	us.loaded = true
+	return nil
+}
+
+// save writes the users to disk via a temp file and rename. The file holds password
+// hashes, so it is only readable by the owner. Caller must hold us.mu (write lock).
+func (us *UserStore) save(list []*User) error {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Username < list[j].Username
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode users: %w", err)
+	}
+
+	tmpPath := us.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write users: %w", err)
+	}
+	if err := os.Rename(tmpPath, us.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write users: %w", err)
+	}
+
+	us.users = list
+	return nil
+}
+
+// List returns all users ordered by name, without password hashes.
+func (us *UserStore) List() ([]User, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if err := us.load(); err != nil {
+		return nil, err
+	}
+	result := make([]User, 0, len(us.users))
+	for _, u := range us.users {
+		copied := *u
+		copied.PasswordHash = ""
+		result = append(result, copied)
+	}
+	return result, nil
+}
+
+// Add creates a user with the given password.
+func (us *UserStore) Add(username, password string, role Role) (*User, error) {
+	if username == "" || password == "" {
+		return nil, errors.New("username and password are required")
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hashCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if err := us.load(); err != nil {
+		return nil, err
+	}
+	for _, u := range us.users {
+		if u.Username == username {
+			return nil, fmt.Errorf("%w: %s", ErrUserExists, username)
+		}
+	}
+
+	now := time.Now()
+	user := &User{Username: username, PasswordHash: string(hash), Role: role, CreatedAt: now, UpdatedAt: now}
+	if err := us.save(append(append([]*User{}, us.users...), user)); err != nil {
+		return nil, err
+	}
+	copied := *user
+	copied.PasswordHash = ""
+	return &copied, nil
+}
+
+// Update changes the role and/or password of a user; empty values are left unchanged.
+func (us *UserStore) Update(username, password string, role Role) (*User, error) {
+	if role != "" && !role.Valid() {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	var hash []byte
+	if password != "" {
+		var err error
+		if hash, err = bcrypt.GenerateFromPassword([]byte(password), hashCost); err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if err := us.load(); err != nil {
+		return nil, err
+	}
+	list := make([]*User, 0, len(us.users))
+	var updated *User
+	for _, u := range us.users {
+		if u.Username == username {
+			copied := *u
+			if role != "" {
+				copied.Role = role
+			}
+			if hash != nil {
+				copied.PasswordHash = string(hash)
+			}
+			copied.UpdatedAt = time.Now()
+			updated = &copied
+			u = updated
+		}
+		list = append(list, u)
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	if err := us.save(list); err != nil {
+		return nil, err
+	}
+	copied := *updated
+	copied.PasswordHash = ""
+	return &copied, nil
+}
+
+// Delete removes a user.
+func (us *UserStore) Delete(username string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if err := us.load(); err != nil {
+		return err
+	}
+	list := make([]*User, 0, len(us.users))
+	for _, u := range us.users {
+		if u.Username != username {
+			list = append(list, u)
+		}
+	}
+	if len(list) == len(us.users) {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	return us.save(list)
+}
+
+// EnsureAdmin creates an admin user with the given name and password if the store has
+// no users yet, so a server requiring authentication can be logged into. It reports
+// whether the user was created, and returns ErrNoCredentials if one was needed but
+// password is empty.
+func (us *UserStore) EnsureAdmin(username, password string) (bool, error) {
+	users, err := us.List()
+	if err != nil {
+		return false, err
+	}
+	if len(users) > 0 {
+		return false, nil
+	}
+	if password == "" {
+		return false, ErrNoCredentials
+	}
+	if _, err := us.Add(username, password, RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Verify checks a password and returns the user's role.
+func (us *UserStore) Verify(username, password string) (Role, bool) {
+	us.mu.Lock()
+	if err := us.load(); err != nil {
+		us.mu.Unlock()
+		return "", false
+	}
+	var hash string
+	var role Role
+	for _, u := range us.users {
+		if u.Username == username {
+			hash, role = u.PasswordHash, u.Role
+		}
+	}
+	us.mu.Unlock()
+
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return "", false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", false
+	}
+	return role, true
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// hotFields are the settings the server applies without a restart; changes to any other
+// field are saved but take effect on the next start.
+var hotFields = map[string]bool{
+	"Server.AllowOrigins":               true,
+	"Server.BodyLimit":                  true,
+	"Processing.MaxConcurrentParses":    true,
+	"Processing.MaxSessions":            true,
+	"Processing.SessionTimeoutMinutes":  true,
+	"Processing.CleanupIntervalMinutes": true,
+	"Processing.MaxMemoryPerSession":    true,
+	"Processing.AutoDetectAnomalies":    true,
+	"Processing.BuildSearchIndex":       true,
+	"Advanced.EnableRequestLogging":     true,
+	"Advanced.DuckDBThreads":            true,
+	"Advanced.DuckDBMemoryLimit":        true,
+	"Advanced.DuckDBQueriesPerSession":  true,
+	"Security.RequireAuth":              true,
+	"Security.AuthToken":                true,
+}
+
+// ErrInvalidConfig wraps the validation errors of a rejected update.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ConfigChange is the outcome of updating the live configuration.
+type ConfigChange struct {
+	Applied         []string `json:"applied"`         // Fields that took effect immediately
+	RestartRequired []string `json:"restartRequired"` // Fields changed since startup that take effect after a restart
+}
+
+// Live holds the effective configuration of a running server. Updates are validated,
+// saved to the config file and handed to the registered listeners so hot fields apply
+// at once.
+type Live struct {
+	mu        sync.RWMutex
+	cfg       AppConfig
+	path      string
+	listeners []func(*AppConfig)
+	startup   AppConfig // What the server was started with
+}
+
+// NewLive wraps the configuration loaded from path.
+func NewLive(cfg *AppConfig, path string) *Live {
+	return &Live{cfg: *cfg, path: path, startup: *cfg}
+}
+
+// Get returns a copy of the effective configuration.
+func (l *Live) Get() *AppConfig {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	cfg := l.cfg
+	return &cfg
+}
+
+// RestartRequired lists the fields changed since startup that are not effective until
+// a restart.
+func (l *Live) RestartRequired() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return coldFields(ChangedFields(&l.startup, &l.cfg))
+}
+
+// OnChange registers fn to be called with the new configuration after every update.
+func (l *Live) OnChange(fn func(*AppConfig)) {
+	l.mu.Lock()
+	l.listeners = append(l.listeners, fn)
+	l.mu.Unlock()
+}
+
+// Update validates next, saves it and applies its hot fields.
+func (l *Live) Update(next *AppConfig) (*ConfigChange, error) {
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	l.mu.Lock()
+	changed := ChangedFields(&l.cfg, next)
+	if l.path != "" {
+		if err := next.Save(l.path); err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+	}
+	l.cfg = *next
+	change := &ConfigChange{
+		Applied:         []string{},
+		RestartRequired: coldFields(ChangedFields(&l.startup, &l.cfg)),
+	}
+	for _, f := range changed {
+		if hotFields[f] {
+			change.Applied = append(change.Applied, f)
+		}
+	}
+	listeners := l.listeners
+	cfg := l.cfg
+	l.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(&cfg)
+	}
+	return change, nil
+}
+
+// ChangedFields lists the fields ("Section.Field") that differ between a and b.
+func ChangedFields(a, b *AppConfig) []string {
+	var changed []string
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		section := va.Type().Field(i)
+		if section.Type.Kind() != reflect.Struct || section.Name == "XMLName" {
+			continue
+		}
+		sa, sb := va.Field(i), vb.Field(i)
+		for j := 0; j < sa.NumField(); j++ {
+			if !reflect.DeepEqual(sa.Field(j).Interface(), sb.Field(j).Interface()) {
+				changed = append(changed, section.Name+"."+sa.Type().Field(j).Name)
+			}
+		}
+	}
+	return changed
+}
+
+// Validate checks the configuration for values the server cannot run with.
+func (c *AppConfig) Validate() error {
+	var errs []error
+	check := func(ok bool, field, msg string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, msg))
+		}
+	}
+	size := func(field, value string) {
+		if _, err := ParseByteSize(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "Server.Port", "must be between 1 and 65535")
+	check(c.Server.ReadTimeout >= 0, "Server.ReadTimeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "Server.WriteTimeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "Server.IdleTimeout", "must not be negative")
+	size("Server.BodyLimit", c.Server.BodyLimit)
+
+	check(c.Storage.DataDirectory != "", "Storage.DataDirectory", "is required")
+	check(c.Storage.UploadsDirectory != "", "Storage.UploadsDirectory", "is required")
+	check(c.Storage.TempDirectory != "", "Storage.TempDirectory", "is required")
+	check(c.Storage.ParsedDataDirectory != "", "Storage.ParsedDataDirectory", "is required")
+	size("Storage.MaxUploadSize", c.Storage.MaxUploadSize)
+
+	check(c.Processing.MaxConcurrentParses > 0, "Processing.MaxConcurrentParses", "must be at least 1")
+	check(c.Processing.MaxSessions >= 0, "Processing.MaxSessions", "must not be negative")
+	check(c.Processing.SessionTimeoutMinutes > 0, "Processing.SessionTimeoutMinutes", "must be at least 1")
+	check(c.Processing.CleanupIntervalMinutes > 0, "Processing.CleanupIntervalMinutes", "must be at least 1")
+	check(c.Processing.CompressionLevel >= 1 && c.Processing.CompressionLevel <= 9, "Processing.CompressionLevel", "must be between 1 and 9")
+	size("Processing.MaxMemoryPerSession", c.Processing.MaxMemoryPerSession)
+
+	switch strings.ToLower(c.Advanced.LogLevel) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		check(false, "Advanced.LogLevel", "must be debug, info, warn or error")
+	}
+	check(c.Advanced.DuckDBThreads >= 0, "Advanced.DuckDBThreads", "must not be negative")
+	check(c.Advanced.DuckDBQueriesPerSession >= 0, "Advanced.DuckDBQueriesPerSession", "must not be negative")
+	size("Advanced.DuckDBMemoryLimit", c.Advanced.DuckDBMemoryLimit)
+
+	return errors.Join(errs...)
+}
+
+// coldFields filters the fields that need a restart.
+func coldFields(fields []string) []string {
+	cold := []string{}
+	for _, f := range fields {
+		if !hotFields[f] {
+			cold = append(cold, f)
+		}
+	}
+	return cold
+}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 // AppConfig represents the root XML configuration structure
 type AppConfig struct {
-	XMLName xml.Name `xml:"PLCLogVisualizer"`
+	XMLName xml.Name `xml:"PLCLogVisualizer" json:"-"`
 	
 	// Server configuration
 	Server ServerConfig `xml:"Server"`
@@ -53,11 +55,14 @@ type StorageConfig struct {
 // ProcessingConfig contains parsing and processing settings
 type ProcessingConfig struct {
 	MaxConcurrentParses  int  `xml:"MaxConcurrentParses"`
+	MaxSessions          int  `xml:"MaxSessions"`
 	SessionTimeoutMinutes int `xml:"SessionTimeoutMinutes"`
 	CleanupIntervalMinutes int `xml:"CleanupIntervalMinutes"`
 	EnableCompression    bool `xml:"EnableCompression"`
 	CompressionLevel     int  `xml:"CompressionLevel"`
 	MaxMemoryPerSession  string `xml:"MaxMemoryPerSession"`
+	AutoDetectAnomalies  bool   `xml:"AutoDetectAnomalies"`
+	BuildSearchIndex     bool   `xml:"BuildSearchIndex"`
 }
 
 // SecurityConfig contains security settings
@@ -72,8 +77,9 @@ type SecurityConfig struct {
 type AdvancedConfig struct {
 	LogLevel            string `xml:"LogLevel"`
 	EnableRequestLogging bool  `xml:"EnableRequestLogging"`
-	DuckDBThreads       int    `xml:"DuckDBThreads"`
-	DuckDBMemoryLimit   string `xml:"DuckDBMemoryLimit"`
+	DuckDBThreads       int    `xml:"DuckDBThreads"`     // Shared by all open sessions
+	DuckDBMemoryLimit   string `xml:"DuckDBMemoryLimit"` // Shared by all open sessions
+	DuckDBQueriesPerSession int `xml:"DuckDBQueriesPerSession"`
 	WebSocketMaxMessageSize int `xml:"WebSocketMaxMessageSizeKB"`
 }
 
@@ -100,11 +106,14 @@ func DefaultConfig() *AppConfig {
 		},
 		Processing: ProcessingConfig{
 			MaxConcurrentParses:    3,
+			MaxSessions:            10,
 			SessionTimeoutMinutes:  30,
 			CleanupIntervalMinutes: 5,
 			EnableCompression:      true,
 			CompressionLevel:       5,
 			MaxMemoryPerSession:    "1GB",
+			AutoDetectAnomalies:    false,
+			BuildSearchIndex:       false,
 		},
 		Security: SecurityConfig{
 			AllowFileDeletion: true,
@@ -117,6 +126,7 @@ func DefaultConfig() *AppConfig {
 			EnableRequestLogging:     true,
 			DuckDBThreads:            4,
 			DuckDBMemoryLimit:        "1GB",
+			DuckDBQueriesPerSession:  3,
 			WebSocketMaxMessageSize:  65536,
 		},
 	}
@@ -214,3 +224,32 @@ func (c *AppConfig) EnsureDirectories() error {
 
 	return nil
 }
+
+// ParseByteSize parses sizes such as "512MB", "4GB" or "2G" (binary units) into bytes.
+// An empty string is 0.
+func ParseByteSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	case strings.HasSuffix(s, "T"):
+		mult = 1 << 40
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// AnnotationKind describes what part of a log an annotation is attached to.
+type AnnotationKind string
+
+const (
+	AnnotationKindPoint AnnotationKind = "point" // A single timestamp
+	AnnotationKindRange AnnotationKind = "range" // A time range [StartTime, EndTime]
+	AnnotationKindEntry AnnotationKind = "entry" // A specific entry row (device/signal at StartTime)
+)
+
+// Annotation is a persistent bookmark or note attached to a parsed log file.
+// Annotations are keyed by file ID so they survive across sessions and users.
+type Annotation struct {
+	ID         string         `json:"id"`
+	FileID     string         `json:"fileId"`
+	Kind       AnnotationKind `json:"kind"`
+	StartTime  int64          `json:"startTime"`         // Unix ms
+	EndTime    int64          `json:"endTime,omitempty"` // Unix ms, only for ranges
+	DeviceID   string         `json:"deviceId,omitempty"`
+	SignalName string         `json:"signalName,omitempty"`
+	Text       string         `json:"text"`
+	Author     string         `json:"author,omitempty"`
+	Tags       []string       `json:"tags,omitempty"`
+	Color      string         `json:"color,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+// End returns the last timestamp covered by the annotation.
+func (a *Annotation) End() int64 {
+	if a.Kind == AnnotationKindRange && a.EndTime > a.StartTime {
+		return a.EndTime
+	}
+	return a.StartTime
+}
+
+// Overlaps reports whether the annotation intersects the [start, end] window (Unix ms).
+func (a *Annotation) Overlaps(start, end int64) bool {
+	return a.StartTime <= end && a.End() >= start
+}
+
+// HasTag reports whether the annotation carries the given tag.
+func (a *Annotation) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ExportProgress reports how far a running entries export has got.
+// Total is the number of matching entries, known once the export has started.
+type ExportProgress struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Format    string    `json:"format"`
+	Rows      int       `json:"rows"`
+	Total     int       `json:"total"`
+	Done      bool      `json:"done"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+}
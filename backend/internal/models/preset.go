@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// FilterPreset is a named, server-side set of entry filters, signal selection and time window
+// that the team applies by ID instead of rebuilding it in every client.
+// Presets without a workspace are global; the others are visible in their workspace only.
+type FilterPreset struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Workspace   string `json:"workspace,omitempty"`
+	Author      string `json:"author,omitempty"`
+
+	// Entry filters, named like the query parameters of the entries endpoint
+	Search          string   `json:"search,omitempty"`
+	Regex           bool     `json:"regex,omitempty"`
+	CaseSensitive   bool     `json:"caseSensitive,omitempty"`
+	SearchMode      string   `json:"searchMode,omitempty"`
+	ShowChangedOnly bool     `json:"showChangedOnly,omitempty"`
+	Categories      []string `json:"categories,omitempty"`
+	SignalType      string   `json:"signalType,omitempty"`
+	SortColumn      string   `json:"sortColumn,omitempty"`
+	SortDirection   string   `json:"sortDirection,omitempty"`
+	Filter          string   `json:"filter,omitempty"` // Filter expression (see parser.CompileFilter)
+
+	// Signal selection ("deviceId::signalName") and time window (Unix ms, 0 = unbounded)
+	Signals   []string `json:"signals,omitempty"`
+	StartTime int64    `json:"startTime,omitempty"`
+	EndTime   int64    `json:"endTime,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// VisibleIn reports whether the preset can be used in a workspace ("" = global only).
+func (p *FilterPreset) VisibleIn(workspace string) bool {
+	return p.Workspace == "" || p.Workspace == workspace
+}
@@ -4,10 +4,12 @@ package models
 type SessionStatus string
 
 const (
-	SessionStatusPending  SessionStatus = "pending"
-	SessionStatusParsing  SessionStatus = "parsing"
-	SessionStatusComplete SessionStatus = "complete"
-	SessionStatusError    SessionStatus = "error"
+	SessionStatusPending   SessionStatus = "pending"
+	SessionStatusParsing   SessionStatus = "parsing"
+	SessionStatusComplete  SessionStatus = "complete"
+	SessionStatusError     SessionStatus = "error"
+	SessionStatusPaused    SessionStatus = "paused"
+	SessionStatusCancelled SessionStatus = "cancelled"
 )
 
 // ParseSession represents a file parsing session.
@@ -16,7 +18,9 @@ type ParseSession struct {
 	FileID           string        `json:"fileId"`
 	FileIDs          []string      `json:"fileIds,omitempty"` // All file IDs for merged sessions
 	Status           SessionStatus `json:"status"`
-	Progress         float64       `json:"progress"` // 0-100
+	Progress         float64       `json:"progress"`                // 0-100
+	QueuePosition    int           `json:"queuePosition,omitempty"` // 1-based position while waiting for a parse slot
+	Priority         int           `json:"priority,omitempty"`      // Higher priorities are parsed first
 	EntryCount       int           `json:"entryCount,omitempty"`
 	SignalCount      int           `json:"signalCount,omitempty"`
 	ProcessingTimeMs int64         `json:"processingTimeMs,omitempty"`
@@ -43,3 +47,16 @@ func NewParseSession(id, fileID string) *ParseSession {
 		Errors:   make([]ParseError, 0),
 	}
 }
+
+// Clone returns a copy of the session that shares no slices with it.
+func (s *ParseSession) Clone() *ParseSession {
+	c := *s
+	c.FileIDs = append([]string(nil), s.FileIDs...)
+	c.Errors = append([]ParseError(nil), s.Errors...)
+	return &c
+}
+
+// Finished reports whether the session will not change anymore.
+func (s SessionStatus) Finished() bool {
+	return s == SessionStatusComplete || s == SessionStatusError || s == SessionStatusCancelled
+}
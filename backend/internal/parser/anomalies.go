@@ -0,0 +1,375 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// AnomalyType is the kind of a detected anomaly.
+type AnomalyType string
+
+const (
+	AnomalyStuck            AnomalyType = "stuck"             // signal never changes
+	AnomalyChattering       AnomalyType = "chattering"        // boolean toggling faster than the threshold
+	AnomalyHeartbeatStopped AnomalyType = "heartbeat-stopped" // heartbeat or counter stops changing
+	AnomalyDeviceSilent     AnomalyType = "device-silent"     // busy device logs nothing for a while
+)
+
+// AnomalySeverity ranks anomalies.
+type AnomalySeverity string
+
+const (
+	SeverityInfo     AnomalySeverity = "info"
+	SeverityWarning  AnomalySeverity = "warning"
+	SeverityCritical AnomalySeverity = "critical"
+)
+
+// AnomalyConfig holds the detection thresholds; zero fields take the defaults
+// of DefaultAnomalyConfig. Durations are in ms.
+type AnomalyConfig struct {
+	// StuckMinSamples is the number of samples a signal needs before a constant value counts as stuck.
+	StuckMinSamples int `json:"stuckMinSamples,omitempty"`
+	// ChatterWindow and ChatterMinToggles: a boolean chatters when it toggles at least
+	// ChatterMinToggles times within ChatterWindow.
+	ChatterWindow     int64 `json:"chatterWindow,omitempty"`
+	ChatterMinToggles int   `json:"chatterMinToggles,omitempty"`
+	// HeartbeatMinChanges is the number of changes before a counter or heartbeat is watched.
+	HeartbeatMinChanges int `json:"heartbeatMinChanges,omitempty"`
+	// DeviceMinEntries is the number of distinct timestamps before a device counts as busy.
+	DeviceMinEntries int `json:"deviceMinEntries,omitempty"`
+	// A heartbeat stops or a device goes silent when a gap exceeds both MinGap and
+	// GapFactor times the median gap of the signal or device.
+	MinGap    int64   `json:"minGap,omitempty"`
+	GapFactor float64 `json:"gapFactor,omitempty"`
+}
+
+// DefaultAnomalyConfig returns the default detection thresholds.
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		StuckMinSamples:     3,
+		ChatterWindow:       1000,
+		ChatterMinToggles:   10,
+		HeartbeatMinChanges: 10,
+		DeviceMinEntries:    100,
+		MinGap:              10000,
+		GapFactor:           10,
+	}
+}
+
+func (c AnomalyConfig) withDefaults() AnomalyConfig {
+	d := DefaultAnomalyConfig()
+	if c.StuckMinSamples <= 0 {
+		c.StuckMinSamples = d.StuckMinSamples
+	}
+	if c.ChatterWindow <= 0 {
+		c.ChatterWindow = d.ChatterWindow
+	}
+	if c.ChatterMinToggles <= 0 {
+		c.ChatterMinToggles = d.ChatterMinToggles
+	}
+	if c.HeartbeatMinChanges <= 0 {
+		c.HeartbeatMinChanges = d.HeartbeatMinChanges
+	}
+	if c.DeviceMinEntries <= 0 {
+		c.DeviceMinEntries = d.DeviceMinEntries
+	}
+	if c.MinGap <= 0 {
+		c.MinGap = d.MinGap
+	}
+	if c.GapFactor <= 0 {
+		c.GapFactor = d.GapFactor
+	}
+	return c
+}
+
+// Anomaly is a detected problem over a time range (Unix ms).
+// SignalName is empty for device anomalies.
+type Anomaly struct {
+	Type       AnomalyType     `json:"type"`
+	Severity   AnomalySeverity `json:"severity"`
+	DeviceID   string          `json:"deviceId"`
+	SignalName string          `json:"signalName,omitempty"`
+	Start      int64           `json:"start"`
+	End        int64           `json:"end"`
+	Value      string          `json:"value,omitempty"`
+	Detail     string          `json:"detail"`
+}
+
+// AnomalyQuery selects a page of anomalies. Empty filters match everything.
+type AnomalyQuery struct {
+	Config   AnomalyConfig   `json:"config"`
+	Type     AnomalyType     `json:"type,omitempty"`
+	Severity AnomalySeverity `json:"severity,omitempty"`
+	DeviceID string          `json:"deviceId,omitempty"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+}
+
+// AnomalyReport is a page of anomalies. Counts holds the number of anomalies
+// of each type before filtering.
+type AnomalyReport struct {
+	Anomalies []Anomaly           `json:"anomalies"`
+	Total     int                 `json:"total"`
+	Page      int                 `json:"page"`
+	PageSize  int                 `json:"pageSize"`
+	Counts    map[AnomalyType]int `json:"counts"`
+}
+
+// GetAnomalies returns a page of the anomalies detected with the query config.
+func (ds *DuckStore) GetAnomalies(ctx context.Context, q AnomalyQuery) (*AnomalyReport, error) {
+	switch q.Type {
+	case "", AnomalyStuck, AnomalyChattering, AnomalyHeartbeatStopped, AnomalyDeviceSilent:
+	default:
+		return nil, fmt.Errorf("%w: unknown anomaly type %q", ErrInvalidQuery, q.Type)
+	}
+	switch q.Severity {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidQuery, q.Severity)
+	}
+
+	all, err := ds.DetectAnomalies(ctx, q.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AnomalyReport{Anomalies: []Anomaly{}, Page: q.Page, PageSize: q.PageSize, Counts: make(map[AnomalyType]int)}
+	var matched []Anomaly
+	for _, a := range all {
+		report.Counts[a.Type]++
+		if (q.Type == "" || a.Type == q.Type) && (q.Severity == "" || a.Severity == q.Severity) &&
+			(q.DeviceID == "" || a.DeviceID == q.DeviceID) {
+			matched = append(matched, a)
+		}
+	}
+	report.Total = len(matched)
+
+	start := (q.Page - 1) * q.PageSize
+	if start < 0 {
+		start = 0
+	}
+	if start < len(matched) {
+		end := start + q.PageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		report.Anomalies = matched[start:end]
+	}
+	return report, nil
+}
+
+// DetectAnomalies runs every detector over the recorded signals (derived signals are not
+// checked) and returns the anomalies ordered by start time. The result of the last config
+// is cached until the store data changes.
+func (ds *DuckStore) DetectAnomalies(ctx context.Context, cfg AnomalyConfig) ([]Anomaly, error) {
+	cfg = cfg.withDefaults()
+
+	// Held during detection so an automatic run after Finalize and a request share one pass
+	ds.anomalyMu.Lock()
+	defer ds.anomalyMu.Unlock()
+	if ds.anomalies != nil && ds.anomalyCfg == cfg {
+		return ds.anomalies, nil
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	anomalies := []Anomaly{}
+	for _, detect := range []func(context.Context, AnomalyConfig) ([]Anomaly, error){
+		ds.detectStuck, ds.detectChattering, ds.detectStoppedHeartbeats, ds.detectSilentDevices,
+	} {
+		found, err := detect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, found...)
+	}
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.DeviceID != b.DeviceID {
+			return a.DeviceID < b.DeviceID
+		}
+		return a.SignalName < b.SignalName
+	})
+
+	ds.anomalies = anomalies
+	ds.anomalyCfg = cfg
+	return anomalies, nil
+}
+
+// detectStuck flags signals with enough samples that all hold the same value.
+func (ds *DuckStore) detectStuck(ctx context.Context, cfg AnomalyConfig) ([]Anomaly, error) {
+	query := fmt.Sprintf(`
+		SELECT device_id, signal, COUNT(*), MIN(timestamp), MAX(timestamp), MIN(v)
+		FROM (SELECT device_id, signal, timestamp, %s AS v FROM entries)
+		GROUP BY device_id, signal
+		HAVING COUNT(*) >= ? AND COUNT(DISTINCT v) = 1
+	`, valueTextExpr)
+	rows, err := ds.db.QueryContext(ctx, query, cfg.StuckMinSamples)
+	if err != nil {
+		return nil, fmt.Errorf("stuck signal query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var anomalies []Anomaly
+	for rows.Next() {
+		a := Anomaly{Type: AnomalyStuck, Severity: SeverityInfo}
+		var samples int
+		if err := rows.Scan(&a.DeviceID, &a.SignalName, &samples, &a.Start, &a.End, &a.Value); err != nil {
+			return nil, err
+		}
+		a.Detail = fmt.Sprintf("%d samples, value never changes", samples)
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// detectChattering flags time ranges where a boolean signal toggles at least ChatterMinToggles
+// times within ChatterWindow. Overlapping windows are merged into one anomaly; a peak of
+// twice the threshold is critical.
+func (ds *DuckStore) detectChattering(ctx context.Context, cfg AnomalyConfig) ([]Anomaly, error) {
+	query := fmt.Sprintf(`
+		WITH changes AS (
+			SELECT device_id, signal, timestamp FROM (
+				SELECT device_id, signal, timestamp, val_bool,
+					LAG(val_bool) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev
+				FROM entries WHERE val_type = 0
+			) WHERE prev IS NOT NULL AND val_bool <> prev
+		), windows AS (
+			SELECT device_id, signal, timestamp, COUNT(*) OVER f AS toggles, MAX(timestamp) OVER f AS win_end
+			FROM changes
+			WINDOW f AS (PARTITION BY device_id, signal ORDER BY timestamp RANGE BETWEEN CURRENT ROW AND %d FOLLOWING)
+		), flagged AS (
+			SELECT *, MAX(win_end) OVER (PARTITION BY device_id, signal ORDER BY timestamp
+				ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING) AS prev_end
+			FROM windows WHERE toggles >= ?
+		), islands AS (
+			SELECT *, SUM(CASE WHEN timestamp <= prev_end THEN 0 ELSE 1 END)
+				OVER (PARTITION BY device_id, signal ORDER BY timestamp) AS grp
+			FROM flagged
+		)
+		SELECT device_id, signal, MIN(timestamp), MAX(win_end), MAX(toggles)
+		FROM islands GROUP BY device_id, signal, grp
+	`, cfg.ChatterWindow-1)
+	rows, err := ds.db.QueryContext(ctx, query, cfg.ChatterMinToggles)
+	if err != nil {
+		return nil, fmt.Errorf("chattering query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var anomalies []Anomaly
+	for rows.Next() {
+		a := Anomaly{Type: AnomalyChattering, Severity: SeverityWarning}
+		var peak int
+		if err := rows.Scan(&a.DeviceID, &a.SignalName, &a.Start, &a.End, &peak); err != nil {
+			return nil, err
+		}
+		if peak >= 2*cfg.ChatterMinToggles {
+			a.Severity = SeverityCritical
+		}
+		a.Detail = fmt.Sprintf("up to %d toggles within %d ms", peak, cfg.ChatterWindow)
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// detectStoppedHeartbeats flags gaps in heartbeat signals. A signal is a heartbeat when its
+// name contains "heartbeat", or a counter when it is an integer and at least 90% of its
+// changes are increments. A gap running to the end of the log means the heartbeat never resumed.
+func (ds *DuckStore) detectStoppedHeartbeats(ctx context.Context, cfg AnomalyConfig) ([]Anomaly, error) {
+	query := fmt.Sprintf(`
+		WITH changes AS (
+			SELECT * FROM (
+				SELECT device_id, signal, timestamp, val_type, v, n,
+					LAG(v) OVER w AS prev, LAG(n) OVER w AS prev_n
+				FROM (SELECT *, %s AS v, %s AS n FROM entries)
+				WINDOW w AS (PARTITION BY device_id, signal ORDER BY timestamp, id)
+			) WHERE prev IS NOT NULL AND v <> prev
+		), beats AS (
+			SELECT device_id, signal FROM changes
+			GROUP BY device_id, signal
+			HAVING COUNT(*) >= ? AND (signal ILIKE '%%heartbeat%%'
+				OR (BOOL_AND(val_type = 1) AND COUNT(*) FILTER (WHERE n > prev_n) >= 0.9 * COUNT(*)))
+		), gaps AS (
+			SELECT device_id, signal, ts, COALESCE(next_ts, ?) AS next_ts, next_ts IS NULL AS open,
+				COALESCE(next_ts, ?) - ts AS gap
+			FROM (
+				SELECT device_id, signal, ts, LEAD(ts) OVER (PARTITION BY device_id, signal ORDER BY ts) AS next_ts
+				FROM (SELECT DISTINCT device_id, signal, timestamp AS ts FROM changes JOIN beats USING (device_id, signal))
+			)
+		), medians AS (
+			SELECT device_id, signal, QUANTILE_CONT(gap, 0.5) AS med_gap FROM gaps WHERE NOT open GROUP BY device_id, signal
+		)
+		SELECT g.device_id, g.signal, g.ts, g.next_ts, g.open, m.med_gap
+		FROM gaps g JOIN medians m USING (device_id, signal)
+		WHERE g.gap > GREATEST(?, m.med_gap * ?)
+	`, valueTextExpr, valueNumExpr)
+	rows, err := ds.db.QueryContext(ctx, query, cfg.HeartbeatMinChanges, ds.maxTs, ds.maxTs, cfg.MinGap, cfg.GapFactor)
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var anomalies []Anomaly
+	for rows.Next() {
+		a := Anomaly{Type: AnomalyHeartbeatStopped, Severity: SeverityCritical}
+		var open bool
+		var median float64
+		if err := rows.Scan(&a.DeviceID, &a.SignalName, &a.Start, &a.End, &open, &median); err != nil {
+			return nil, err
+		}
+		a.Detail = fmt.Sprintf("no change for %d ms (usually every %.0f ms)", a.End-a.Start, median)
+		if open {
+			a.Detail += ", never resumed"
+		}
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
+
+// detectSilentDevices flags gaps where a busy device logs nothing. A gap of more than
+// ten times the threshold is critical.
+func (ds *DuckStore) detectSilentDevices(ctx context.Context, cfg AnomalyConfig) ([]Anomaly, error) {
+	query := `
+		WITH gaps AS (
+			SELECT device_id, ts, next_ts, next_ts - ts AS gap FROM (
+				SELECT device_id, ts, LEAD(ts) OVER (PARTITION BY device_id ORDER BY ts) AS next_ts
+				FROM (SELECT DISTINCT device_id, timestamp AS ts FROM entries)
+			) WHERE next_ts IS NOT NULL
+		), busy AS (
+			SELECT device_id, QUANTILE_CONT(gap, 0.5) AS med_gap FROM gaps
+			GROUP BY device_id HAVING COUNT(*) + 1 >= ?
+		)
+		SELECT g.device_id, g.ts, g.next_ts, b.med_gap, GREATEST(?, b.med_gap * ?) AS threshold
+		FROM gaps g JOIN busy b USING (device_id)
+		WHERE g.gap > GREATEST(?, b.med_gap * ?)
+	`
+	rows, err := ds.db.QueryContext(ctx, query, cfg.DeviceMinEntries, cfg.MinGap, cfg.GapFactor, cfg.MinGap, cfg.GapFactor)
+	if err != nil {
+		return nil, fmt.Errorf("device silence query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var anomalies []Anomaly
+	for rows.Next() {
+		a := Anomaly{Type: AnomalyDeviceSilent, Severity: SeverityWarning}
+		var median, threshold float64
+		if err := rows.Scan(&a.DeviceID, &a.Start, &a.End, &median, &threshold); err != nil {
+			return nil, err
+		}
+		if float64(a.End-a.Start) > 10*threshold {
+			a.Severity = SeverityCritical
+		}
+		a.Detail = fmt.Sprintf("silent for %d ms (usually every %.0f ms)", a.End-a.Start, median)
+		anomalies = append(anomalies, a)
+	}
+	return anomalies, rows.Err()
+}
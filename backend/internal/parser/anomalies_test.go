@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_DetectAnomalies(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	// PLC logs every 100ms for 100s but goes silent between 40s and 60s
+	for ms := 0; ms <= 100_000; ms += 100 {
+		if ms > 40_000 && ms < 60_000 {
+			continue
+		}
+		store.AddEntry(createTestEntry("PLC", "Mode", at(ms), "AUTO", ""))
+		// Counter increments every second and stops at 80s
+		if ms%1000 == 0 && ms <= 80_000 {
+			store.AddEntry(createTestEntry("PLC", "Counter", at(ms), ms/1000, ""))
+		}
+	}
+	// Sensor toggles every 50ms between 10s and 11s
+	for i := 0; i <= 20; i++ {
+		store.AddEntry(createTestEntry("PLC", "Sensor", at(10_000+i*50), i%2 == 0, ""))
+	}
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	anomalies, err := store.DetectAnomalies(ctx, AnomalyConfig{})
+	if err != nil {
+		t.Fatalf("DetectAnomalies failed: %v", err)
+	}
+	found := make(map[AnomalyType][]Anomaly)
+	for _, a := range anomalies {
+		found[a.Type] = append(found[a.Type], a)
+	}
+
+	if stuck := found[AnomalyStuck]; len(stuck) != 1 || stuck[0].SignalName != "Mode" || stuck[0].Value != "AUTO" {
+		t.Errorf("Expected Mode stuck at AUTO, got %+v", stuck)
+	}
+
+	chatter := found[AnomalyChattering]
+	if len(chatter) != 1 || chatter[0].SignalName != "Sensor" {
+		t.Fatalf("Expected one chattering range of Sensor, got %+v", chatter)
+	}
+	if chatter[0].Start != base.UnixMilli()+10_050 || chatter[0].End != base.UnixMilli()+11_000 {
+		t.Errorf("Expected chattering from 10.05s to 11s, got %d..%d",
+			chatter[0].Start-base.UnixMilli(), chatter[0].End-base.UnixMilli())
+	}
+	if chatter[0].Severity != SeverityCritical {
+		t.Errorf("Expected 20 toggles per second to be critical, got %s", chatter[0].Severity)
+	}
+
+	beats := found[AnomalyHeartbeatStopped]
+	if len(beats) != 2 {
+		t.Fatalf("Expected the counter to stop during the silence and at 80s, got %+v", beats)
+	}
+	if beats[0].Start != base.UnixMilli()+40_000 || beats[0].End != base.UnixMilli()+60_000 {
+		t.Errorf("Expected first counter gap from 40s to 60s, got %+v", beats[0])
+	}
+	if beats[1].Start != base.UnixMilli()+80_000 || beats[1].End != base.UnixMilli()+100_000 {
+		t.Errorf("Expected counter stopped from 80s to the end, got %+v", beats[1])
+	}
+
+	silent := found[AnomalyDeviceSilent]
+	if len(silent) != 1 || silent[0].DeviceID != "PLC" || silent[0].End-silent[0].Start != 20_000 {
+		t.Errorf("Expected PLC silent for 20s, got %+v", silent)
+	}
+
+	// Paging and filtering reuse the cached detection
+	report, err := store.GetAnomalies(ctx, AnomalyQuery{Type: AnomalyHeartbeatStopped, Page: 2, PageSize: 1})
+	if err != nil {
+		t.Fatalf("GetAnomalies failed: %v", err)
+	}
+	if report.Total != 2 || len(report.Anomalies) != 1 || report.Anomalies[0].Start != beats[1].Start {
+		t.Errorf("Expected the second heartbeat anomaly on page 2, got %+v", report)
+	}
+	if report.Counts[AnomalyStuck] != 1 || report.Counts[AnomalyChattering] != 1 {
+		t.Errorf("Expected unfiltered counts per type, got %v", report.Counts)
+	}
+
+	if _, err := store.GetAnomalies(ctx, AnomalyQuery{Type: "flaky", Page: 1, PageSize: 10}); err == nil {
+		t.Error("Expected an error for an unknown anomaly type")
+	}
+}
@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/marcboeker/go-duckdb"
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// ArrowStreamMIME is the content type of Arrow IPC stream responses.
+const ArrowStreamMIME = "application/vnd.apache.arrow.stream"
+
+var arrowStringDict = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+
+// arrowEntryFields is the columnar layout of log entries. Device, signal, category and
+// signal type are dictionary encoded since few distinct names repeat over many rows; the
+// value sits in the column of its type (val_bool, val_int, val_float or val_str) and the
+// other value columns are null, as in the entries table.
+var arrowEntryFields = []arrow.Field{
+	{Name: "timestamp", Type: arrow.FixedWidthTypes.Timestamp_ms},
+	{Name: "device_id", Type: arrowStringDict},
+	{Name: "signal", Type: arrowStringDict},
+	{Name: "category", Type: arrowStringDict},
+	{Name: "signal_type", Type: arrowStringDict},
+	{Name: "val_bool", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	{Name: "val_int", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	{Name: "val_float", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "val_str", Type: arrow.BinaryTypes.String, Nullable: true},
+}
+
+// WriteArrowEntries writes entries as a single-batch Arrow IPC stream. Metadata is
+// attached to the schema (e.g. paging totals).
+func WriteArrowEntries(w io.Writer, entries []models.LogEntry, metadata map[string]string) error {
+	return writeArrowEntries(w, entries, nil, metadata)
+}
+
+// WriteArrowBoundaries writes boundary values as an Arrow IPC stream of entries with an
+// extra dictionary encoded "side" column ("before" or "after"), ordered by side and signal.
+func WriteArrowBoundaries(w io.Writer, b *BoundaryValues) error {
+	var entries []models.LogEntry
+	var sides []string
+	for _, side := range []struct {
+		name   string
+		values map[string]models.LogEntry
+	}{{"before", b.Before}, {"after", b.After}} {
+		keys := make([]string, 0, len(side.values))
+		for k := range side.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			entries = append(entries, side.values[k])
+			sides = append(sides, side.name)
+		}
+	}
+	return writeArrowEntries(w, entries, sides, nil)
+}
+
+func writeArrowEntries(w io.Writer, entries []models.LogEntry, sides []string, metadata map[string]string) error {
+	fields := arrowEntryFields
+	if sides != nil {
+		fields = append(append([]arrow.Field{}, fields...), arrow.Field{Name: "side", Type: arrowStringDict})
+	}
+	var md *arrow.Metadata
+	if len(metadata) > 0 {
+		m := arrow.MetadataFrom(metadata)
+		md = &m
+	}
+	schema := arrow.NewSchema(fields, md)
+
+	mem := memory.DefaultAllocator
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+
+	ts := b.Field(0).(*array.TimestampBuilder)
+	device := b.Field(1).(*array.BinaryDictionaryBuilder)
+	signal := b.Field(2).(*array.BinaryDictionaryBuilder)
+	category := b.Field(3).(*array.BinaryDictionaryBuilder)
+	signalType := b.Field(4).(*array.BinaryDictionaryBuilder)
+	valBool := b.Field(5).(*array.BooleanBuilder)
+	valInt := b.Field(6).(*array.Int64Builder)
+	valFloat := b.Field(7).(*array.Float64Builder)
+	valStr := b.Field(8).(*array.StringBuilder)
+	b.Reserve(len(entries))
+
+	for i := range entries {
+		e := &entries[i]
+		ts.Append(arrow.Timestamp(e.Timestamp.UnixMilli()))
+		if err := device.AppendString(e.DeviceID); err != nil {
+			return err
+		}
+		if err := signal.AppendString(e.SignalName); err != nil {
+			return err
+		}
+		if err := category.AppendString(e.Category); err != nil {
+			return err
+		}
+		if err := signalType.AppendString(string(e.SignalType)); err != nil {
+			return err
+		}
+
+		switch v := e.Value.(type) {
+		case bool:
+			valBool.Append(v)
+		case int:
+			valInt.Append(int64(v))
+		case int64:
+			valInt.Append(v)
+		case float64:
+			valFloat.Append(v)
+		case string:
+			valStr.Append(v)
+		default:
+			valStr.Append(fmt.Sprint(v))
+		}
+		// every value column gets a null except the one of the value's type
+		for _, vb := range []array.Builder{valBool, valInt, valFloat, valStr} {
+			if vb.Len() <= i {
+				vb.AppendNull()
+			}
+		}
+	}
+	if sides != nil {
+		side := b.Field(9).(*array.BinaryDictionaryBuilder)
+		for _, s := range sides {
+			if err := side.AppendString(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(mem))
+	if err := writer.Write(rec); err != nil {
+		writer.Close()
+		return fmt.Errorf("arrow write failed: %w", err)
+	}
+	return writer.Close()
+}
+
+// RunSQLArrow runs a console query like RunSQL and writes the result to w as an Arrow IPC
+// stream, converted by DuckDB itself. It returns the number of rows written and whether
+// the result was cut at the row limit.
+func (ds *DuckStore) RunSQLArrow(ctx context.Context, w io.Writer, q SQLQuery) (int, bool, error) {
+	limit, timeout := q.limits()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return 0, false, ctx.Err()
+	}
+
+	query, err := ds.consoleQuery(ctx, q.SQL, limit)
+	if err != nil {
+		return 0, false, err
+	}
+
+	conn, err := ds.db.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	var n int
+	var truncated bool
+	err = conn.Raw(func(dc interface{}) error {
+		ar, err := duckdb.NewArrowFromConn(dc.(driver.Conn))
+		if err != nil {
+			return err
+		}
+		reader, err := ar.QueryContext(ctx, query)
+		if err != nil {
+			return sqlRunError(ctx, err)
+		}
+		defer reader.Release()
+
+		writer := ipc.NewWriter(w, ipc.WithSchema(reader.Schema()))
+		for reader.Next() && !truncated {
+			rec := reader.Record()
+			if n+int(rec.NumRows()) > limit {
+				rec = rec.NewSlice(0, int64(limit-n))
+				defer rec.Release()
+				truncated = true
+			}
+			if err := writer.Write(rec); err != nil {
+				writer.Close()
+				return fmt.Errorf("arrow write failed: %w", err)
+			}
+			n += int(rec.NumRows())
+		}
+		if err := reader.Err(); err != nil {
+			writer.Close()
+			return sqlRunError(ctx, err)
+		}
+		return writer.Close()
+	})
+	return n, truncated, err
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+func readArrowStream(t *testing.T, data []byte) (*arrow.Schema, []arrow.Record) {
+	t.Helper()
+	r, err := ipc.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Not an Arrow stream: %v", err)
+	}
+	defer r.Release()
+	var recs []arrow.Record
+	for r.Next() {
+		rec := r.Record()
+		rec.Retain()
+		recs = append(recs, rec)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("Reading Arrow stream failed: %v", err)
+	}
+	return r.Schema(), recs
+}
+
+func TestWriteArrowEntries(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	entries := []models.LogEntry{
+		{DeviceID: "PLC", SignalName: "Motor", Timestamp: base, Value: true, SignalType: models.SignalTypeBoolean},
+		{DeviceID: "PLC", SignalName: "Speed", Timestamp: base.Add(time.Second), Value: 2.5, SignalType: models.SignalTypeInteger},
+		{DeviceID: "PLC", SignalName: "Motor", Timestamp: base.Add(2 * time.Second), Value: false, SignalType: models.SignalTypeBoolean},
+		{DeviceID: "AUX", SignalName: "State", Timestamp: base.Add(3 * time.Second), Value: "RUN", SignalType: models.SignalTypeString},
+		{DeviceID: "AUX", SignalName: "Count", Timestamp: base.Add(4 * time.Second), Value: 7, SignalType: models.SignalTypeInteger},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArrowEntries(&buf, entries, map[string]string{"total": "5"}); err != nil {
+		t.Fatalf("WriteArrowEntries failed: %v", err)
+	}
+	schema, recs := readArrowStream(t, buf.Bytes())
+	if v, _ := schema.Metadata().GetValue("total"); v != "5" {
+		t.Errorf("Expected total metadata 5, got %q", v)
+	}
+	if len(recs) != 1 || recs[0].NumRows() != 5 {
+		t.Fatalf("Expected one batch of 5 rows, got %d batches", len(recs))
+	}
+	rec := recs[0]
+	defer rec.Release()
+
+	if ts := rec.Column(0).(*array.Timestamp).Value(1); int64(ts) != base.Add(time.Second).UnixMilli() {
+		t.Errorf("Unexpected timestamp %d", ts)
+	}
+	signals := rec.Column(2).(*array.Dictionary)
+	if dict := signals.Dictionary().(*array.String); dict.Len() != 4 || signals.GetValueIndex(2) != signals.GetValueIndex(0) {
+		t.Errorf("Expected 4 dictionary encoded signal names, got %d", dict.Len())
+	}
+
+	valBool := rec.Column(5).(*array.Boolean)
+	valInt := rec.Column(6).(*array.Int64)
+	valFloat := rec.Column(7).(*array.Float64)
+	valStr := rec.Column(8).(*array.String)
+	if !valBool.Value(0) || valBool.Value(2) || !valBool.IsNull(1) {
+		t.Error("Unexpected val_bool column")
+	}
+	if valFloat.Value(1) != 2.5 || valFloat.NullN() != 4 {
+		t.Error("Unexpected val_float column")
+	}
+	if valStr.Value(3) != "RUN" || valInt.Value(4) != 7 || valInt.NullN() != 4 {
+		t.Error("Unexpected val_str or val_int column")
+	}
+}
+
+func TestWriteArrowBoundaries(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	b := &BoundaryValues{
+		Before: map[string]models.LogEntry{
+			"PLC::Speed": {DeviceID: "PLC", SignalName: "Speed", Timestamp: base, Value: 1.5},
+			"PLC::Motor": {DeviceID: "PLC", SignalName: "Motor", Timestamp: base, Value: true},
+		},
+		After: map[string]models.LogEntry{
+			"PLC::Motor": {DeviceID: "PLC", SignalName: "Motor", Timestamp: base.Add(time.Minute), Value: false},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteArrowBoundaries(&buf, b); err != nil {
+		t.Fatalf("WriteArrowBoundaries failed: %v", err)
+	}
+	schema, recs := readArrowStream(t, buf.Bytes())
+	defer recs[0].Release()
+	if schema.Field(9).Name != "side" || recs[0].NumRows() != 3 {
+		t.Fatalf("Expected 3 rows with a side column, got %s", schema)
+	}
+	sides := recs[0].Column(9).(*array.Dictionary)
+	dict := sides.Dictionary().(*array.String)
+	signals := recs[0].Column(2).(*array.Dictionary)
+	signalDict := signals.Dictionary().(*array.String)
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, dict.Value(sides.GetValueIndex(i))+":"+signalDict.Value(signals.GetValueIndex(i)))
+	}
+	if got[0] != "before:Motor" || got[1] != "before:Speed" || got[2] != "after:Motor" {
+		t.Errorf("Unexpected boundary rows %v", got)
+	}
+}
+
+func TestDuckStore_RunSQLArrow(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	for i := 0; i < 5; i++ {
+		store.AddEntry(createTestEntry("PLC", "Count", base.Add(time.Duration(i)*time.Second), i, ""))
+	}
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, truncated, err := store.RunSQLArrow(context.Background(), &buf, SQLQuery{SQL: "SELECT timestamp, val_int FROM changes ORDER BY timestamp", Limit: 3})
+	if err != nil {
+		t.Fatalf("RunSQLArrow failed: %v", err)
+	}
+	if n != 3 || !truncated {
+		t.Errorf("Expected 3 truncated rows, got %d (truncated %v)", n, truncated)
+	}
+	schema, recs := readArrowStream(t, buf.Bytes())
+	rows := int64(0)
+	for _, rec := range recs {
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	if schema.NumFields() != 2 || schema.Field(1).Name != "val_int" || rows != 3 {
+		t.Errorf("Unexpected result %s with %d rows", schema, rows)
+	}
+
+	if _, _, err := store.RunSQLArrow(context.Background(), &buf, SQLQuery{SQL: "DROP TABLE entries"}); err == nil {
+		t.Error("Expected DROP to be rejected")
+	}
+}
@@ -21,6 +21,7 @@ Benefits:
 package parser
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -620,10 +621,10 @@ func (p *BinaryFormatParser) CanParse(filePath string) (bool, error) {
 }
 
 func (p *BinaryFormatParser) Parse(filePath string) (*models.ParsedLog, []*models.ParseError, error) {
-	return p.ParseWithProgress(filePath, nil)
+	return p.ParseWithProgress(context.Background(), filePath, nil)
 }
 
-func (p *BinaryFormatParser) ParseWithProgress(filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
+func (p *BinaryFormatParser) ParseWithProgress(ctx context.Context, filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, nil, err
@@ -642,11 +643,18 @@ func (p *BinaryFormatParser) ParseWithProgress(filePath string, onProgress Progr
 		onProgress(0, 0, totalBytes)
 	}
 
+	// The decoder reads the file in one go, so cancellation is only checked around it
+	if err := Checkpoint(ctx); err != nil {
+		return nil, nil, err
+	}
 	decoder := NewBinaryDecoder(file)
 	parsed, err := decoder.Decode()
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := Checkpoint(ctx); err != nil {
+		return nil, nil, err
+	}
 
 	// Report final progress
 	if onProgress != nil {
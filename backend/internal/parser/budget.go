@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// minStoreMemory is the smallest memory share a store is admitted with.
+const minStoreMemory = 128 << 20
+
+// ErrResourceBudget is returned when opening a store would shrink the memory share of
+// every store below minStoreMemory.
+var ErrResourceBudget = errors.New("DuckDB memory budget exhausted")
+
+// ResourceLimits is the DuckDB budget shared by all stores of the process.
+type ResourceLimits struct {
+	MemoryLimit     int64 // Bytes across all stores
+	MaxStoreMemory  int64 // Bytes a single store may use; 0 means MemoryLimit
+	Threads         int   // DuckDB threads across all stores
+	QueriesPerStore int   // Concurrent queries per store
+}
+
+// DefaultResourceLimits are used until SetResourceLimits is called.
+var DefaultResourceLimits = ResourceLimits{
+	MemoryLimit:     1 << 30,
+	MaxStoreMemory:  1 << 30,
+	Threads:         4,
+	QueriesPerStore: 3,
+}
+
+// ResourceStatus reports the budget and how it is currently shared.
+type ResourceStatus struct {
+	MemoryLimit     int64 `json:"memoryLimit"`
+	MaxStoreMemory  int64 `json:"maxStoreMemory"`
+	Threads         int   `json:"threads"`
+	QueriesPerStore int   `json:"queriesPerStore"`
+	OpenStores      int   `json:"openStores"`
+	MaxStores       int   `json:"maxStores"`
+	StoreMemory     int64 `json:"storeMemory"`  // memory_limit of each open store
+	StoreThreads    int   `json:"storeThreads"` // threads of each open store
+	MemoryInUse     int64 `json:"memoryInUse"`  // Sum of the memory limits of open stores
+	Rejected        int64 `json:"rejected"`     // Stores refused since startup
+}
+
+// resourceBudget splits the limits evenly over the open stores. Every store gets
+// MemoryLimit/n (at most MaxStoreMemory) and Threads/n (at least one), re-applied to the
+// open stores whenever one opens or closes.
+type resourceBudget struct {
+	mu       sync.Mutex
+	limits   ResourceLimits
+	stores   map[*DuckStore]struct{}
+	opening  int // admitted stores whose database is not open yet
+	rejected int64
+
+	// Current share, read without mu by connections opened while rebalancing
+	memShare    atomic.Int64
+	threadShare atomic.Int64
+}
+
+var budget = &resourceBudget{
+	limits: DefaultResourceLimits,
+	stores: make(map[*DuckStore]struct{}),
+}
+
+// SetResourceLimits replaces the shared budget; zero fields keep their defaults. Open
+// stores are rebalanced immediately.
+func SetResourceLimits(l ResourceLimits) {
+	if l.MemoryLimit <= 0 {
+		l.MemoryLimit = DefaultResourceLimits.MemoryLimit
+	}
+	if l.MaxStoreMemory <= 0 || l.MaxStoreMemory > l.MemoryLimit {
+		l.MaxStoreMemory = l.MemoryLimit
+	}
+	if l.Threads <= 0 {
+		l.Threads = DefaultResourceLimits.Threads
+	}
+	if l.QueriesPerStore <= 0 {
+		l.QueriesPerStore = DefaultResourceLimits.QueriesPerStore
+	}
+
+	budget.mu.Lock()
+	budget.limits = l
+	stores := budget.rebalanceLocked()
+	budget.mu.Unlock()
+	budget.apply(stores)
+}
+
+// GetResourceStatus returns the budget and the current share of each store.
+func GetResourceStatus() ResourceStatus {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	n := len(budget.stores)
+	mem, threads := budget.shareLocked(n)
+	st := ResourceStatus{
+		MemoryLimit:     budget.limits.MemoryLimit,
+		MaxStoreMemory:  budget.limits.MaxStoreMemory,
+		Threads:         budget.limits.Threads,
+		QueriesPerStore: budget.limits.QueriesPerStore,
+		OpenStores:      n,
+		MaxStores:       budget.maxStoresLocked(),
+		Rejected:        budget.rejected,
+	}
+	if n > 0 {
+		st.StoreMemory = mem
+		st.StoreThreads = threads
+		st.MemoryInUse = mem * int64(n)
+	}
+	return st
+}
+
+// CanOpenStore reports whether another store would currently be admitted.
+func CanOpenStore() bool {
+	return StoreHeadroom() > 0
+}
+
+// StoreHeadroom is the number of further stores that would currently be admitted.
+func StoreHeadroom() int {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	return budget.maxStoresLocked() - len(budget.stores) - budget.opening
+}
+
+func (b *resourceBudget) maxStoresLocked() int {
+	n := int(b.limits.MemoryLimit / minStoreMemory)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// shareLocked is the memory and thread share of each of n stores.
+func (b *resourceBudget) shareLocked(n int) (int64, int) {
+	if n < 1 {
+		n = 1
+	}
+	mem := b.limits.MemoryLimit / int64(n)
+	if mem > b.limits.MaxStoreMemory {
+		mem = b.limits.MaxStoreMemory
+	}
+	threads := b.limits.Threads / n
+	if threads < 1 {
+		threads = 1
+	}
+	return mem, threads
+}
+
+// admit reserves a share for a store about to be opened and returns its query
+// concurrency. Call attach with the opened store, or abort on failure.
+func (b *resourceBudget) admit() (int, error) {
+	b.mu.Lock()
+	if len(b.stores)+b.opening >= b.maxStoresLocked() {
+		b.rejected++
+		b.mu.Unlock()
+		return 0, ErrResourceBudget
+	}
+	b.opening++
+	stores := b.rebalanceLocked()
+	queries := b.limits.QueriesPerStore
+	b.mu.Unlock()
+
+	b.apply(stores)
+	return queries, nil
+}
+
+func (b *resourceBudget) attach(ds *DuckStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.opening--
+	b.stores[ds] = struct{}{}
+}
+
+func (b *resourceBudget) abort() {
+	b.mu.Lock()
+	b.opening--
+	stores := b.rebalanceLocked()
+	b.mu.Unlock()
+	b.apply(stores)
+}
+
+// release returns the share of a closed store to the others.
+func (b *resourceBudget) release(ds *DuckStore) {
+	b.mu.Lock()
+	if _, ok := b.stores[ds]; !ok {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.stores, ds)
+	stores := b.rebalanceLocked()
+	b.mu.Unlock()
+	b.apply(stores)
+}
+
+// rebalanceLocked computes the current share and returns the open stores it has to be
+// applied to. The SETs run in apply, after mu is released, so a slow store does not hold
+// up admission of the others.
+func (b *resourceBudget) rebalanceLocked() []*DuckStore {
+	mem, threads := b.shareLocked(len(b.stores) + b.opening)
+	b.memShare.Store(mem)
+	b.threadShare.Store(int64(threads))
+	stores := make([]*DuckStore, 0, len(b.stores))
+	for ds := range b.stores {
+		stores = append(stores, ds)
+	}
+	return stores
+}
+
+// apply sets the current share on each store, skipping stores that are closing. The
+// share is read when the SETs run, so concurrent rebalances converge on the latest one.
+func (b *resourceBudget) apply(stores []*DuckStore) {
+	for _, ds := range stores {
+		if !ds.Acquire() {
+			continue
+		}
+		for _, pragma := range b.sharePragmas() {
+			if _, err := ds.db.Exec(pragma); err != nil {
+				fmt.Printf("[DuckStore] Failed to apply %s: %v\n", pragma, err)
+			}
+		}
+		ds.Release()
+	}
+}
+
+// sharePragmas sets the current share on a connection; stores run them on every new one.
+func (b *resourceBudget) sharePragmas() []string {
+	return []string{
+		fmt.Sprintf("SET memory_limit='%dMiB'", b.memShare.Load()>>20),
+		fmt.Sprintf("SET threads=%d", b.threadShare.Load()),
+	}
+}
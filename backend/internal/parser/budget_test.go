@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResourceBudget(t *testing.T) {
+	open := GetResourceStatus().OpenStores
+	defer SetResourceLimits(DefaultResourceLimits)
+	SetResourceLimits(ResourceLimits{
+		MemoryLimit:     int64(open+2) * minStoreMemory,
+		Threads:         2,
+		QueriesPerStore: 2,
+	})
+
+	first, cleanupFirst := createTestStore(t)
+	defer cleanupFirst()
+	second, cleanupSecond := createTestStore(t)
+
+	memoryLimit := func(ds *DuckStore) string {
+		var v string
+		if err := ds.db.QueryRow("SELECT current_setting('memory_limit')").Scan(&v); err != nil {
+			t.Fatalf("Reading memory_limit failed: %v", err)
+		}
+		return v
+	}
+
+	st := GetResourceStatus()
+	if st.OpenStores != open+2 || st.StoreMemory != minStoreMemory || st.StoreThreads != 1 {
+		t.Errorf("Unexpected status %+v", st)
+	}
+	if cap(second.querySem) != 2 {
+		t.Errorf("Expected 2 concurrent queries per store, got %d", cap(second.querySem))
+	}
+	if CanOpenStore() {
+		t.Error("Expected the budget to be exhausted")
+	}
+	if _, err := NewDuckStore(t.TempDir(), "over_budget"); !errors.Is(err, ErrResourceBudget) {
+		t.Fatalf("Expected ErrResourceBudget, got %v", err)
+	}
+	if GetResourceStatus().Rejected == 0 {
+		t.Error("Expected the rejected store to be counted")
+	}
+	shared := memoryLimit(first)
+
+	// Closing a store gives its share to the others
+	cleanupSecond()
+	if !CanOpenStore() {
+		t.Error("Expected room for another store")
+	}
+	if got := memoryLimit(first); got == shared {
+		t.Errorf("Expected memory_limit to grow beyond %s", shared)
+	}
+}
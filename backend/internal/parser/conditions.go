@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidQuery marks errors caused by an invalid analysis request (bad rule, unknown condition, ...)
+// as opposed to database failures.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ConditionType is a comparison applied to an entry value.
+// The names match the condition types used by the frontend transition rules.
+type ConditionType string
+
+const (
+	ConditionAny       ConditionType = ""
+	ConditionEquals    ConditionType = "equals"
+	ConditionNotEquals ConditionType = "not-equals"
+	ConditionGreater   ConditionType = "greater"
+	ConditionLess      ConditionType = "less"
+	ConditionNotEmpty  ConditionType = "not-empty"
+	ConditionEmpty     ConditionType = "empty"
+)
+
+// valueTextExpr renders any entry value as text, matching String(value) on the frontend.
+const valueTextExpr = `CASE val_type WHEN 0 THEN CASE WHEN val_bool THEN 'true' ELSE 'false' END ` +
+	`WHEN 1 THEN CAST(val_int AS VARCHAR) WHEN 2 THEN CAST(val_float AS VARCHAR) ELSE COALESCE(val_str, '') END`
+
+// valueNumExpr renders any entry value as a DOUBLE (NULL for non-numeric strings).
+const valueNumExpr = `CASE val_type WHEN 0 THEN CAST(CAST(val_bool AS INTEGER) AS DOUBLE) ` +
+	`WHEN 1 THEN CAST(val_int AS DOUBLE) WHEN 2 THEN val_float ELSE TRY_CAST(val_str AS DOUBLE) END`
+
+// valueEmptyExpr is true for empty string values.
+const valueEmptyExpr = `(val_type = 3 AND COALESCE(val_str, '') = '')`
+
+// signalMatchSQL returns a SQL predicate matching a signal reference.
+// "deviceId::signalName" matches one signal; a bare name matches that signal on every device.
+func signalMatchSQL(signal string) (string, []interface{}) {
+	if parts := strings.Split(signal, "::"); len(parts) == 2 {
+		return "(device_id = ? AND signal = ?)", []interface{}{parts[0], parts[1]}
+	}
+	return "(signal = ?)", []interface{}{signal}
+}
+
+// conditionSQL returns a SQL predicate applying a condition to the value of an entries row.
+func conditionSQL(cond ConditionType, value interface{}) (string, []interface{}, error) {
+	switch cond {
+	case ConditionAny:
+		return "TRUE", nil, nil
+	case ConditionEquals:
+		return equalsSQL(value)
+	case ConditionNotEquals:
+		clause, args, err := equalsSQL(value)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(NOT " + clause + ")", args, nil
+	case ConditionGreater, ConditionLess:
+		num, ok := conditionNumber(value)
+		if !ok {
+			return "", nil, fmt.Errorf("condition %q needs a numeric value, got %v", cond, value)
+		}
+		op := ">"
+		if cond == ConditionLess {
+			op = "<"
+		}
+		return fmt.Sprintf("(COALESCE(%s %s ?, false))", valueNumExpr, op), []interface{}{num}, nil
+	case ConditionNotEmpty:
+		return "(NOT " + valueEmptyExpr + ")", nil, nil
+	case ConditionEmpty:
+		return valueEmptyExpr, nil, nil
+	default:
+		return "", nil, fmt.Errorf("unknown condition %q", cond)
+	}
+}
+
+// equalsSQL compares the entry value with an expected value.
+// Boolean rows also accept ON/OFF/TRUE/FALSE/YES/NO spellings of the expected value.
+func equalsSQL(value interface{}) (string, []interface{}, error) {
+	text := conditionText(value)
+	if b, ok := conditionBool(value); ok {
+		return fmt.Sprintf("((val_type = 0 AND val_bool = ?) OR (val_type <> 0 AND %s = ?))", valueTextExpr),
+			[]interface{}{b, text}, nil
+	}
+	return fmt.Sprintf("(%s = ?)", valueTextExpr), []interface{}{text}, nil
+}
+
+func conditionText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func conditionBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		u := strings.ToUpper(strings.TrimSpace(v))
+		if boolTrue[u] {
+			return true, true
+		}
+		if boolFalse[u] {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func conditionNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
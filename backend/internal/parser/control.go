@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"context"
+	"sync"
+)
+
+// checkpointLines is how many lines parsers read between checkpoints.
+const checkpointLines = 4096
+
+// PauseGate lets a running parse be paused and resumed. Attach it to the parse context
+// with WithPauseGate; the parser blocks at its next checkpoint while the gate is paused.
+type PauseGate struct {
+	mu     sync.Mutex
+	resume chan struct{} // nil while running, closed on resume
+}
+
+// NewPauseGate returns a gate in the running state.
+func NewPauseGate() *PauseGate {
+	return &PauseGate{}
+}
+
+// Pause makes parses using the gate stop at their next checkpoint.
+func (g *PauseGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume == nil {
+		g.resume = make(chan struct{})
+	}
+}
+
+// Resume lets paused parses continue.
+func (g *PauseGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume != nil {
+		close(g.resume)
+		g.resume = nil
+	}
+}
+
+// Paused reports whether the gate is paused.
+func (g *PauseGate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resume != nil
+}
+
+// wait blocks while the gate is paused, or until ctx is done.
+func (g *PauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type pauseGateKey struct{}
+
+// WithPauseGate returns a context carrying the pause gate of a parse.
+func WithPauseGate(ctx context.Context, g *PauseGate) context.Context {
+	return context.WithValue(ctx, pauseGateKey{}, g)
+}
+
+// Checkpoint is called by parsers between units of work. It returns the context error
+// once the parse is cancelled and blocks while the parse is paused.
+func Checkpoint(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g, ok := ctx.Value(pauseGateKey{}).(*PauseGate); ok {
+		return g.wait(ctx)
+	}
+	return nil
+}
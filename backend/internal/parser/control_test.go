@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauseGate(t *testing.T) {
+	gate := NewPauseGate()
+	ctx, cancel := context.WithCancel(WithPauseGate(context.Background(), gate))
+	defer cancel()
+
+	if err := Checkpoint(ctx); err != nil {
+		t.Fatalf("Running gate blocked: %v", err)
+	}
+
+	gate.Pause()
+	if !gate.Paused() {
+		t.Fatal("Expected gate to be paused")
+	}
+	done := make(chan error, 1)
+	go func() { done <- Checkpoint(ctx) }()
+	select {
+	case <-done:
+		t.Fatal("Checkpoint passed a paused gate")
+	case <-time.After(50 * time.Millisecond):
+	}
+	gate.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("Checkpoint failed after resume: %v", err)
+	}
+
+	// Cancelling releases a paused parse with the context error
+	gate.Pause()
+	go func() { done <- Checkpoint(ctx) }()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPLCDebugParser_Cancel(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 2*checkpointLines; i++ {
+		fmt.Fprintf(&sb, "2025-09-22 13:00:00.%03d [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n", i%1000)
+	}
+	path := filepath.Join(t.TempDir(), "cancel.log")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewPLCDebugParser()
+	if _, _, err := p.ParseWithProgress(ctx, path, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseWithProgress: expected context.Canceled, got %v", err)
+	}
+
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+	if _, err := p.ParseToDuckStore(ctx, path, store, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseToDuckStore: expected context.Canceled, got %v", err)
+	}
+}
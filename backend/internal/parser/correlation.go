@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// correlationHistogramBins is the number of lead time histogram bins over the window.
+const correlationHistogramBins = 10
+
+// CorrelationQuery looks for signals that change shortly before changes of a target signal.
+// Target events are value changes of Target whose new value satisfies the condition.
+// Start and End (Unix ms) bound the target events; zero means unbounded.
+type CorrelationQuery struct {
+	Target     string        `json:"target"`
+	Condition  ConditionType `json:"condition,omitempty"`
+	Value      interface{}   `json:"value,omitempty"`
+	Window     int64         `json:"window"`
+	Start      int64         `json:"start,omitempty"`
+	End        int64         `json:"end,omitempty"`
+	MinSupport int           `json:"minSupport,omitempty"`
+	Limit      int           `json:"limit,omitempty"`
+}
+
+// CorrelationCandidate is a signal ranked by how consistently it changes before the target.
+// Support is the number of target events preceded by a change of the signal within the window
+// and Ratio the share of target events. Baseline estimates the chance that any window of the
+// same length holds a change of the signal; Score = Ratio - Baseline ranks the candidates.
+// Lead times (ms) are measured from the closest preceding change to the target event.
+type CorrelationCandidate struct {
+	Key        string  `json:"key"`
+	DeviceID   string  `json:"deviceId"`
+	SignalName string  `json:"signalName"`
+	Support    int     `json:"support"`
+	Ratio      float64 `json:"ratio"`
+	Baseline   float64 `json:"baseline"`
+	Score      float64 `json:"score"`
+	LeadMin    int64   `json:"leadMin"`
+	LeadMax    int64   `json:"leadMax"`
+	LeadMean   float64 `json:"leadMean"`
+	LeadP50    float64 `json:"leadP50"`
+	LeadP90    float64 `json:"leadP90"`
+	Histogram  []int   `json:"histogram"`
+}
+
+// CorrelationResult lists the candidates for a target, best first.
+type CorrelationResult struct {
+	Target       string                 `json:"target"`
+	TargetEvents int                    `json:"targetEvents"`
+	Window       int64                  `json:"window"`
+	Candidates   []CorrelationCandidate `json:"candidates"`
+}
+
+// FindCorrelations ranks all other signals by how consistently they change within the
+// window before the target events.
+func (ds *DuckStore) FindCorrelations(ctx context.Context, q CorrelationQuery) (*CorrelationResult, error) {
+	parts := strings.Split(q.Target, "::")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w: target %q must be deviceId::signalName", ErrInvalidQuery, q.Target)
+	}
+	if q.Window <= 0 {
+		return nil, fmt.Errorf("%w: window must be positive", ErrInvalidQuery)
+	}
+	condClause, condArgs, err := conditionSQL(q.Condition, q.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	rangeClause := ""
+	var rangeArgs []interface{}
+	if q.Start != 0 {
+		rangeClause += " AND timestamp >= ?"
+		rangeArgs = append(rangeArgs, q.Start)
+	}
+	if q.End != 0 {
+		rangeClause += " AND timestamp <= ?"
+		rangeArgs = append(rangeArgs, q.End)
+	}
+
+	// changes: value changes of every signal; targets: the matching changes of the target;
+	// leads: the closest preceding change of each other signal per target event.
+	cte := fmt.Sprintf(`
+		WITH changes AS (
+			SELECT * FROM (
+				SELECT id, timestamp, device_id, signal, val_type, val_bool, val_int, val_float, val_str, v,
+					LAG(v) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev
+				FROM (SELECT *, %s AS v FROM entries)
+			) WHERE prev IS NOT NULL AND v <> prev
+		), targets AS (
+			SELECT DISTINCT timestamp AS ts FROM changes
+			WHERE device_id = ? AND signal = ? AND %s%s
+		), leads AS (
+			SELECT c.device_id, c.signal, t.ts, MIN(t.ts - c.timestamp) AS lead
+			FROM targets t JOIN changes c
+				ON c.timestamp >= t.ts - ? AND c.timestamp < t.ts
+			WHERE NOT (c.device_id = ? AND c.signal = ?)
+			GROUP BY c.device_id, c.signal, t.ts
+		)
+	`, valueTextExpr, condClause, rangeClause)
+	args := []interface{}{parts[0], parts[1]}
+	args = append(args, condArgs...)
+	args = append(args, rangeArgs...)
+	args = append(args, q.Window, parts[0], parts[1])
+
+	result := &CorrelationResult{Target: q.Target, Window: q.Window, Candidates: []CorrelationCandidate{}}
+	if err := ds.db.QueryRowContext(ctx, cte+"SELECT COUNT(*) FROM targets", args...).Scan(&result.TargetEvents); err != nil {
+		return nil, fmt.Errorf("correlation target query failed: %w", err)
+	}
+	if result.TargetEvents == 0 {
+		return result, nil
+	}
+
+	// Baseline: the chance that a window of this length holds a change of the signal,
+	// estimated from the signal's overall change rate over the store time range.
+	duration := ds.maxTs - ds.minTs
+	if duration < q.Window {
+		duration = q.Window
+	}
+	minSupport := q.MinSupport
+	if minSupport < 1 {
+		minSupport = 1
+	}
+	limit := q.Limit
+	if limit < 1 {
+		limit = 50
+	}
+
+	rankQuery := cte + `
+		, ranked AS (
+			SELECT l.device_id, l.signal, COUNT(*) AS support,
+				MIN(l.lead) AS lead_min, MAX(l.lead) AS lead_max, AVG(l.lead) AS lead_mean,
+				QUANTILE_CONT(l.lead, 0.5) AS lead_p50, QUANTILE_CONT(l.lead, 0.9) AS lead_p90
+			FROM leads l GROUP BY l.device_id, l.signal HAVING COUNT(*) >= ?
+		), totals AS (
+			SELECT device_id, signal, COUNT(*) AS total FROM changes GROUP BY device_id, signal
+		)
+		SELECT r.device_id, r.signal, r.support, r.lead_min, r.lead_max, r.lead_mean, r.lead_p50, r.lead_p90,
+			LEAST(1.0, t.total * CAST(? AS DOUBLE) / ?) AS baseline
+		FROM ranked r JOIN totals t USING (device_id, signal)
+		ORDER BY r.support * 1.0 / ? - baseline DESC, r.support DESC, r.device_id, r.signal
+		LIMIT ?
+	`
+	rankArgs := append(append([]interface{}{}, args...), minSupport, q.Window, duration, result.TargetEvents, limit)
+	rows, err := ds.db.QueryContext(ctx, rankQuery, rankArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("correlation query failed: %w", err)
+	}
+	index := make(map[string]int)
+	for rows.Next() {
+		var c CorrelationCandidate
+		if err := rows.Scan(&c.DeviceID, &c.SignalName, &c.Support, &c.LeadMin, &c.LeadMax,
+			&c.LeadMean, &c.LeadP50, &c.LeadP90, &c.Baseline); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Key = c.DeviceID + "::" + c.SignalName
+		c.Ratio = float64(c.Support) / float64(result.TargetEvents)
+		c.Score = c.Ratio - c.Baseline
+		c.Histogram = make([]int, correlationHistogramBins)
+		index[c.Key] = len(result.Candidates)
+		result.Candidates = append(result.Candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.Candidates) == 0 {
+		return result, nil
+	}
+
+	// Lead time histograms of the ranked candidates
+	var keyClauses []string
+	histArgs := append([]interface{}{}, args...)
+	histArgs = append(histArgs, correlationHistogramBins, q.Window, correlationHistogramBins-1)
+	for _, c := range result.Candidates {
+		keyClauses = append(keyClauses, "(device_id = ? AND signal = ?)")
+		histArgs = append(histArgs, c.DeviceID, c.SignalName)
+	}
+	histQuery := cte + fmt.Sprintf(`
+		SELECT device_id, signal, LEAST(lead * ? // ?, ?) AS bin, COUNT(*)
+		FROM leads WHERE %s
+		GROUP BY device_id, signal, bin
+	`, strings.Join(keyClauses, " OR "))
+	rows, err = ds.db.QueryContext(ctx, histQuery, histArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("correlation histogram query failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deviceID, signal string
+		var bin, count int
+		if err := rows.Scan(&deviceID, &signal, &bin, &count); err != nil {
+			return nil, err
+		}
+		if i, ok := index[deviceID+"::"+signal]; ok && bin >= 0 && bin < correlationHistogramBins {
+			result.Candidates[i].Histogram[bin] = count
+		}
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_FindCorrelations(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	store.AddEntry(createTestEntry("DEV", "Error", at(0), "", ""))
+	store.AddEntry(createTestEntry("DEV", "Jam", at(0), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Noise", at(0), 0, ""))
+	for i := 0; i < 4; i++ {
+		t0 := 10_000 * (i + 1)
+		// Jam goes high 200-300ms before every error
+		store.AddEntry(createTestEntry("DEV", "Jam", at(t0-200-i*30), true, ""))
+		store.AddEntry(createTestEntry("DEV", "Error", at(t0), "E42", ""))
+		store.AddEntry(createTestEntry("DEV", "Error", at(t0+1000), "", ""))
+		store.AddEntry(createTestEntry("DEV", "Jam", at(t0+1000), false, ""))
+	}
+	// Noise changes only before the first error
+	store.AddEntry(createTestEntry("DEV", "Noise", at(9_500), 1, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	res, err := store.FindCorrelations(ctx, CorrelationQuery{
+		Target: "DEV::Error", Condition: ConditionNotEmpty, Window: 1000,
+	})
+	if err != nil {
+		t.Fatalf("FindCorrelations failed: %v", err)
+	}
+	if res.TargetEvents != 4 {
+		t.Fatalf("Expected 4 target events (cleared errors excluded), got %d", res.TargetEvents)
+	}
+	if len(res.Candidates) != 2 {
+		t.Fatalf("Expected Jam and Noise as candidates, got %+v", res.Candidates)
+	}
+
+	jam := res.Candidates[0]
+	if jam.Key != "DEV::Jam" || jam.Support != 4 || jam.Ratio != 1 {
+		t.Errorf("Expected Jam ranked first with full support, got %+v", jam)
+	}
+	if jam.LeadMin != 200 || jam.LeadMax != 290 {
+		t.Errorf("Expected leads between 200 and 290ms, got %d..%d", jam.LeadMin, jam.LeadMax)
+	}
+	if jam.Histogram[2] != 4 {
+		t.Errorf("Expected all leads in the 200-300ms bin, got %v", jam.Histogram)
+	}
+	if noise := res.Candidates[1]; noise.Support != 1 || noise.Score >= jam.Score {
+		t.Errorf("Expected Noise with support 1 ranked below Jam, got %+v", noise)
+	}
+
+	t.Run("min support", func(t *testing.T) {
+		res, err := store.FindCorrelations(ctx, CorrelationQuery{
+			Target: "DEV::Error", Condition: ConditionNotEmpty, Window: 1000, MinSupport: 2,
+		})
+		if err != nil {
+			t.Fatalf("FindCorrelations failed: %v", err)
+		}
+		if len(res.Candidates) != 1 {
+			t.Errorf("Expected only Jam, got %+v", res.Candidates)
+		}
+	})
+
+	t.Run("rejects invalid window", func(t *testing.T) {
+		if _, err := store.FindCorrelations(ctx, CorrelationQuery{Target: "DEV::Error"}); err == nil {
+			t.Error("Expected error for missing window")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"context"
 	"os"
 	"regexp"
 	"strings"
@@ -50,10 +51,10 @@ func (p *CSVSignalParser) CanParse(filePath string) (bool, error) {
 }
 
 func (p *CSVSignalParser) Parse(filePath string) (*models.ParsedLog, []*models.ParseError, error) {
-	return p.ParseWithProgress(filePath, nil)
+	return p.ParseWithProgress(context.Background(), filePath, nil)
 }
 
-func (p *CSVSignalParser) ParseWithProgress(filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
+func (p *CSVSignalParser) ParseWithProgress(ctx context.Context, filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, nil, err
@@ -106,6 +107,11 @@ func (p *CSVSignalParser) ParseWithProgress(filePath string, onProgress Progress
 	
 	for scanner.Scan() {
 		lineNum++
+		if lineNum%checkpointLines == 0 {
+			if err := Checkpoint(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
 		line := scanner.Text()
 		bytesRead += int64(len(line)) + 1
 		
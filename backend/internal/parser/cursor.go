@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// EntryPage is a page of entries read with a cursor. Next continues after the last entry
+// and Prev before the first; they are empty at the end and start of the result.
+type EntryPage struct {
+	Entries []models.LogEntry `json:"entries"`
+	Next    string            `json:"next,omitempty"`
+	Prev    string            `json:"prev,omitempty"`
+	Total   int               `json:"total"`
+}
+
+// entryCursor is a position between two entries of a sorted result: right after the entry
+// with sort key (KeyInt or KeyStr) and id ID, or right before it if Before is set.
+// Query binds the cursor to the filters and sort it was issued for.
+type entryCursor struct {
+	KeyInt int64  `json:"n,omitempty"`
+	KeyStr string `json:"s,omitempty"`
+	ID     int64  `json:"i"`
+	Before bool   `json:"b,omitempty"`
+	Query  string `json:"q"`
+}
+
+func (c entryCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// setKey stores a scanned sort key (an integer for id and timestamp, otherwise a string).
+func (c *entryCursor) setKey(key interface{}) {
+	switch k := key.(type) {
+	case string:
+		c.KeyStr = k
+	case int32:
+		c.KeyInt = int64(k)
+	case int64:
+		c.KeyInt = k
+	}
+}
+
+func decodeEntryCursor(token string) (entryCursor, error) {
+	var c entryCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return c, nil
+}
+
+// entrySort returns the sort column and direction of params; def is the default column.
+func entrySort(params QueryParams, def string) (string, string) {
+	col := def
+	switch params.SortColumn {
+	case "timestamp":
+		col = "timestamp"
+	case "deviceId":
+		col = "device_id"
+	case "signalName":
+		col = "signal"
+	case "category":
+		col = "category"
+	}
+	dir := "ASC"
+	if params.SortDirection == "desc" {
+		dir = "DESC"
+	}
+	return col, dir
+}
+
+// cursorQueryHash identifies a filter and sort, so cursors are not reused across queries.
+func cursorQueryHash(where string, args []interface{}, col, dir string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%v|%s|%s", where, args, col, dir)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// QueryEntriesCursor returns up to limit entries after the cursor, or before it if backward
+// is set, in the sort order of params. An empty cursor starts at the beginning (or, backward,
+// at the end). Each page is a keyset seek on (sort column, id), so reading deep into a large
+// result costs the same as reading its first page and needs no materialized id list.
+func (ds *DuckStore) QueryEntriesCursor(ctx context.Context, params QueryParams, cursor string, backward bool, limit int) (*EntryPage, error) {
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	where, args := ds.buildWhereClause(params)
+	col, dir := entrySort(params, "id")
+	hash := cursorQueryHash(where, args, col, dir)
+
+	total, err := ds.countMatching(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses []string
+	if where != "" {
+		clauses = append(clauses, "("+where+")")
+	}
+	queryArgs := append([]interface{}{}, args...)
+	if cursor != "" {
+		c, err := decodeEntryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Query != hash {
+			return nil, fmt.Errorf("%w: cursor belongs to a different filter or sort", ErrInvalidQuery)
+		}
+
+		// Seek past the cursor in reading direction. Reading forward from a position
+		// before an entry includes that entry, reading backward from after it does too.
+		op := ">"
+		if dir == "DESC" {
+			op = "<"
+		}
+		inclusive := c.Before
+		if backward {
+			op = map[string]string{">": "<", "<": ">"}[op]
+			inclusive = !c.Before
+		}
+		idOp := op
+		if inclusive {
+			idOp += "="
+		}
+		var key interface{} = c.KeyInt
+		if col != "id" && col != "timestamp" {
+			key = c.KeyStr
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", col, op, col, idOp))
+		queryArgs = append(queryArgs, key, key, c.ID)
+	}
+
+	readDir := dir
+	if backward {
+		readDir = map[string]string{"ASC": "DESC", "DESC": "ASC"}[dir]
+	}
+	query := fmt.Sprintf(`
+		SELECT id, %s, timestamp, device_id, signal, category, val_type, val_bool, val_int, val_float, val_str
+		FROM entries`, col)
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", col, readDir, readDir, limit+1)
+
+	rows, err := ds.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("cursor query failed: %w", err)
+	}
+	defer rows.Close()
+
+	type keyed struct {
+		cursor entryCursor
+		entry  models.LogEntry
+	}
+	var page []keyed
+	for rows.Next() {
+		var id int32
+		var key interface{}
+		var tsMs int64
+		var deviceID, signal, category string
+		var valType int
+		var valBool sql.NullBool
+		var valInt sql.NullInt64
+		var valFloat sql.NullFloat64
+		var valStr sql.NullString
+		if err := rows.Scan(&id, &key, &tsMs, &deviceID, &signal, &category, &valType, &valBool, &valInt, &valFloat, &valStr); err != nil {
+			return nil, err
+		}
+		c := entryCursor{ID: int64(id), Query: hash}
+		c.setKey(key)
+		page = append(page, keyed{cursor: c, entry: models.LogEntry{
+			Timestamp:  time.UnixMilli(tsMs),
+			DeviceID:   deviceID,
+			SignalName: signal,
+			Category:   category,
+			Value:      decodeValue(valType, valBool.Bool, valInt.Int64, valFloat.Float64, valStr.String),
+			SignalType: valTypeToSignalType(valType),
+		}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor scan failed: %w", err)
+	}
+
+	more := len(page) > limit
+	if more {
+		page = page[:limit]
+	}
+	if backward {
+		for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+			page[i], page[j] = page[j], page[i]
+		}
+	}
+
+	result := &EntryPage{Entries: make([]models.LogEntry, len(page)), Total: total}
+	for i, k := range page {
+		result.Entries[i] = k.entry
+	}
+	if len(page) == 0 {
+		// Nothing in reading direction; offer the way back
+		if backward {
+			result.Next = cursor
+		} else {
+			result.Prev = cursor
+		}
+		return result, nil
+	}
+
+	first, last := page[0].cursor, page[len(page)-1].cursor
+	first.Before = true
+	hasPrev, hasNext := cursor != "", more
+	if backward {
+		hasPrev, hasNext = more, cursor != ""
+	}
+	if hasPrev {
+		result.Prev = first.encode()
+	}
+	if hasNext {
+		result.Next = last.encode()
+	}
+	return result, nil
+}
+
+// CursorAtTime returns a cursor right before the first entry (in the sort order of params)
+// at or after ts, the cursor equivalent of GetIndexByTime. It returns "" if there is none.
+func (ds *DuckStore) CursorAtTime(ctx context.Context, params QueryParams, ts int64) (string, error) {
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	where, args := ds.buildWhereClause(params)
+	col, dir := entrySort(params, "id")
+
+	query := fmt.Sprintf("SELECT id, %s FROM entries WHERE timestamp >= ?", col)
+	if where != "" {
+		query += " AND (" + where + ")"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT 1", col, dir, dir)
+
+	var id int32
+	var key interface{}
+	err := ds.db.QueryRowContext(ctx, query, append([]interface{}{ts}, args...)...).Scan(&id, &key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cursor query failed: %w", err)
+	}
+
+	c := entryCursor{ID: int64(id), Before: true, Query: cursorQueryHash(where, args, col, dir)}
+	c.setKey(key)
+	return c.encode(), nil
+}
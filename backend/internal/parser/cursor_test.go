@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_QueryEntriesCursor(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	for i := 0; i < 25; i++ {
+		device := fmt.Sprintf("DEV%d", i%3)
+		store.AddEntry(createTestEntry(device, "Counter", base.Add(time.Duration(i)*time.Second), i, ""))
+	}
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	values := func(page *EntryPage) []int {
+		var out []int
+		for _, e := range page.Entries {
+			out = append(out, e.Value.(int))
+		}
+		return out
+	}
+
+	t.Run("walks forward and back", func(t *testing.T) {
+		var seen []int
+		var pages []*EntryPage
+		cursor := ""
+		for {
+			page, err := store.QueryEntriesCursor(ctx, QueryParams{}, cursor, false, 10)
+			if err != nil {
+				t.Fatalf("QueryEntriesCursor failed: %v", err)
+			}
+			pages = append(pages, page)
+			seen = append(seen, values(page)...)
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		if len(seen) != 25 || seen[0] != 0 || seen[24] != 24 || len(pages) != 3 {
+			t.Fatalf("Expected 25 entries in 3 pages, got %v", seen)
+		}
+		if pages[0].Prev != "" || pages[0].Total != 25 {
+			t.Errorf("Expected no prev cursor on the first page and a total of 25, got %+v", pages[0])
+		}
+
+		back, err := store.QueryEntriesCursor(ctx, QueryParams{}, pages[2].Prev, true, 10)
+		if err != nil {
+			t.Fatalf("QueryEntriesCursor backward failed: %v", err)
+		}
+		if got := values(back); len(got) != 10 || got[0] != 10 || got[9] != 19 || back.Prev == "" || back.Next == "" {
+			t.Errorf("Expected entries 10-19 with both cursors, got %v", got)
+		}
+
+		last, _ := store.QueryEntriesCursor(ctx, QueryParams{}, "", true, 10)
+		if got := values(last); len(got) != 10 || got[9] != 24 || last.Next != "" {
+			t.Errorf("Expected the last 10 entries, got %v", got)
+		}
+	})
+
+	t.Run("sorted by device with filter", func(t *testing.T) {
+		params := QueryParams{SortColumn: "deviceId", SortDirection: "desc", Search: "DEV"}
+		var seen []int
+		cursor := ""
+		for i := 0; i < 10; i++ {
+			page, err := store.QueryEntriesCursor(ctx, params, cursor, false, 4)
+			if err != nil {
+				t.Fatalf("QueryEntriesCursor failed: %v", err)
+			}
+			seen = append(seen, values(page)...)
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		// DEV2 holds 2, 5, ..., 23 and comes first; ties keep id order reversed
+		if len(seen) != 25 || seen[0] != 23 || seen[7] != 2 || seen[8] != 22 {
+			t.Errorf("Unexpected order %v", seen)
+		}
+
+		if _, err := store.QueryEntriesCursor(ctx, QueryParams{}, cursor, false, 4); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected ErrInvalidQuery for a cursor of another sort, got %v", err)
+		}
+	})
+
+	t.Run("starts at a time", func(t *testing.T) {
+		cursor, err := store.CursorAtTime(ctx, QueryParams{}, base.Add(12*time.Second).UnixMilli())
+		if err != nil {
+			t.Fatalf("CursorAtTime failed: %v", err)
+		}
+		next, _ := store.QueryEntriesCursor(ctx, QueryParams{}, cursor, false, 3)
+		if got := values(next); len(got) != 3 || got[0] != 12 {
+			t.Errorf("Expected entries from 12, got %v", got)
+		}
+		prev, _ := store.QueryEntriesCursor(ctx, QueryParams{}, cursor, true, 3)
+		if got := values(prev); len(got) != 3 || got[2] != 11 {
+			t.Errorf("Expected entries up to 11, got %v", got)
+		}
+
+		if cursor, _ := store.CursorAtTime(ctx, QueryParams{}, base.Add(time.Hour).UnixMilli()); cursor != "" {
+			t.Errorf("Expected no cursor past the end, got %q", cursor)
+		}
+	})
+
+	if _, err := store.QueryEntriesCursor(ctx, QueryParams{}, "not-a-cursor", false, 10); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for a malformed cursor, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// DerivedDeviceID is the device of derived signals defined without one.
+const DerivedDeviceID = "Derived"
+
+// DerivedCategory is the category of entries produced by derived signals.
+const DerivedCategory = "derived"
+
+// DerivedSignal is a virtual signal computed from an expression over other signals
+// (see CompileExpression). Inputs hold their last value until they change, and the
+// derived signal has a value once every input has one.
+type DerivedSignal struct {
+	DeviceID   string            `json:"deviceId"`
+	Name       string            `json:"name"`
+	Expression string            `json:"expression"`
+	SignalType models.SignalType `json:"signalType"`
+}
+
+// Key returns the signal key (deviceId::name) of the derived signal.
+func (d DerivedSignal) Key() string {
+	return d.DeviceID + "::" + d.Name
+}
+
+// maxDerivedCached caps the value changes of a derived signal kept in memory; signals that
+// change more often are evaluated per query.
+const maxDerivedCached = 200_000
+
+type derivedSignal struct {
+	def  DerivedSignal
+	expr *CompiledExpression
+
+	// mu guards the cached value changes over the whole store, computed on first use.
+	// cachedLen is the store size they were computed at; tooMany marks a signal over
+	// maxDerivedCached changes.
+	mu        sync.Mutex
+	cached    bool
+	cachedLen int
+	tooMany   bool
+	changes   []models.LogEntry
+}
+
+// SetDerivedSignal compiles and adds (or replaces) a derived signal on this store.
+// Referenced signals are typed with queries bound to ctx.
+func (ds *DuckStore) SetDerivedSignal(ctx context.Context, def DerivedSignal) (DerivedSignal, error) {
+	def.Name = strings.TrimSpace(def.Name)
+	def.DeviceID = strings.TrimSpace(def.DeviceID)
+	if def.DeviceID == "" {
+		def.DeviceID = DerivedDeviceID
+	}
+	if def.Name == "" || strings.Contains(def.Name, "::") || strings.Contains(def.DeviceID, "::") {
+		return def, fmt.Errorf("%w: derived signal needs a name without '::'", ErrInvalidQuery)
+	}
+	if ds.hasSignal(def.Key()) {
+		return def, fmt.Errorf("%w: %s is a recorded signal", ErrInvalidQuery, def.Key())
+	}
+
+	expr, err := CompileExpression(def.Expression, func(ref string) (exprType, bool) {
+		return ds.expressionRefType(ctx, ref)
+	})
+	if err != nil {
+		return def, err
+	}
+	if len(expr.Columns) == 0 {
+		return def, fmt.Errorf("%w: expression must reference at least one signal", ErrInvalidQuery)
+	}
+	switch expr.Type {
+	case exprBool:
+		def.SignalType = valTypeToSignalType(valTypeBool)
+	case exprNum:
+		def.SignalType = valTypeToSignalType(valTypeFloat)
+	default:
+		def.SignalType = valTypeToSignalType(valTypeString)
+	}
+
+	ds.derivedMu.Lock()
+	if ds.derived == nil {
+		ds.derived = make(map[string]*derivedSignal)
+	}
+	ds.derived[def.Key()] = &derivedSignal{def: def, expr: expr}
+	ds.derivedMu.Unlock()
+	return def, nil
+}
+
+// RemoveDerivedSignal removes a derived signal by key. Returns false if it did not exist.
+func (ds *DuckStore) RemoveDerivedSignal(key string) bool {
+	ds.derivedMu.Lock()
+	defer ds.derivedMu.Unlock()
+	if _, ok := ds.derived[key]; !ok {
+		return false
+	}
+	delete(ds.derived, key)
+	return true
+}
+
+// DerivedSignals returns the derived signals of this store sorted by key.
+func (ds *DuckStore) DerivedSignals() []DerivedSignal {
+	ds.derivedMu.RLock()
+	defer ds.derivedMu.RUnlock()
+	defs := make([]DerivedSignal, 0, len(ds.derived))
+	for _, d := range ds.derived {
+		defs = append(defs, d.def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Key() < defs[j].Key() })
+	return defs
+}
+
+// expressionRefType resolves the type of a recorded signal referenced by an expression.
+func (ds *DuckStore) expressionRefType(ctx context.Context, ref string) (exprType, bool) {
+	if !ds.hasSignal(ref) {
+		return 0, false
+	}
+	parts := strings.Split(ref, "::")
+	var valType int
+	err := ds.db.QueryRowContext(ctx, "SELECT MODE(val_type) FROM entries WHERE device_id = ? AND signal = ?", parts[0], parts[1]).Scan(&valType)
+	if err != nil {
+		return 0, false
+	}
+	switch valType {
+	case valTypeBool:
+		return exprBool, true
+	case valTypeInt, valTypeFloat:
+		return exprNum, true
+	default:
+		return exprStr, true
+	}
+}
+
+// splitDerived separates derived signal keys from recorded ones.
+// An empty selection selects every recorded and every derived signal; recorded is then nil.
+func (ds *DuckStore) splitDerived(signals []string) (recorded []string, derived []*derivedSignal) {
+	ds.derivedMu.RLock()
+	defer ds.derivedMu.RUnlock()
+
+	if len(signals) == 0 {
+		for _, d := range ds.derived {
+			derived = append(derived, d)
+		}
+		return nil, derived
+	}
+	for _, s := range signals {
+		if d, ok := ds.derived[s]; ok {
+			derived = append(derived, d)
+		} else {
+			recorded = append(recorded, s)
+		}
+	}
+	return recorded, derived
+}
+
+// derivedEntries evaluates a derived signal on entries up to untilMs and returns its value
+// changes selected by tail (a WHERE/ORDER BY/LIMIT clause over timestamp).
+func (ds *DuckStore) derivedEntries(ctx context.Context, d *derivedSignal, untilMs int64, tail string, tailArgs ...interface{}) ([]models.LogEntry, error) {
+	var refClauses, columns []string
+	var refArgs, colArgs []interface{}
+	seen := make(map[string]bool)
+	for i, col := range d.expr.Columns {
+		parts := strings.Split(col.ref, "::")
+		if !seen[col.ref] {
+			seen[col.ref] = true
+			refClauses = append(refClauses, "(device_id = ? AND signal = ?)")
+			refArgs = append(refArgs, parts[0], parts[1])
+		}
+
+		var value string
+		switch {
+		case col.kind == "delta":
+			value = "n - prev_n"
+		case col.kind == "rate":
+			value = "CASE WHEN timestamp > prev_ts THEN (n - prev_n) * 1000.0 / (timestamp - prev_ts) END"
+		case col.typ == exprBool:
+			value = "val_bool"
+		case col.typ == exprNum:
+			value = "n"
+		default:
+			value = "s"
+		}
+		columns = append(columns, fmt.Sprintf(
+			"LAST_VALUE(CASE WHEN device_id = ? AND signal = ? THEN %s END IGNORE NULLS) OVER w AS c%d", value, i))
+		colArgs = append(colArgs, parts[0], parts[1])
+	}
+
+	query := fmt.Sprintf(`
+		WITH src AS (
+			SELECT *, LAG(n) OVER p AS prev_n, LAG(timestamp) OVER p AS prev_ts
+			FROM (
+				SELECT id, timestamp, device_id, signal, val_bool, %s AS n, %s AS s
+				FROM entries WHERE timestamp <= ? AND (%s)
+			)
+			WINDOW p AS (PARTITION BY device_id, signal ORDER BY timestamp, id)
+		), wide AS (
+			SELECT timestamp, id, %s
+			FROM src
+			WINDOW w AS (ORDER BY timestamp, id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+		), evaluated AS (
+			SELECT timestamp, (%s) AS val FROM wide
+			QUALIFY ROW_NUMBER() OVER (PARTITION BY timestamp ORDER BY id DESC) = 1
+		), changes AS (
+			SELECT timestamp, val FROM (
+				SELECT timestamp, val, LAG(val) OVER (ORDER BY timestamp) AS prev,
+					ROW_NUMBER() OVER (ORDER BY timestamp) AS rn
+				FROM evaluated WHERE val IS NOT NULL
+			) WHERE rn = 1 OR val <> prev
+		)
+		SELECT timestamp, val FROM changes %s
+	`, valueNumExpr, valueTextExpr, strings.Join(refClauses, " OR "), strings.Join(columns, ", "), d.expr.SQL, tail)
+
+	args := append([]interface{}{untilMs}, refArgs...)
+	args = append(args, colArgs...)
+	args = append(args, tailArgs...)
+
+	rows, err := ds.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("derived signal %s query failed: %w", d.def.Key(), err)
+	}
+	defer rows.Close()
+
+	var entries []models.LogEntry
+	for rows.Next() {
+		var ts int64
+		entry := models.LogEntry{
+			DeviceID:   d.def.DeviceID,
+			SignalName: d.def.Name,
+			SignalType: d.def.SignalType,
+			Category:   DerivedCategory,
+		}
+		switch d.expr.Type {
+		case exprBool:
+			var v bool
+			if err := rows.Scan(&ts, &v); err != nil {
+				return nil, err
+			}
+			entry.Value = v
+		case exprNum:
+			var v float64
+			if err := rows.Scan(&ts, &v); err != nil {
+				return nil, err
+			}
+			entry.Value = v
+		default:
+			var v sql.NullString
+			if err := rows.Scan(&ts, &v); err != nil {
+				return nil, err
+			}
+			entry.Value = v.String
+		}
+		entry.Timestamp = time.UnixMilli(ts)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// derivedChanges returns the value changes of a derived signal over the whole store,
+// computing them on first use and again once the store has grown. ok is false if the
+// signal changes too often to cache; callers then query derivedEntries directly.
+func (ds *DuckStore) derivedChanges(ctx context.Context, d *derivedSignal) (changes []models.LogEntry, ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := ds.Len()
+	if d.cached && d.cachedLen == n {
+		return d.changes, !d.tooMany, nil
+	}
+
+	ds.mu.RLock()
+	maxTs := ds.maxTs
+	ds.mu.RUnlock()
+	entries, err := ds.derivedEntries(ctx, d, maxTs, fmt.Sprintf("ORDER BY timestamp LIMIT %d", maxDerivedCached+1))
+	if err != nil {
+		return nil, false, err
+	}
+	d.cached, d.cachedLen = true, n
+	d.tooMany = len(entries) > maxDerivedCached
+	d.changes = nil
+	if !d.tooMany {
+		d.changes = entries
+	}
+	return d.changes, !d.tooMany, nil
+}
+
+// derivedRange returns the value changes of a derived signal within [startMs, endMs].
+func (ds *DuckStore) derivedRange(ctx context.Context, d *derivedSignal, startMs, endMs int64) ([]models.LogEntry, error) {
+	changes, ok, err := ds.derivedChanges(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return ds.derivedEntries(ctx, d, endMs, "WHERE timestamp >= ? ORDER BY timestamp", startMs)
+	}
+	from := sort.Search(len(changes), func(i int) bool { return changes[i].Timestamp.UnixMilli() >= startMs })
+	to := sort.Search(len(changes), func(i int) bool { return changes[i].Timestamp.UnixMilli() > endMs })
+	if from >= to {
+		return nil, nil
+	}
+	return append([]models.LogEntry(nil), changes[from:to]...), nil
+}
+
+// derivedLatest returns the last value change of a derived signal at or before untilMs, if any.
+func (ds *DuckStore) derivedLatest(ctx context.Context, d *derivedSignal, untilMs int64) ([]models.LogEntry, error) {
+	changes, ok, err := ds.derivedChanges(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return ds.derivedEntries(ctx, d, untilMs, "ORDER BY timestamp DESC LIMIT 1")
+	}
+	i := sort.Search(len(changes), func(i int) bool { return changes[i].Timestamp.UnixMilli() > untilMs })
+	if i == 0 {
+		return nil, nil
+	}
+	return []models.LogEntry{changes[i-1]}, nil
+}
+
+// derivedNext returns the first value change of a derived signal after afterMs, if any.
+func (ds *DuckStore) derivedNext(ctx context.Context, d *derivedSignal, afterMs int64) ([]models.LogEntry, error) {
+	changes, ok, err := ds.derivedChanges(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		ds.mu.RLock()
+		maxTs := ds.maxTs
+		ds.mu.RUnlock()
+		return ds.derivedEntries(ctx, d, maxTs, "WHERE timestamp > ? ORDER BY timestamp LIMIT 1", afterMs)
+	}
+	i := sort.Search(len(changes), func(i int) bool { return changes[i].Timestamp.UnixMilli() > afterMs })
+	if i == len(changes) {
+		return nil, nil
+	}
+	return []models.LogEntry{changes[i]}, nil
+}
+
+// derivedChunk returns the value changes of derived signals within [startMs, endMs].
+func (ds *DuckStore) derivedChunk(ctx context.Context, derived []*derivedSignal, startMs, endMs int64) ([]models.LogEntry, error) {
+	var entries []models.LogEntry
+	for _, d := range derived {
+		chunk, err := ds.derivedRange(ctx, d, startMs, endMs)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, chunk...)
+	}
+	return entries, nil
+}
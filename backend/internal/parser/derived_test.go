@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_DerivedSignals(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	store.AddEntry(createTestEntry("DEV", "Motor", at(0), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(0), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Motor", at(100), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(300), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Motor", at(400), true, "")) // repeated, no change
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(500), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Counter", at(0), 10, ""))
+	store.AddEntry(createTestEntry("DEV", "Counter", at(1000), 30, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	ready, err := store.SetDerivedSignal(ctx, DerivedSignal{Name: "Ready", Expression: "DEV::Motor AND NOT DEV::Sensor"})
+	if err != nil {
+		t.Fatalf("SetDerivedSignal failed: %v", err)
+	}
+	if ready.Key() != "Derived::Ready" {
+		t.Fatalf("Expected default device, got %s", ready.Key())
+	}
+	if _, err := store.SetDerivedSignal(ctx, DerivedSignal{DeviceID: "Calc", Name: "Rate", Expression: "rate(DEV::Counter)"}); err != nil {
+		t.Fatalf("SetDerivedSignal failed: %v", err)
+	}
+
+	t.Run("listed with recorded signals", func(t *testing.T) {
+		if _, ok := store.GetSignals()["Derived::Ready"]; !ok {
+			t.Error("Expected derived signal in GetSignals")
+		}
+		types, _ := store.GetSignalTypes()
+		if types["Derived::Ready"] != "boolean" {
+			t.Errorf("Expected boolean type, got %q", types["Derived::Ready"])
+		}
+	})
+
+	t.Run("chunk holds value changes only", func(t *testing.T) {
+		entries, err := store.GetChunk(ctx, at(0), at(1000), []string{"Derived::Ready"})
+		if err != nil {
+			t.Fatalf("GetChunk failed: %v", err)
+		}
+		want := []struct {
+			ms int
+			v  bool
+		}{{0, false}, {100, true}, {300, false}, {500, true}}
+		if len(entries) != len(want) {
+			t.Fatalf("Expected %d changes, got %+v", len(want), entries)
+		}
+		for i, w := range want {
+			if !entries[i].Timestamp.Equal(at(w.ms)) || entries[i].Value != w.v {
+				t.Errorf("Change %d: expected %v at %d, got %v at %v", i, w.v, w.ms, entries[i].Value, entries[i].Timestamp)
+			}
+		}
+	})
+
+	t.Run("chunk mixes recorded and derived signals", func(t *testing.T) {
+		entries, err := store.GetChunk(ctx, at(200), at(1000), []string{"DEV::Sensor", "Calc::Rate"})
+		if err != nil {
+			t.Fatalf("GetChunk failed: %v", err)
+		}
+		if len(entries) != 3 || entries[2].Value != 20.0 {
+			t.Errorf("Expected two Sensor entries and one rate of 20/s, got %+v", entries)
+		}
+	})
+
+	t.Run("values at time and boundaries", func(t *testing.T) {
+		values, err := store.GetValuesAtTime(ctx, at(350), []string{"Derived::Ready"})
+		if err != nil || len(values) != 1 || values[0].Value != false {
+			t.Fatalf("Expected Ready=false at 350, got %+v (%v)", values, err)
+		}
+		bounds, err := store.GetBoundaryValues(ctx, at(150), at(350), []string{"Derived::Ready", "DEV::Motor"})
+		if err != nil {
+			t.Fatalf("GetBoundaryValues failed: %v", err)
+		}
+		if before := bounds.Before["Derived::Ready"]; !before.Timestamp.Equal(at(100)) || before.Value != true {
+			t.Errorf("Unexpected before value: %+v", before)
+		}
+		if after := bounds.After["Derived::Ready"]; !after.Timestamp.Equal(at(500)) {
+			t.Errorf("Unexpected after value: %+v", after)
+		}
+		if _, ok := bounds.Before["DEV::Motor"]; !ok {
+			t.Error("Expected recorded signal boundaries to be kept")
+		}
+	})
+
+	t.Run("value changes are computed once", func(t *testing.T) {
+		d := store.derived["Derived::Ready"]
+		if !d.cached || d.tooMany || len(d.changes) != 4 {
+			t.Fatalf("Expected 4 cached changes, got cached=%v %+v", d.cached, d.changes)
+		}
+		// Queries past the cache limit fall back to SQL with the same results
+		d.mu.Lock()
+		d.tooMany, d.changes = true, nil
+		d.mu.Unlock()
+		entries, err := store.GetChunk(ctx, at(100), at(400), []string{"Derived::Ready"})
+		if err != nil || len(entries) != 2 || entries[0].Value != true || entries[1].Value != false {
+			t.Errorf("Expected the changes at 100 and 300, got %+v (%v)", entries, err)
+		}
+	})
+
+	t.Run("rejects invalid definitions", func(t *testing.T) {
+		if _, err := store.SetDerivedSignal(ctx, DerivedSignal{DeviceID: "DEV", Name: "Motor", Expression: "DEV::Sensor"}); err == nil {
+			t.Error("Expected error shadowing a recorded signal")
+		}
+		if _, err := store.SetDerivedSignal(ctx, DerivedSignal{Name: "Bad", Expression: "DEV::Motor AND"}); err == nil {
+			t.Error("Expected syntax error")
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		if !store.RemoveDerivedSignal("Calc::Rate") || store.RemoveDerivedSignal("Calc::Rate") {
+			t.Error("Expected remove to succeed once")
+		}
+		if len(store.DerivedSignals()) != 1 {
+			t.Errorf("Expected one derived signal left, got %+v", store.DerivedSignals())
+		}
+	})
+}
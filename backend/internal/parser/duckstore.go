@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,23 +19,31 @@ import (
 // DuckStore stores log entries in a temporary DuckDB file for memory efficiency.
 // This allows parsing files larger than available RAM.
 type DuckStore struct {
-	db         *sql.DB
-	dbPath     string
+	db        *sql.DB
+	dbPath    string
+	batchSize int
+	batch     []*models.LogEntry
+	lastError error // stores the last flush error
+
+	// Entry count, signals, devices and time range; written by AddEntry, guarded by mu
+	// so they can be read while a parse is still adding entries
+	mu         sync.RWMutex
 	entryCount int
-	batchSize  int
-	batch      []*models.LogEntry
 	signals    map[string]struct{}
 	devices    map[string]struct{}
 	minTs      int64
 	maxTs      int64
-	lastError  error // stores the last flush error
 
 	// Cache for total counts by filter to avoid repeated COUNT queries
 	countCache   map[string]int
 	countCacheMu sync.RWMutex
 
-	// Semaphore to limit concurrent queries (prevents memory spikes during rapid scrolling)
+	// Semaphore to limit concurrent queries (prevents memory spikes during rapid scrolling);
+	// its size is ResourceLimits.QueriesPerStore
 	querySem chan struct{}
+	// seq numbers stores in the order they were opened; queries holding slots of two
+	// stores take them in this order
+	seq uint64
 
 	// Cache of ordered id lists for filtered pagination.
 	// Key: "where|sort|dir|args" — Value: ordered slice of matching row ids.
@@ -42,6 +51,35 @@ type DuckStore struct {
 	pageIndex   map[string][]int32
 	pageIndexMu sync.RWMutex
 
+	// Cache of per-signal statistics keyed by signal key (deviceId::signal).
+	// statsComplete is set once statistics for every signal have been computed.
+	statsCache    map[string]SignalStats
+	statsComplete bool
+	statsCacheMu  sync.RWMutex
+
+	// Derived (virtual) signals keyed by signal key, evaluated on demand
+	derived   map[string]*derivedSignal
+	derivedMu sync.RWMutex
+
+	// Anomalies detected with anomalyCfg; nil until the first detection
+	anomalies  []Anomaly
+	anomalyCfg AnomalyConfig
+	anomalyMu  sync.Mutex
+
+	// Inverted index for term and prefix searches; built on first use or in Finalize
+	searchIdx             *searchIndex
+	searchIndexMu         sync.Mutex
+	searchIndexOnFinalize bool
+
+	// Entries committed so far, readable while a parse is still adding entries
+	live liveState
+
+	// Readers using the store outside the session lock (console queries, exports); a
+	// Close while any remain is carried out by the last Release
+	refMu   sync.Mutex
+	refs    int
+	closing bool
+
 	// persistent means Close() should not delete the database file.
 	// Set for parsed files stored in the persistent cache.
 	persistent bool
@@ -58,15 +96,17 @@ func NewDuckStore(tempDir string, sessionID string) (*DuckStore, error) {
 func NewDuckStoreAtPath(dbPath string) (*DuckStore, error) {
 	fmt.Printf("[DuckStore] Creating database at: %s\n", dbPath)
 
+	// Reserve this store's share of the shared memory and thread budget
+	queries, err := budget.admit()
+	if err != nil {
+		return nil, err
+	}
+
 	// Open with optimized settings for large datasets
 	fmt.Printf("[DuckStore] Creating DuckDB connector...\n")
 	connector, err := duckdb.NewConnector(dbPath, func(execer driver.ExecerContext) error {
 		// Set memory limit and other pragmas
-		pragmas := []string{
-			"PRAGMA memory_limit='1GB'",
-			"PRAGMA threads=4",
-			"PRAGMA enable_progress_bar=false",
-		}
+		pragmas := append(budget.sharePragmas(), "PRAGMA enable_progress_bar=false")
 		for _, pragma := range pragmas {
 			fmt.Printf("[DuckStore] Executing: %s\n", pragma)
 			if _, err := execer.ExecContext(context.Background(), pragma, nil); err != nil {
@@ -78,6 +118,7 @@ func NewDuckStoreAtPath(dbPath string) (*DuckStore, error) {
 	})
 	if err != nil {
 		fmt.Printf("[DuckStore] ERROR creating connector: %v\n", err)
+		budget.abort()
 		return nil, fmt.Errorf("failed to create DuckDB connector: %w", err)
 	}
 	fmt.Printf("[DuckStore] Connector created successfully\n")
@@ -104,6 +145,7 @@ func NewDuckStoreAtPath(dbPath string) (*DuckStore, error) {
 		fmt.Printf("[DuckStore] ERROR creating table: %v\n", err)
 		db.Close()
 		os.Remove(dbPath)
+		budget.abort()
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 	fmt.Printf("[DuckStore] Table created successfully\n")
@@ -112,7 +154,7 @@ func NewDuckStoreAtPath(dbPath string) (*DuckStore, error) {
 	// Creating indexes during inserts significantly slows down the parsing phase.
 
 	fmt.Printf("[DuckStore] Initialization complete, ready for inserts\n")
-	return &DuckStore{
+	ds := &DuckStore{
 		db:         db,
 		dbPath:     dbPath,
 		batchSize:  50000, // 50K entries per batch for high performance with Appender
@@ -123,8 +165,12 @@ func NewDuckStoreAtPath(dbPath string) (*DuckStore, error) {
 		maxTs:      0,
 		countCache: make(map[string]int),
 		pageIndex:  make(map[string][]int32),
-		querySem:   make(chan struct{}, 3), // Max 3 concurrent queries
-	}, nil
+		statsCache: make(map[string]SignalStats),
+		querySem:   make(chan struct{}, queries),
+		seq:        storeSeq.Add(1),
+	}
+	budget.attach(ds)
+	return ds, nil
 }
 
 // OpenDuckStoreReadOnly opens an existing DuckDB file in read-only mode.
@@ -135,14 +181,15 @@ func OpenDuckStoreReadOnly(dbPath string) (*DuckStore, error) {
 
 	// Open with read-only mode to avoid file locking issues
 	// This allows multiple processes/connections to read the same database
+	queries, err := budget.admit()
+	if err != nil {
+		return nil, err
+	}
+
 	readOnlyPath := dbPath + "?access_mode=read_only"
 	connector, err := duckdb.NewConnector(readOnlyPath, func(execer driver.ExecerContext) error {
 		// Set pragmas optimized for read-only queries
-		pragmas := []string{
-			"PRAGMA memory_limit='1GB'",
-			"PRAGMA threads=4",
-			"PRAGMA enable_progress_bar=false",
-		}
+		pragmas := append(budget.sharePragmas(), "PRAGMA enable_progress_bar=false")
 		for _, pragma := range pragmas {
 			if _, err := execer.ExecContext(context.Background(), pragma, nil); err != nil {
 				fmt.Printf("[DuckStore] Pragma warning: %v\n", err)
@@ -152,6 +199,7 @@ func OpenDuckStoreReadOnly(dbPath string) (*DuckStore, error) {
 		return nil
 	})
 	if err != nil {
+		budget.abort()
 		return nil, fmt.Errorf("failed to open DuckDB connector: %w", err)
 	}
 
@@ -162,6 +210,7 @@ func OpenDuckStoreReadOnly(dbPath string) (*DuckStore, error) {
 	err = db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&entryCount)
 	if err != nil {
 		db.Close()
+		budget.abort()
 		return nil, fmt.Errorf("failed to get entry count: %w", err)
 	}
 
@@ -192,7 +241,7 @@ func OpenDuckStoreReadOnly(dbPath string) (*DuckStore, error) {
 	fmt.Printf("[DuckStore] Opened existing DB: %d entries, %d signals, %d devices\n",
 		entryCount, len(signals), len(devices))
 
-	return &DuckStore{
+	ds := &DuckStore{
 		db:         db,
 		dbPath:     dbPath,
 		entryCount: entryCount,
@@ -204,15 +253,21 @@ func OpenDuckStoreReadOnly(dbPath string) (*DuckStore, error) {
 		maxTs:      maxTs,
 		countCache: make(map[string]int),
 		pageIndex:  make(map[string][]int32),
-		querySem:   make(chan struct{}, 3),
+		statsCache: make(map[string]SignalStats),
+		querySem:   make(chan struct{}, queries),
+		seq:        storeSeq.Add(1),
 		persistent: true, // Read-only stores should never delete the file
-	}, nil
+	}
+	budget.attach(ds)
+	ds.live.committed = CommitInfo{Count: entryCount, StartTime: minTs, EndTime: maxTs}
+	return ds, nil
 }
 
 // AddEntry adds an entry to the store. Entries are batched for efficient insertion.
 func (ds *DuckStore) AddEntry(entry *models.LogEntry) {
 	ds.batch = append(ds.batch, entry)
 
+	ds.mu.Lock()
 	// Track signals and devices (use string concatenation instead of fmt.Sprintf for speed)
 	sigKey := entry.DeviceID + "::" + entry.SignalName
 	ds.signals[sigKey] = struct{}{}
@@ -228,6 +283,7 @@ func (ds *DuckStore) AddEntry(entry *models.LogEntry) {
 	}
 
 	ds.entryCount++
+	ds.mu.Unlock()
 
 	if len(ds.batch) >= ds.batchSize {
 		if err := ds.flushBatch(); err != nil {
@@ -304,6 +360,7 @@ func (ds *DuckStore) flushBatch() error {
 	fmt.Printf("[DuckStore] Batch %d complete in %v\n", batchNum, elapsed)
 
 	ds.batch = ds.batch[:0]
+	ds.commit()
 	return nil
 }
 
@@ -316,15 +373,11 @@ func (ds *DuckStore) Finalize() error {
 	fmt.Printf("[DuckStore] Finalizing: Creating indexes for %d entries...\n", ds.entryCount)
 	start := time.Now()
 
-	// Apply memory limit before heavy index creation (1.5GB limit)
-	// This helps prevent OOM kills in Docker environments
-	_, err := ds.db.Exec("PRAGMA memory_limit='1536MB'")
-	if err != nil {
-		fmt.Printf("[DuckStore] Warning: failed to set memory limit: %v\n", err)
-	}
+	// Index creation stays within the store's share of the memory budget, which
+	// helps prevent OOM kills in Docker environments
 
 	// Create index on timestamp for efficient chunk queries
-	_, err = ds.db.Exec("CREATE INDEX idx_ts ON entries(timestamp)")
+	_, err := ds.db.Exec("CREATE INDEX idx_ts ON entries(timestamp)")
 	if err != nil {
 		return fmt.Errorf("idx_ts creation failed: %w", err)
 	}
@@ -347,11 +400,17 @@ func (ds *DuckStore) Finalize() error {
 	}
 
 	fmt.Printf("[DuckStore] Finalization complete in %v\n", time.Since(start))
+
+	if ds.searchIndexOnFinalize {
+		ds.getSearchIndex()
+	}
 	return nil
 }
 
-// Len returns the total number of entries
+// Len returns the total number of entries added, including those not yet committed
 func (ds *DuckStore) Len() int {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	return ds.entryCount
 }
 
@@ -375,7 +434,11 @@ type QueryParams struct {
 	SignalType          string
 	SearchRegex         bool
 	SearchCaseSensitive bool
+	SearchMode          string // SearchSubstring, SearchTerm or SearchPrefix; ignored for regex searches
 	ShowChanged         bool
+	Filter              *Filter // Compiled filter expression (see CompileFilter), ANDed with the other filters
+	StartTs             int64   // Only entries at or after this time (Unix ms), 0 = unbounded
+	EndTs               int64   // Only entries at or before this time (Unix ms), 0 = unbounded
 }
 
 // QueryEntries returns filtered, sorted, and paginated entries
@@ -390,40 +453,9 @@ func (ds *DuckStore) QueryEntries(ctx context.Context, params QueryParams, page,
 
 	where, args := ds.buildWhereClause(params)
 
-	// Create cache key from where clause (filters determine count)
-	cacheKey := where
-	if cacheKey == "" {
-		cacheKey = "__total__"
-	}
-
-	// Check cache for total count
-	ds.countCacheMu.RLock()
-	total, found := ds.countCache[cacheKey]
-	ds.countCacheMu.RUnlock()
-
-	// If not cached, run count query
-	if !found {
-		countQuery := "SELECT COUNT(*) FROM entries"
-		if where != "" {
-			countQuery += " WHERE " + where
-		}
-
-		// Check context before query
-		select {
-		case <-ctx.Done():
-			return nil, 0, ctx.Err()
-		default:
-		}
-
-		err := ds.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
-		if err != nil {
-			return nil, 0, fmt.Errorf("count query failed: %w", err)
-		}
-
-		// Cache the count
-		ds.countCacheMu.Lock()
-		ds.countCache[cacheKey] = total
-		ds.countCacheMu.Unlock()
+	total, err := ds.countMatching(ctx, where, args)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	if total == 0 {
@@ -444,6 +476,45 @@ func (ds *DuckStore) QueryEntries(ctx context.Context, params QueryParams, page,
 	return entries, total, nil
 }
 
+// countMatching returns the number of entries matching a where clause, cached per filter.
+func (ds *DuckStore) countMatching(ctx context.Context, where string, args []interface{}) (int, error) {
+	// Create cache key from where clause and its arguments (filters determine count)
+	cacheKey := fmt.Sprintf("%s|%v", where, args)
+	if where == "" {
+		cacheKey = "__total__"
+	}
+
+	// Check cache for total count
+	ds.countCacheMu.RLock()
+	total, found := ds.countCache[cacheKey]
+	ds.countCacheMu.RUnlock()
+	if found {
+		return total, nil
+	}
+
+	countQuery := "SELECT COUNT(*) FROM entries"
+	if where != "" {
+		countQuery += " WHERE " + where
+	}
+
+	// Check context before query
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	if err := ds.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("count query failed: %w", err)
+	}
+
+	// Cache the count
+	ds.countCacheMu.Lock()
+	ds.countCache[cacheKey] = total
+	ds.countCacheMu.Unlock()
+	return total, nil
+}
+
 // queryWithKeysetPagination uses efficient pagination for all page depths.
 //
 // Unfiltered + timestamp/id sort: direct primary key range scan — O(log n).
@@ -628,7 +699,7 @@ func scanEntries(rows *sql.Rows, capacity int) ([]models.LogEntry, error) {
 	return entries, rows.Err()
 }
 
-// ClearCountCache clears the count and page index caches (call when data changes)
+// ClearCountCache clears the count, page index, signal statistics, anomaly and search index caches (call when data changes)
 func (ds *DuckStore) ClearCountCache() {
 	ds.countCacheMu.Lock()
 	ds.countCache = make(map[string]int)
@@ -637,6 +708,19 @@ func (ds *DuckStore) ClearCountCache() {
 	ds.pageIndexMu.Lock()
 	ds.pageIndex = make(map[string][]int32)
 	ds.pageIndexMu.Unlock()
+
+	ds.statsCacheMu.Lock()
+	ds.statsCache = make(map[string]SignalStats)
+	ds.statsComplete = false
+	ds.statsCacheMu.Unlock()
+
+	ds.anomalyMu.Lock()
+	ds.anomalies = nil
+	ds.anomalyMu.Unlock()
+
+	ds.searchIndexMu.Lock()
+	ds.searchIdx = nil
+	ds.searchIndexMu.Unlock()
 }
 
 // GetCategories returns all unique categories in the store
@@ -663,7 +747,16 @@ func (ds *DuckStore) buildWhereClause(params QueryParams) (string, []interface{}
 	var args []interface{}
 
 	if params.Search != "" {
-		if params.SearchRegex {
+		var idx *searchIndex
+		if !params.SearchRegex && (params.SearchMode == SearchTerm || params.SearchMode == SearchPrefix) {
+			idx = ds.getSearchIndex()
+		}
+		if idx != nil {
+			// Word search through the search index (case-insensitive)
+			clause, searchArgs := idx.whereClause(params.Search, params.SearchMode == SearchPrefix)
+			clauses = append(clauses, "("+clause+")")
+			args = append(args, searchArgs...)
+		} else if params.SearchRegex {
 			// Regex search using DuckDB regexp_matches
 			clauses = append(clauses, "(regexp_matches(device_id, ?) OR regexp_matches(signal, ?) OR regexp_matches(COALESCE(val_str, ''), ?) OR regexp_matches(CAST(val_int AS VARCHAR), ?) OR regexp_matches(CAST(val_float AS VARCHAR), ?) OR regexp_matches(CAST(val_bool AS VARCHAR), ?))")
 			args = append(args, params.Search, params.Search, params.Search, params.Search, params.Search, params.Search)
@@ -730,6 +823,20 @@ func (ds *DuckStore) buildWhereClause(params QueryParams) (string, []interface{}
 		}
 	}
 
+	if params.Filter != nil {
+		clauses = append(clauses, "("+params.Filter.SQL+")")
+		args = append(args, params.Filter.Args...)
+	}
+
+	if params.StartTs > 0 {
+		clauses = append(clauses, "timestamp >= ?")
+		args = append(args, params.StartTs)
+	}
+	if params.EndTs > 0 {
+		clauses = append(clauses, "timestamp <= ?")
+		args = append(args, params.EndTs)
+	}
+
 	if len(clauses) == 0 {
 		return "", nil
 	}
@@ -793,6 +900,15 @@ func (ds *DuckStore) GetChunk(ctx context.Context, startTs, endTs time.Time, sig
 	startMs := startTs.UnixMilli()
 	endMs := endTs.UnixMilli()
 
+	recorded, derived := ds.splitDerived(signals)
+	derivedEntries, err := ds.derivedChunk(ctx, derived, startMs, endMs)
+	if err != nil {
+		return nil, err
+	}
+	if len(signals) > 0 && len(recorded) == 0 {
+		return derivedEntries, nil
+	}
+
 	query := `
 		SELECT timestamp, device_id, signal, category, val_type, val_bool, val_int, val_float, val_str
 		FROM entries WHERE timestamp >= ? AND timestamp <= ?
@@ -800,9 +916,9 @@ func (ds *DuckStore) GetChunk(ctx context.Context, startTs, endTs time.Time, sig
 	var args []interface{}
 	args = append(args, startMs, endMs)
 
-	if len(signals) > 0 {
+	if len(recorded) > 0 {
 		var signalClauses []string
-		for _, s := range signals {
+		for _, s := range recorded {
 			parts := strings.Split(s, "::")
 			if len(parts) == 2 {
 				signalClauses = append(signalClauses, "(device_id = ? AND signal = ?)")
@@ -837,8 +953,15 @@ func (ds *DuckStore) GetChunk(ctx context.Context, startTs, endTs time.Time, sig
 	if count == 500000 {
 		fmt.Printf("[DuckStore] Warning: GetChunk query truncated at 500,000 entries for range [%d, %d]\n", startMs, endMs)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return entries, rows.Err()
+	if len(derivedEntries) > 0 {
+		entries = append(entries, derivedEntries...)
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+	}
+	return entries, nil
 }
 
 // GetValuesAtTime returns the most recent value for all signals at or before the given timestamp.
@@ -853,6 +976,20 @@ func (ds *DuckStore) GetValuesAtTime(ctx context.Context, ts time.Time, signals
 
 	tsMs := ts.UnixMilli()
 
+	recorded, derived := ds.splitDerived(signals)
+	var derivedEntries []models.LogEntry
+	for _, d := range derived {
+		latest, err := ds.derivedLatest(ctx, d, tsMs)
+		if err != nil {
+			return nil, err
+		}
+		derivedEntries = append(derivedEntries, latest...)
+	}
+	if len(signals) > 0 && len(recorded) == 0 {
+		return derivedEntries, nil
+	}
+	signals = recorded
+
 	// Use window function to get the latest entry for each signal
 	query := `
 		WITH latest_entries AS (
@@ -912,8 +1049,11 @@ func (ds *DuckStore) GetValuesAtTime(ctx context.Context, ts time.Time, signals
 		}
 		entries = append(entries, entry)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return entries, rows.Err()
+	return append(entries, derivedEntries...), nil
 }
 
 // BoundaryValues holds the last value before and first value after a time window for each signal
@@ -945,6 +1085,24 @@ func (ds *DuckStore) GetBoundaryValues(ctx context.Context, startTs, endTs time.
 		return result, nil
 	}
 
+	signals, derived := ds.splitDerived(signals)
+	for _, d := range derived {
+		before, err := ds.derivedLatest(ctx, d, startMs-1)
+		if err != nil {
+			return nil, err
+		}
+		after, err := ds.derivedNext(ctx, d, endMs)
+		if err != nil {
+			return nil, err
+		}
+		if len(before) > 0 {
+			result.Before[d.def.Key()] = before[0]
+		}
+		if len(after) > 0 {
+			result.After[d.def.Key()] = after[0]
+		}
+	}
+
 	// Build signal filter clause
 	var signalClauses []string
 	var args []interface{}
@@ -1044,6 +1202,9 @@ func (ds *DuckStore) GetSignalTypes() (map[string]models.SignalType, error) {
 		}
 		result[key] = valTypeToSignalType(valType)
 	}
+	for _, d := range ds.DerivedSignals() {
+		result[d.Key()] = d.SignalType
+	}
 	return result, rows.Err()
 }
 
@@ -1167,17 +1328,47 @@ func (ds *DuckStore) GetTimeTree(ctx context.Context, params QueryParams) ([]Tim
 	return result, rows.Err()
 }
 
+// GetSignals returns a copy of the signal keys, including derived signals
 func (ds *DuckStore) GetSignals() map[string]struct{} {
-	return ds.signals
+	ds.derivedMu.RLock()
+	defer ds.derivedMu.RUnlock()
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	signals := make(map[string]struct{}, len(ds.signals)+len(ds.derived))
+	for key := range ds.signals {
+		signals[key] = struct{}{}
+	}
+	for key := range ds.derived {
+		signals[key] = struct{}{}
+	}
+	return signals
+}
+
+// hasSignal reports whether key is a recorded (not derived) signal.
+func (ds *DuckStore) hasSignal(key string) bool {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	_, ok := ds.signals[key]
+	return ok
 }
 
-// GetDevices returns all unique device IDs
+// GetDevices returns a copy of all unique device IDs
 func (ds *DuckStore) GetDevices() map[string]struct{} {
-	return ds.devices
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	devices := make(map[string]struct{}, len(ds.devices))
+	for id := range ds.devices {
+		devices[id] = struct{}{}
+	}
+	return devices
 }
 
 // GetTimeRange returns the time range of stored entries
 func (ds *DuckStore) GetTimeRange() *models.TimeRange {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	if ds.entryCount == 0 {
 		return nil
 	}
@@ -1192,16 +1383,58 @@ func (ds *DuckStore) SetPersistent(p bool) {
 	ds.persistent = p
 }
 
-// Close closes the database connection.
-// If the store is not persistent, it also removes the database file.
+// Acquire registers a reader that uses the store until it calls Release. It returns
+// false once the store is closing.
+func (ds *DuckStore) Acquire() bool {
+	ds.refMu.Lock()
+	defer ds.refMu.Unlock()
+	if ds.closing {
+		return false
+	}
+	ds.refs++
+	return true
+}
+
+// Release ends a read registered with Acquire, closing the store if Close was called
+// while it ran.
+func (ds *DuckStore) Release() {
+	ds.refMu.Lock()
+	ds.refs--
+	closeNow := ds.closing && ds.refs == 0
+	ds.refMu.Unlock()
+	if closeNow {
+		ds.close()
+	}
+}
+
+// InUse reports whether readers registered with Acquire are still running.
+func (ds *DuckStore) InUse() bool {
+	ds.refMu.Lock()
+	defer ds.refMu.Unlock()
+	return ds.refs > 0
+}
+
+// Close closes the database connection once the last reader registered with Acquire has
+// released it. If the store is not persistent, it also removes the database file.
 func (ds *DuckStore) Close() error {
+	ds.refMu.Lock()
+	closeNow := !ds.closing && ds.refs == 0
+	ds.closing = true
+	ds.refMu.Unlock()
+	if closeNow {
+		ds.close()
+	}
+	return nil
+}
+
+func (ds *DuckStore) close() {
+	budget.release(ds)
 	if ds.db != nil {
 		ds.db.Close()
 	}
 	if ds.dbPath != "" && !ds.persistent {
 		os.Remove(ds.dbPath)
 	}
-	return nil
 }
 
 // Value type constants
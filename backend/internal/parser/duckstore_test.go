@@ -842,6 +842,41 @@ func TestDuckStore_Persistent(t *testing.T) {
 	})
 }
 
+func TestDuckStore_CloseWaitsForReaders(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	store.AddEntry(createTestEntry("PLC-01", "Signal1", time.Now(), true, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Failed to finalize: %v", err)
+	}
+	dbPath := store.dbPath
+
+	if !store.Acquire() {
+		t.Fatal("Expected to acquire an open store")
+	}
+	store.Close()
+	if store.Acquire() {
+		t.Error("Expected a closing store to refuse new readers")
+	}
+
+	// The running reader can still query until it releases the store
+	if _, err := store.GetEntries(context.Background(), 0, 1); err != nil {
+		t.Errorf("Expected the reader to keep using the store, got %v", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("Expected the database file to stay until released, got %v", err)
+	}
+
+	store.Release()
+	if store.InUse() {
+		t.Error("Expected no readers after release")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Error("Expected the last release to close and remove the store")
+	}
+}
+
 func TestDuckStore_OpenReadOnly(t *testing.T) {
 	t.Run("opens existing database read-only", func(t *testing.T) {
 		tempDir := t.TempDir()
@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// EdgeType selects which value changes of a signal count as edges.
+type EdgeType string
+
+const (
+	EdgeRising  EdgeType = "rising"  // boolean false -> true
+	EdgeFalling EdgeType = "falling" // boolean true -> false
+	EdgeBoth    EdgeType = "both"    // any boolean edge
+	EdgeChange  EdgeType = "change"  // any value change, for signals of every type
+)
+
+// EdgeQuery selects edges of one signal. Start and End (Unix ms) bound the edge
+// timestamps; zero means unbounded. The optional condition applies to the value after the edge.
+type EdgeQuery struct {
+	Signal    string        `json:"signal"`
+	Type      EdgeType      `json:"type"`
+	Condition ConditionType `json:"condition,omitempty"`
+	Value     interface{}   `json:"value,omitempty"`
+	Start     int64         `json:"start,omitempty"`
+	End       int64         `json:"end,omitempty"`
+	Page      int           `json:"page"`
+	PageSize  int           `json:"pageSize"`
+}
+
+// Edge is a change of a signal value.
+// PulseWidth is the time (ms) until the next change of the signal; nil for the last change.
+type Edge struct {
+	Timestamp  int64       `json:"timestamp"`
+	Value      interface{} `json:"value"`
+	PrevValue  interface{} `json:"prevValue"`
+	PulseWidth *int64      `json:"pulseWidth,omitempty"`
+}
+
+// EdgeList is a page of edges.
+type EdgeList struct {
+	Edges    []Edge `json:"edges"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+// GetEdges returns a page of edges of a signal in time order.
+func (ds *DuckStore) GetEdges(ctx context.Context, q EdgeQuery) (*EdgeList, error) {
+	edgesCTE, args, err := edgesSQL(q)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	list := &EdgeList{Edges: []Edge{}, Page: q.Page, PageSize: q.PageSize}
+	if err := ds.db.QueryRowContext(ctx, edgesCTE+"SELECT COUNT(*) FROM edges", args...).Scan(&list.Total); err != nil {
+		return nil, fmt.Errorf("edge count query failed: %w", err)
+	}
+
+	rows, err := ds.db.QueryContext(ctx, edgesCTE+edgeSelect+" ORDER BY timestamp, id LIMIT ? OFFSET ?",
+		append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("edge query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		edge, err := scanEdge(rows)
+		if err != nil {
+			return nil, err
+		}
+		list.Edges = append(list.Edges, edge)
+	}
+	return list, rows.Err()
+}
+
+// FindEdge returns the first edge strictly after (forward) or before (backward) the given
+// timestamp, or nil if there is none. Start and End of the query are ignored.
+func (ds *DuckStore) FindEdge(ctx context.Context, q EdgeQuery, from int64, forward bool) (*Edge, error) {
+	q.Start, q.End = 0, 0
+	edgesCTE, args, err := edgesSQL(q)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	query := edgesCTE + edgeSelect + " WHERE timestamp > ? ORDER BY timestamp, id LIMIT 1"
+	if !forward {
+		query = edgesCTE + edgeSelect + " WHERE timestamp < ? ORDER BY timestamp DESC, id DESC LIMIT 1"
+	}
+	rows, err := ds.db.QueryContext(ctx, query, append(args, from)...)
+	if err != nil {
+		return nil, fmt.Errorf("edge query failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	edge, err := scanEdge(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &edge, nil
+}
+
+const edgeSelect = `
+	SELECT timestamp, val_type, val_bool, val_int, val_float, val_str,
+		prev_type, prev_bool, prev_int, prev_float, prev_str, pulse
+	FROM edges`
+
+// edgesSQL returns a WITH clause defining an "edges" relation for the query.
+// Pulse widths are measured between consecutive changes before any filtering,
+// so a rising edge carries the width of the high pulse that follows it.
+func edgesSQL(q EdgeQuery) (string, []interface{}, error) {
+	if len(strings.Split(q.Signal, "::")) != 2 {
+		return "", nil, fmt.Errorf("%w: signal %q must be deviceId::signalName", ErrInvalidQuery, q.Signal)
+	}
+
+	var typeFilter string
+	switch q.Type {
+	case EdgeRising:
+		typeFilter = "val_type = 0 AND prev_type = 0 AND val_bool AND NOT prev_bool"
+	case EdgeFalling:
+		typeFilter = "val_type = 0 AND prev_type = 0 AND NOT val_bool AND prev_bool"
+	case EdgeBoth:
+		typeFilter = "val_type = 0 AND prev_type = 0"
+	case EdgeChange, "":
+		typeFilter = "TRUE"
+	default:
+		return "", nil, fmt.Errorf("%w: unknown edge type %q", ErrInvalidQuery, q.Type)
+	}
+
+	condClause, condArgs, err := conditionSQL(q.Condition, q.Value)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+
+	signalClause, args := signalMatchSQL(q.Signal)
+	rangeClause := ""
+	if q.Start != 0 {
+		rangeClause += " AND timestamp >= ?"
+	}
+	if q.End != 0 {
+		rangeClause += " AND timestamp <= ?"
+	}
+
+	cte := fmt.Sprintf(`
+		WITH seq AS (
+			SELECT id, timestamp, val_type, val_bool, val_int, val_float, val_str, v,
+				LAG(v) OVER w AS prev,
+				LAG(val_type) OVER w AS prev_type, LAG(val_bool) OVER w AS prev_bool,
+				LAG(val_int) OVER w AS prev_int, LAG(val_float) OVER w AS prev_float, LAG(val_str) OVER w AS prev_str
+			FROM (SELECT *, %s AS v FROM entries WHERE %s)
+			WINDOW w AS (ORDER BY timestamp, id)
+		), changes AS (
+			SELECT *, LEAD(timestamp) OVER (ORDER BY timestamp, id) - timestamp AS pulse
+			FROM seq WHERE prev IS NOT NULL AND v <> prev
+		), edges AS (
+			SELECT * FROM changes WHERE %s AND %s%s
+		)
+	`, valueTextExpr, signalClause, typeFilter, condClause, rangeClause)
+
+	args = append(args, condArgs...)
+	if q.Start != 0 {
+		args = append(args, q.Start)
+	}
+	if q.End != 0 {
+		args = append(args, q.End)
+	}
+	return cte, args, nil
+}
+
+func scanEdge(rows *sql.Rows) (Edge, error) {
+	var edge Edge
+	var valType, prevType int
+	var valBool, prevBool sql.NullBool
+	var valInt, prevInt sql.NullInt64
+	var valFloat, prevFloat sql.NullFloat64
+	var valStr, prevStr sql.NullString
+	var pulse sql.NullInt64
+	if err := rows.Scan(&edge.Timestamp, &valType, &valBool, &valInt, &valFloat, &valStr,
+		&prevType, &prevBool, &prevInt, &prevFloat, &prevStr, &pulse); err != nil {
+		return edge, err
+	}
+	edge.Value = decodeValue(valType, valBool.Bool, valInt.Int64, valFloat.Float64, valStr.String)
+	edge.PrevValue = decodeValue(prevType, prevBool.Bool, prevInt.Int64, prevFloat.Float64, prevStr.String)
+	if pulse.Valid {
+		edge.PulseWidth = &pulse.Int64
+	}
+	return edge, nil
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_Edges(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	ms := func(off int) int64 { return base.UnixMilli() + int64(off) }
+
+	// Sensor: high pulses of 100ms and 300ms
+	for _, e := range []struct {
+		ms int
+		v  bool
+	}{{0, false}, {100, true}, {150, true}, {200, false}, {500, true}, {800, false}} {
+		store.AddEntry(createTestEntry("DEV", "Sensor", at(e.ms), e.v, ""))
+	}
+	for i, v := range []int{1, 1, 5, 2, 7} {
+		store.AddEntry(createTestEntry("DEV", "Step", at(i*100), v, ""))
+	}
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("rising edges with pulse width", func(t *testing.T) {
+		list, err := store.GetEdges(ctx, EdgeQuery{Signal: "DEV::Sensor", Type: EdgeRising, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		if list.Total != 2 || len(list.Edges) != 2 {
+			t.Fatalf("Expected 2 rising edges, got %+v", list)
+		}
+		if list.Edges[0].Timestamp != ms(100) || *list.Edges[0].PulseWidth != 100 {
+			t.Errorf("Unexpected first edge: %+v", list.Edges[0])
+		}
+		if list.Edges[1].Timestamp != ms(500) || *list.Edges[1].PulseWidth != 300 {
+			t.Errorf("Unexpected second edge: %+v", list.Edges[1])
+		}
+	})
+
+	t.Run("falling edges in range, paged", func(t *testing.T) {
+		list, err := store.GetEdges(ctx, EdgeQuery{
+			Signal: "DEV::Sensor", Type: EdgeFalling, Start: ms(150), End: ms(900), Page: 2, PageSize: 1,
+		})
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		if list.Total != 2 || len(list.Edges) != 1 || list.Edges[0].Timestamp != ms(800) {
+			t.Fatalf("Expected second falling edge at 800, got %+v", list)
+		}
+		if list.Edges[0].PulseWidth != nil {
+			t.Errorf("Expected no pulse width for the last change")
+		}
+	})
+
+	t.Run("value changes with condition", func(t *testing.T) {
+		list, err := store.GetEdges(ctx, EdgeQuery{
+			Signal: "DEV::Step", Type: EdgeChange, Condition: ConditionGreater, Value: "4", Page: 1, PageSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("GetEdges failed: %v", err)
+		}
+		if list.Total != 2 || list.Edges[0].Value != 5 || list.Edges[0].PrevValue != 1 {
+			t.Errorf("Expected changes to 5 and 7, got %+v", list.Edges)
+		}
+	})
+
+	t.Run("next and previous", func(t *testing.T) {
+		next, err := store.FindEdge(ctx, EdgeQuery{Signal: "DEV::Sensor", Type: EdgeRising}, ms(100), true)
+		if err != nil {
+			t.Fatalf("FindEdge failed: %v", err)
+		}
+		if next == nil || next.Timestamp != ms(500) {
+			t.Errorf("Expected next rising edge at 500, got %+v", next)
+		}
+		prev, _ := store.FindEdge(ctx, EdgeQuery{Signal: "DEV::Sensor", Type: EdgeBoth}, ms(500), false)
+		if prev == nil || prev.Timestamp != ms(200) {
+			t.Errorf("Expected previous edge at 200, got %+v", prev)
+		}
+		none, _ := store.FindEdge(ctx, EdgeQuery{Signal: "DEV::Sensor", Type: EdgeRising}, ms(500), true)
+		if none != nil {
+			t.Errorf("Expected no edge after the last one, got %+v", none)
+		}
+	})
+
+	t.Run("rejects unknown type", func(t *testing.T) {
+		if _, err := store.GetEdges(ctx, EdgeQuery{Signal: "DEV::Sensor", Type: "sideways"}); err == nil {
+			t.Error("Expected error for unknown edge type")
+		}
+	})
+}
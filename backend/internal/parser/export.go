@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// ExportFormat is the file format of an entries export.
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+	ExportXLSX   ExportFormat = "xlsx"
+)
+
+// ContentType returns the MIME type of the format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// ExportColumns are the exportable columns in default order, named like the LogEntry JSON fields.
+var ExportColumns = []string{"timestamp", "deviceId", "signalName", "category", "value", "signalType"}
+
+// exportProgressInterval is how many rows are written between progress reports.
+const exportProgressInterval = 10000
+
+// maxXLSXRows is the row limit of an Excel worksheet, minus the header row.
+const maxXLSXRows = 1048575
+
+// ExportQuery selects the entries and layout of an export. Entries match Params and are
+// written in its sort order. Columns picks and orders the columns (all if empty) and
+// NoHeader drops the header row of CSV and XLSX.
+// OnStart is called with the number of matching entries before anything is written, and
+// Progress every 10,000 rows and once at the end with the rows written so far.
+type ExportQuery struct {
+	Params   QueryParams
+	Format   ExportFormat
+	Columns  []string
+	NoHeader bool
+	OnStart  func(total int)
+	Progress func(rows int)
+}
+
+// ExportEntries streams the matching entries to w row by row as they come from DuckDB,
+// so exports of any size run in constant memory. It returns the number of rows written.
+// Validation errors are returned before anything is written. Only counting the entries
+// takes a query slot; the download itself does not hold one, as it runs at the pace of
+// the client.
+func (ds *DuckStore) ExportEntries(ctx context.Context, w io.Writer, q ExportQuery) (int, error) {
+	columns := q.Columns
+	if len(columns) == 0 {
+		columns = ExportColumns
+	}
+	for _, col := range columns {
+		if !isExportColumn(col) {
+			return 0, fmt.Errorf("%w: unknown export column %q (one of %s)", ErrInvalidQuery, col, strings.Join(ExportColumns, ", "))
+		}
+	}
+
+	var out exportWriter
+	switch q.Format {
+	case ExportCSV, "":
+		out = &csvExportWriter{w: csv.NewWriter(w), columns: columns}
+	case ExportNDJSON:
+		out = &ndjsonExportWriter{w: w, columns: columns}
+	case ExportXLSX:
+		out = &xlsxExportWriter{w: w, columns: columns}
+	default:
+		return 0, fmt.Errorf("%w: unknown export format %q (csv, ndjson or xlsx)", ErrInvalidQuery, q.Format)
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	where, args := ds.buildWhereClause(q.Params)
+	total, err := ds.countMatching(ctx, where, args)
+	<-ds.querySem
+	if err != nil {
+		return 0, err
+	}
+	if q.Format == ExportXLSX && total > maxXLSXRows {
+		return 0, fmt.Errorf("%w: %d entries exceed the %d rows of an xlsx sheet, use csv or ndjson", ErrInvalidQuery, total, maxXLSXRows)
+	}
+
+	col, dir := entrySort(q.Params, "id")
+	query := "SELECT timestamp, device_id, signal, category, val_type, val_bool, val_int, val_float, val_str FROM entries"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", col, dir, dir)
+
+	rows, err := ds.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("export query failed: %w", err)
+	}
+	defer rows.Close()
+
+	if q.OnStart != nil {
+		q.OnStart(total)
+	}
+	if err := out.begin(!q.NoHeader); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for rows.Next() {
+		entry, err := scanEntryRows(rows)
+		if err != nil {
+			return n, err
+		}
+		if err := out.row(&entry); err != nil {
+			return n, err
+		}
+		n++
+		if n%exportProgressInterval == 0 && q.Progress != nil {
+			q.Progress(n)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("export scan failed: %w", err)
+	}
+	if err := out.end(); err != nil {
+		return n, err
+	}
+	if q.Progress != nil {
+		q.Progress(n)
+	}
+	return n, nil
+}
+
+func isExportColumn(col string) bool {
+	for _, c := range ExportColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// exportWriter writes one export format.
+type exportWriter interface {
+	begin(header bool) error
+	row(e *models.LogEntry) error
+	end() error
+}
+
+// exportTimestamp formats entry times in exports: UTC ISO 8601 with milliseconds.
+func exportTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// exportText returns a column of an entry as text.
+func exportText(e *models.LogEntry, col string) string {
+	switch col {
+	case "timestamp":
+		return exportTimestamp(e.Timestamp)
+	case "deviceId":
+		return e.DeviceID
+	case "signalName":
+		return e.SignalName
+	case "category":
+		return e.Category
+	case "signalType":
+		return string(e.SignalType)
+	}
+	switch v := e.Value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []string
+	record  []string
+}
+
+func (cw *csvExportWriter) begin(header bool) error {
+	cw.record = make([]string, len(cw.columns))
+	if header {
+		return cw.w.Write(cw.columns)
+	}
+	return nil
+}
+
+func (cw *csvExportWriter) row(e *models.LogEntry) error {
+	_, textValue := e.Value.(string)
+	for i, col := range cw.columns {
+		cw.record[i] = exportText(e, col)
+		if col != "value" || textValue {
+			cw.record[i] = csvCell(cw.record[i])
+		}
+	}
+	return cw.w.Write(cw.record)
+}
+
+// csvCell prefixes text starting with =, +, - or @ with a quote so spreadsheets show it
+// instead of running it as a formula. Numeric values are left alone to stay numbers.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (cw *csvExportWriter) end() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonExportWriter writes one JSON object per line with the columns in order.
+// Values keep their JSON type; timestamps are ISO 8601 strings as in the other formats.
+type ndjsonExportWriter struct {
+	w       io.Writer
+	columns []string
+	buf     []byte
+}
+
+func (nw *ndjsonExportWriter) begin(bool) error { return nil }
+
+func (nw *ndjsonExportWriter) row(e *models.LogEntry) error {
+	nw.buf = append(nw.buf[:0], '{')
+	for i, col := range nw.columns {
+		if i > 0 {
+			nw.buf = append(nw.buf, ',')
+		}
+		nw.buf = strconv.AppendQuote(nw.buf, col)
+		nw.buf = append(nw.buf, ':')
+		var v interface{} = exportText(e, col)
+		if col == "value" {
+			v = e.Value
+			if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				v = nil // Not representable in JSON
+			}
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		nw.buf = append(nw.buf, data...)
+	}
+	nw.buf = append(nw.buf, '}', '\n')
+	_, err := nw.w.Write(nw.buf)
+	return err
+}
+
+func (nw *ndjsonExportWriter) end() error { return nil }
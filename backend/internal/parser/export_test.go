@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_ExportEntries(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	store.AddEntry(createTestEntry("PLC", "Motor", base, true, "OUTPUT"))
+	store.AddEntry(createTestEntry("PLC", "Speed", base.Add(time.Second), 2.5, "OUTPUT"))
+	store.AddEntry(createTestEntry("PLC", "State", base.Add(2*time.Second), `Jam, "belt" <3>`, "INPUT"))
+	store.AddEntry(createTestEntry("AUX", "Count", base.Add(3*time.Second), 7, "INPUT"))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("csv with header", func(t *testing.T) {
+		var buf bytes.Buffer
+		var started, progressed int
+		n, err := store.ExportEntries(ctx, &buf, ExportQuery{
+			Params:   QueryParams{Categories: []string{"INPUT"}},
+			Format:   ExportCSV,
+			OnStart:  func(total int) { started = total },
+			Progress: func(rows int) { progressed = rows },
+		})
+		if err != nil {
+			t.Fatalf("ExportEntries failed: %v", err)
+		}
+		want := "timestamp,deviceId,signalName,category,value,signalType\n" +
+			"2024-03-01T10:00:02.000Z,PLC,State,INPUT,\"Jam, \"\"belt\"\" <3>\",string\n" +
+			"2024-03-01T10:00:03.000Z,AUX,Count,INPUT,7,integer\n"
+		if buf.String() != want {
+			t.Errorf("Unexpected CSV:\n%s", buf.String())
+		}
+		if n != 2 || started != 2 || progressed != 2 {
+			t.Errorf("Expected 2 rows, start and progress reports, got %d, %d, %d", n, started, progressed)
+		}
+	})
+
+	t.Run("ndjson with selected columns", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := store.ExportEntries(ctx, &buf, ExportQuery{
+			Params:  QueryParams{StartTs: base.Add(time.Second).UnixMilli(), EndTs: base.Add(time.Second).UnixMilli()},
+			Format:  ExportNDJSON,
+			Columns: []string{"signalName", "value"},
+		})
+		if err != nil {
+			t.Fatalf("ExportEntries failed: %v", err)
+		}
+		if buf.String() != "{\"signalName\":\"Speed\",\"value\":2.5}\n" {
+			t.Errorf("Unexpected NDJSON: %q", buf.String())
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := store.ExportEntries(ctx, &buf, ExportQuery{Format: ExportXLSX, NoHeader: true}); err != nil {
+			t.Fatalf("ExportEntries failed: %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Export is not a zip archive: %v", err)
+		}
+		var sheet string
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, _ := f.Open()
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				sheet = string(data)
+			}
+		}
+		if strings.Count(sheet, "<row>") != 4 || !strings.Contains(sheet, `<c t="b"><v>1</v></c>`) ||
+			!strings.Contains(sheet, "<c><v>2.5</v></c>") || !strings.Contains(sheet, "Jam, &#34;belt&#34; &lt;3&gt;") {
+			t.Errorf("Unexpected sheet: %s", sheet)
+		}
+	})
+
+	for _, q := range []ExportQuery{{Format: "pdf"}, {Columns: []string{"value", "nope"}}} {
+		var buf bytes.Buffer
+		if _, err := store.ExportEntries(ctx, &buf, q); !errors.Is(err, ErrInvalidQuery) || buf.Len() != 0 {
+			t.Errorf("%+v: expected ErrInvalidQuery and no output, got %v", q, err)
+		}
+	}
+}
+
+func TestDuckStore_ExportCSVFormulas(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	store.AddEntry(createTestEntry("@PLC", "=Cmd", base, "=HYPERLINK(\"x\")", "+IN"))
+	store.AddEntry(createTestEntry("@PLC", "Temp", base.Add(time.Second), -1.5, "-OUT"))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := store.ExportEntries(context.Background(), &buf, ExportQuery{
+		Format:  ExportCSV,
+		Columns: []string{"deviceId", "signalName", "category", "value"},
+	}); err != nil {
+		t.Fatalf("ExportEntries failed: %v", err)
+	}
+	want := "deviceId,signalName,category,value\n" +
+		"'@PLC,'=Cmd,'+IN,\"'=HYPERLINK(\"\"x\"\")\"\n" +
+		"'@PLC,Temp,'-OUT,-1.5\n"
+	if buf.String() != want {
+		t.Errorf("Expected text cells to be neutralized and numbers kept, got:\n%s", buf.String())
+	}
+}
+
+func TestDuckStore_ExportNonFinite(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	store.AddEntry(createTestEntry("PLC", "Ratio", base, math.NaN(), ""))
+	store.AddEntry(createTestEntry("PLC", "Ratio", base.Add(time.Second), math.Inf(1), ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if _, err := store.ExportEntries(ctx, &buf, ExportQuery{Format: ExportNDJSON, Columns: []string{"value"}}); err != nil {
+		t.Fatalf("NDJSON export failed: %v", err)
+	}
+	if buf.String() != "{\"value\":null}\n{\"value\":null}\n" {
+		t.Errorf("Expected null values, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if _, err := store.ExportEntries(ctx, &buf, ExportQuery{Format: ExportXLSX, Columns: []string{"value"}, NoHeader: true}); err != nil {
+		t.Fatalf("XLSX export failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Export is not a zip archive: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(data), ">NaN</t>") || !strings.Contains(string(data), ">+Inf</t>") {
+			t.Errorf("Expected NaN and +Inf as strings, got %s", data)
+		}
+	}
+}
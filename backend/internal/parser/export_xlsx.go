@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// xlsxParts are the fixed parts of a single-sheet workbook. The sheet itself is streamed
+// with inline strings, so no shared string table has to be held in memory.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Entries" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxExportWriter writes a minimal Office Open XML workbook. Numbers and booleans are
+// typed cells; everything else, timestamps included, is an inline string.
+type xlsxExportWriter struct {
+	w       io.Writer
+	columns []string
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+}
+
+func (xw *xlsxExportWriter) begin(header bool) error {
+	xw.zw = zip.NewWriter(xw.w)
+	for _, part := range xlsxParts {
+		f, err := xw.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	xw.sheet = bufio.NewWriterSize(f, 64*1024)
+	xw.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if header {
+		xw.sheet.WriteString("<row>")
+		for _, col := range xw.columns {
+			xw.stringCell(col)
+		}
+		xw.sheet.WriteString("</row>")
+	}
+	return nil
+}
+
+func (xw *xlsxExportWriter) row(e *models.LogEntry) error {
+	xw.sheet.WriteString("<row>")
+	for _, col := range xw.columns {
+		if col != "value" {
+			xw.stringCell(exportText(e, col))
+			continue
+		}
+		switch v := e.Value.(type) {
+		case bool:
+			if v {
+				xw.sheet.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				xw.sheet.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case int:
+			xw.sheet.WriteString("<c><v>" + strconv.Itoa(v) + "</v></c>")
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				xw.stringCell(exportText(e, col)) // Not representable as an Excel number
+				break
+			}
+			xw.sheet.WriteString("<c><v>" + strconv.FormatFloat(v, 'g', -1, 64) + "</v></c>")
+		default:
+			xw.stringCell(exportText(e, col))
+		}
+	}
+	_, err := xw.sheet.WriteString("</row>")
+	return err
+}
+
+func (xw *xlsxExportWriter) stringCell(s string) {
+	xw.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(xw.sheet, []byte(s))
+	xw.sheet.WriteString("</t></is></c>")
+}
+
+func (xw *xlsxExportWriter) end() error {
+	xw.sheet.WriteString("</sheetData></worksheet>")
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
@@ -0,0 +1,510 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression language for derived signals.
+//
+//	expr    := or
+//	or      := and ("OR" and)*
+//	and     := not ("AND" not)*
+//	not     := "NOT" not | cmp
+//	cmp     := sum (("=" | "!=" | "<>" | "<" | "<=" | ">" | ">=") sum)?
+//	sum     := product (("+" | "-") product)*
+//	product := unary (("*" | "/") unary)*
+//	unary   := "-" unary | primary
+//	primary := number | 'string' | TRUE | FALSE | ref | func "(" args ")" | "(" expr ")"
+//	ref     := device::signal | `device::signal`
+//
+// Functions: abs(x), min(a, b), max(a, b), delta(ref) (change since the previous sample)
+// and rate(ref) (change per second since the previous sample).
+// Keywords and function names are case-insensitive.
+
+// ExpressionError is a syntax or type error at a position (1-based column) of an expression.
+type ExpressionError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos, e.Msg)
+}
+
+// Unwrap lets callers treat expression errors as invalid queries.
+func (e *ExpressionError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+type exprType int
+
+const (
+	exprBool exprType = iota
+	exprNum
+	exprStr
+)
+
+func (t exprType) String() string {
+	switch t {
+	case exprBool:
+		return "boolean"
+	case exprNum:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokRef
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexExpression splits an expression into tokens.
+// Identifiers followed by "::" form a signal reference; device IDs may contain '-' and '.'.
+func lexExpression(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", start + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", start + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", start + 1})
+			i++
+		case c == '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return nil, &ExpressionError{start + 1, "unterminated string"}
+			}
+			tokens = append(tokens, token{tokString, src[i+1 : i+1+end], start + 1})
+			i += end + 2
+		case c == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, &ExpressionError{start + 1, "unterminated signal reference"}
+			}
+			ref := src[i+1 : i+1+end]
+			if len(strings.Split(ref, "::")) != 2 {
+				return nil, &ExpressionError{start + 1, fmt.Sprintf("signal reference %q must be device::signal", ref)}
+			}
+			tokens = append(tokens, token{tokRef, ref, start + 1})
+			i += end + 2
+		case strings.ContainsRune("=!<>+-*/", c):
+			op := string(c)
+			if i+1 < len(src) && (src[i:i+2] == "!=" || src[i:i+2] == "<>" || src[i:i+2] == "<=" || src[i:i+2] == ">=") {
+				op = src[i : i+2]
+			} else if c == '!' {
+				return nil, &ExpressionError{start + 1, "unexpected '!'"}
+			}
+			tokens = append(tokens, token{tokOp, op, start + 1})
+			i += len(op)
+		case c == '.' || unicode.IsDigit(c) || unicode.IsLetter(c) || c == '_':
+			// Longest run allowed in a device ID, then check for a "::" reference
+			j := i
+			for j < len(src) && isDeviceChar(rune(src[j])) {
+				j++
+			}
+			if strings.HasPrefix(src[j:], "::") {
+				k := j + 2
+				for k < len(src) && isSignalChar(rune(src[k])) {
+					k++
+				}
+				if k == j+2 {
+					return nil, &ExpressionError{start + 1, "missing signal name after '::'"}
+				}
+				tokens = append(tokens, token{tokRef, src[i:k], start + 1})
+				i = k
+				continue
+			}
+			if unicode.IsDigit(c) || c == '.' {
+				j = i
+				for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+					j++
+				}
+				tokens = append(tokens, token{tokNumber, src[i:j], start + 1})
+			} else {
+				j = i
+				for j < len(src) && isSignalChar(rune(src[j])) {
+					j++
+				}
+				tokens = append(tokens, token{tokIdent, src[i:j], start + 1})
+			}
+			i = j
+		default:
+			return nil, &ExpressionError{start + 1, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src) + 1}), nil
+}
+
+func isSignalChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.'
+}
+
+func isDeviceChar(c rune) bool {
+	return isSignalChar(c) || c == '-'
+}
+
+// exprNode is a typed, compiled expression fragment.
+type exprNode struct {
+	sql string
+	typ exprType
+}
+
+// exprColumn is an input column required by a compiled expression.
+type exprColumn struct {
+	ref  string   // device::signal
+	kind string   // "value", "delta" or "rate"
+	typ  exprType // type of the referenced signal
+}
+
+// CompiledExpression is an expression translated to SQL over columns c0..cn,
+// each holding the last-value-carried-forward input described by Columns.
+type CompiledExpression struct {
+	SQL     string
+	Type    exprType
+	Columns []exprColumn
+}
+
+type exprParser struct {
+	tokens  []token
+	pos     int
+	refType func(ref string) (exprType, bool)
+	columns []exprColumn
+}
+
+// CompileExpression parses an expression and translates it to SQL.
+// refType reports the type of a referenced signal and whether it exists.
+func CompileExpression(src string, refType func(ref string) (exprType, bool)) (*CompiledExpression, error) {
+	tokens, err := lexExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, refType: refType}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &ExpressionError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return &CompiledExpression{SQL: node.sql, Type: node.typ, Columns: p.columns}, nil
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseLogical("OR", p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseLogical("AND", p.parseNot)
+}
+
+func (p *exprParser) parseLogical(op string, operand func() (exprNode, error)) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return left, err
+	}
+	for {
+		pos := p.peek().pos
+		if !p.keyword(op) {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return right, err
+		}
+		l, err := asBool(left, pos)
+		if err != nil {
+			return l, err
+		}
+		r, err := asBool(right, pos)
+		if err != nil {
+			return r, err
+		}
+		left = exprNode{fmt.Sprintf("(%s %s %s)", l.sql, op, r.sql), exprBool}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	pos := p.peek().pos
+	if p.keyword("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return operand, err
+		}
+		b, err := asBool(operand, pos)
+		if err != nil {
+			return b, err
+		}
+		return exprNode{"(NOT " + b.sql + ")", exprBool}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return left, err
+	}
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return left, nil
+	}
+	switch tok.text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseSum()
+	if err != nil {
+		return right, err
+	}
+
+	op := tok.text
+	if op == "!=" {
+		op = "<>"
+	}
+	switch {
+	case left.typ == exprStr && right.typ == exprStr:
+	case left.typ == exprBool && right.typ == exprBool && (op == "=" || op == "<>"):
+	case left.typ != exprStr && right.typ != exprStr:
+		left, right = asNum(left), asNum(right)
+	default:
+		return left, &ExpressionError{tok.pos, fmt.Sprintf("cannot compare %s with %s using %s", left.typ, right.typ, tok.text)}
+	}
+	return exprNode{fmt.Sprintf("(%s %s %s)", left.sql, op, right.sql), exprBool}, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	return p.parseArithmetic("+-", p.parseProduct)
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	return p.parseArithmetic("*/", p.parseUnary)
+}
+
+func (p *exprParser) parseArithmetic(ops string, operand func() (exprNode, error)) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return left, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp || len(tok.text) != 1 || !strings.Contains(ops, tok.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return right, err
+		}
+		if left.typ == exprStr || right.typ == exprStr {
+			return left, &ExpressionError{tok.pos, fmt.Sprintf("operator %s needs numbers", tok.text)}
+		}
+		l, r := asNum(left), asNum(right)
+		if tok.text == "/" {
+			left = exprNode{fmt.Sprintf("(%s / NULLIF(%s, 0))", l.sql, r.sql), exprNum}
+		} else {
+			left = exprNode{fmt.Sprintf("(%s %s %s)", l.sql, tok.text, r.sql), exprNum}
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	tok := p.peek()
+	if tok.kind == tokOp && tok.text == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return operand, err
+		}
+		if operand.typ == exprStr {
+			return operand, &ExpressionError{tok.pos, "cannot negate a string"}
+		}
+		return exprNode{"(-" + asNum(operand).sql + ")", exprNum}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return exprNode{}, &ExpressionError{tok.pos, fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return exprNode{strconv.FormatFloat(f, 'g', -1, 64) + "::DOUBLE", exprNum}, nil
+	case tokString:
+		return exprNode{"'" + strings.ReplaceAll(tok.text, "'", "''") + "'", exprStr}, nil
+	case tokRef:
+		return p.column(tok, "value")
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return node, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return node, &ExpressionError{closing.pos, "expected ')'"}
+		}
+		return node, nil
+	case tokIdent:
+		switch strings.ToUpper(tok.text) {
+		case "TRUE":
+			return exprNode{"TRUE", exprBool}, nil
+		case "FALSE":
+			return exprNode{"FALSE", exprBool}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		return exprNode{}, &ExpressionError{tok.pos, fmt.Sprintf("unknown identifier %q (signal references are device::signal)", tok.text)}
+	case tokEOF:
+		return exprNode{}, &ExpressionError{tok.pos, "unexpected end of expression"}
+	default:
+		return exprNode{}, &ExpressionError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	p.next() // (
+	fn := strings.ToLower(name.text)
+
+	if fn == "delta" || fn == "rate" {
+		ref := p.next()
+		if ref.kind != tokRef {
+			return exprNode{}, &ExpressionError{ref.pos, fn + "() takes a signal reference"}
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return exprNode{}, &ExpressionError{closing.pos, "expected ')'"}
+		}
+		return p.column(ref, fn)
+	}
+
+	var args []exprNode
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return arg, err
+		}
+		if arg.typ == exprStr {
+			return arg, &ExpressionError{name.pos, fn + "() needs numbers"}
+		}
+		args = append(args, asNum(arg))
+		sep := p.next()
+		if sep.kind == tokRParen {
+			break
+		}
+		if sep.kind != tokComma {
+			return exprNode{}, &ExpressionError{sep.pos, "expected ',' or ')'"}
+		}
+	}
+
+	switch {
+	case fn == "abs" && len(args) == 1:
+		return exprNode{"ABS(" + args[0].sql + ")", exprNum}, nil
+	case (fn == "min" || fn == "max") && len(args) == 2:
+		sqlFn := "LEAST"
+		if fn == "max" {
+			sqlFn = "GREATEST"
+		}
+		return exprNode{fmt.Sprintf("%s(%s, %s)", sqlFn, args[0].sql, args[1].sql), exprNum}, nil
+	case fn == "abs", fn == "min", fn == "max":
+		return exprNode{}, &ExpressionError{name.pos, fmt.Sprintf("wrong number of arguments for %s()", fn)}
+	default:
+		return exprNode{}, &ExpressionError{name.pos, fmt.Sprintf("unknown function %q", name.text)}
+	}
+}
+
+// column registers an input column for a signal reference.
+func (p *exprParser) column(ref token, kind string) (exprNode, error) {
+	typ, ok := p.refType(ref.text)
+	if !ok {
+		return exprNode{}, &ExpressionError{ref.pos, fmt.Sprintf("unknown signal %q", ref.text)}
+	}
+	refTyp := typ
+	if kind != "value" {
+		if typ == exprStr {
+			return exprNode{}, &ExpressionError{ref.pos, fmt.Sprintf("%s() needs a numeric signal", kind)}
+		}
+		typ = exprNum
+	}
+
+	idx := -1
+	for i, c := range p.columns {
+		if c.ref == ref.text && c.kind == kind {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		idx = len(p.columns)
+		p.columns = append(p.columns, exprColumn{ref: ref.text, kind: kind, typ: refTyp})
+	}
+	return exprNode{fmt.Sprintf("c%d", idx), typ}, nil
+}
+
+func asNum(n exprNode) exprNode {
+	if n.typ == exprBool {
+		return exprNode{"CAST(" + n.sql + " AS DOUBLE)", exprNum}
+	}
+	return n
+}
+
+func asBool(n exprNode, pos int) (exprNode, error) {
+	switch n.typ {
+	case exprBool:
+		return n, nil
+	case exprNum:
+		return exprNode{"(" + n.sql + " <> 0)", exprBool}, nil
+	default:
+		return n, &ExpressionError{pos, "expected a boolean, got a string"}
+	}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileExpression(t *testing.T) {
+	types := map[string]exprType{
+		"B1FCNV11301-601::Motor_Running": exprBool,
+		"B1FCNV11301-601::Sensor_A":      exprBool,
+		"PLC::Pressure":                  exprNum,
+		"PLC::Mode":                      exprStr,
+	}
+	refType := func(ref string) (exprType, bool) {
+		typ, ok := types[ref]
+		return typ, ok
+	}
+
+	valid := []struct {
+		src  string
+		typ  exprType
+		cols int
+	}{
+		{"B1FCNV11301-601::Motor_Running AND NOT B1FCNV11301-601::Sensor_A", exprBool, 2},
+		{"PLC::Pressure > 120", exprBool, 1},
+		{"rate(PLC::Pressure)", exprNum, 1},
+		{"(PLC::Pressure - delta(PLC::Pressure)) * 2 / 3", exprNum, 2},
+		{"`PLC::Mode` = 'AUTO' or PLC::Pressure >= -1.5", exprBool, 2},
+		{"max(PLC::Pressure, 10) + abs(-PLC::Pressure)", exprNum, 1},
+		{"B1FCNV11301-601::Sensor_A + 1", exprNum, 1},
+	}
+	for _, tc := range valid {
+		expr, err := CompileExpression(tc.src, refType)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.src, err)
+			continue
+		}
+		if expr.Type != tc.typ || len(expr.Columns) != tc.cols {
+			t.Errorf("%q: expected %s with %d columns, got %s with %d (%s)", tc.src, tc.typ, tc.cols, expr.Type, len(expr.Columns), expr.SQL)
+		}
+	}
+
+	invalid := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"PLC::Pressure >", 16, "unexpected end"},
+		{"PLC::Missing > 1", 1, "unknown signal"},
+		{"PLC::Mode + 1", 11, "needs numbers"},
+		{"(PLC::Pressure > 1", 19, "expected ')'"},
+		{"Pressure > 1", 1, "unknown identifier"},
+		{"rate(PLC::Mode)", 6, "numeric signal"},
+		{"PLC::Pressure # 2", 15, "unexpected character"},
+		{"PLC::Mode AND PLC::Pressure > 1", 11, "expected a boolean"},
+	}
+	for _, tc := range invalid {
+		_, err := CompileExpression(tc.src, refType)
+		var exprErr *ExpressionError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: expected an ExpressionError, got %v", tc.src, err)
+			continue
+		}
+		if exprErr.Pos != tc.pos || !strings.Contains(exprErr.Msg, tc.msg) {
+			t.Errorf("%q: expected %q at column %d, got %v", tc.src, tc.msg, tc.pos, exprErr)
+		}
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%q: expected error to wrap ErrInvalidQuery", tc.src)
+		}
+	}
+}
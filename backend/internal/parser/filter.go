@@ -0,0 +1,410 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter language for log entries.
+//
+//	filter  := or
+//	or      := and ("OR" and)*
+//	and     := not (["AND"] not)*
+//	not     := ("NOT" | "-") not | primary
+//	primary := "(" or ")" | field op value | "time" ":" "[" [time] ".." [time] "]" | value
+//	field   := "device" | "signal" | "category" | "type" | "value" | "time"
+//	op      := ":" | "=" | "!=" | "<" | "<=" | ">" | ">="
+//	value   := word | "quoted string"
+//
+// device, signal and category match case-insensitive globs (* and ?); signal also accepts
+// device::signal. value compares numerically against a number and as case-insensitive text
+// (or glob) otherwise. time takes Unix ms, UTC dates (2006-01-02T15:04:05) or UTC times of
+// day (15:04:05.000), which match on every day. A bare value is a substring search over
+// device, signal and value. Keywords and field names are case-insensitive.
+//
+// Example: device:B1FCNV* AND signal:Error* AND value>3 AND NOT category:INPUT AND time:[10:00..10:05]
+
+// Filter is a compiled filter: a SQL predicate over the entries table and its arguments.
+type Filter struct {
+	Source string
+	SQL    string
+	Args   []interface{}
+}
+
+// msPerDay is used to match times of day on Unix ms timestamps (UTC).
+const msPerDay = 24 * 60 * 60 * 1000
+
+// CompileFilter parses a filter expression into parameterized SQL.
+// Returns nil for an empty filter. Syntax errors are *ExpressionError values with the column.
+func CompileFilter(src string) (*Filter, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	p := &filterParser{src: src}
+	sql, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		if p.src[p.pos] == ')' {
+			return nil, p.errorf(p.pos, "unexpected ')'")
+		}
+		return nil, p.errorf(p.pos, "unexpected %q", p.src[p.pos:])
+	}
+	return &Filter{Source: src, SQL: sql, Args: p.args}, nil
+}
+
+type filterParser struct {
+	src  string
+	pos  int
+	args []interface{}
+}
+
+func (p *filterParser) errorf(pos int, format string, a ...interface{}) error {
+	return &ExpressionError{Pos: pos + 1, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// keyword consumes a case-insensitive keyword followed by a delimiter.
+func (p *filterParser) keyword(kw string) bool {
+	end := p.pos + len(kw)
+	if end > len(p.src) || !strings.EqualFold(p.src[p.pos:end], kw) {
+		return false
+	}
+	if end < len(p.src) && !unicode.IsSpace(rune(p.src[end])) && p.src[end] != '(' {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *filterParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		p.skipSpace()
+		if !p.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " OR " + right + ")"
+	}
+}
+
+func (p *filterParser) parseAnd() (string, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return "", err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] == ')' {
+			return left, nil
+		}
+		start := p.pos
+		if p.keyword("OR") {
+			p.pos = start
+			return left, nil
+		}
+		p.keyword("AND")
+		right, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		left = left + " AND " + right
+	}
+}
+
+func (p *filterParser) parseNot() (string, error) {
+	p.skipSpace()
+	negate := p.keyword("NOT")
+	if !negate && p.pos+1 < len(p.src) && p.src[p.pos] == '-' && !unicode.IsSpace(rune(p.src[p.pos+1])) {
+		p.pos++
+		negate = true
+	}
+	if negate {
+		inner, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return "(NOT " + inner + ")", nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "", p.errorf(p.pos, "expected a filter term")
+	}
+	start := p.pos
+	switch p.src[p.pos] {
+	case '(':
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return "", p.errorf(start, "missing ')'")
+		}
+		p.pos++
+		return "(" + inner + ")", nil
+	case ')':
+		return "", p.errorf(p.pos, "unexpected ')'")
+	}
+
+	// field op value
+	end := p.pos
+	for end < len(p.src) && unicode.IsLetter(rune(p.src[end])) {
+		end++
+	}
+	field := strings.ToLower(p.src[p.pos:end])
+	switch field {
+	case "device", "signal", "category", "type", "value", "time":
+		if op := filterOp(p.src[end:]); op != "" {
+			p.pos = end + len(op)
+			return p.fieldTerm(field, op, start)
+		}
+	}
+
+	// Bare value: substring search
+	value, _, err := p.readValue()
+	if err != nil {
+		return "", err
+	}
+	pattern := "%" + likeEscape(value) + "%"
+	p.args = append(p.args, pattern, pattern, pattern)
+	return fmt.Sprintf(`(device_id ILIKE ? ESCAPE '\' OR signal ILIKE ? ESCAPE '\' OR %s ILIKE ? ESCAPE '\')`, valueTextExpr), nil
+}
+
+// filterOp returns the comparison operator at the start of s, if any.
+func filterOp(s string) string {
+	for _, op := range []string{"!=", ">=", "<=", ":", "=", "<", ">"} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// readValue reads a quoted string or a word ending at whitespace or ')'.
+func (p *filterParser) readValue() (string, int, error) {
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		var sb strings.Builder
+		for i := p.pos + 1; i < len(p.src); i++ {
+			switch c := p.src[i]; {
+			case c == '\\' && i+1 < len(p.src):
+				i++
+				sb.WriteByte(p.src[i])
+			case c == '"':
+				p.pos = i + 1
+				return sb.String(), start, nil
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return "", start, p.errorf(start, "unterminated string")
+	}
+	for p.pos < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos])) && p.src[p.pos] != ')' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", start, p.errorf(start, "expected a value")
+	}
+	return p.src[start:p.pos], start, nil
+}
+
+// fieldTerm compiles "field op value"; the operator has been consumed.
+func (p *filterParser) fieldTerm(field, op string, start int) (string, error) {
+	if field == "time" && op == ":" {
+		return p.timeRange()
+	}
+	value, valuePos, err := p.readValue()
+	if err != nil {
+		return "", err
+	}
+
+	switch field {
+	case "device", "signal", "category":
+		if op != ":" && op != "=" && op != "!=" {
+			return "", p.errorf(start, "operator %s is not supported for %s", op, field)
+		}
+		var clause string
+		if field == "signal" && strings.Contains(value, "::") {
+			device, signal, _ := strings.Cut(value, "::")
+			clause = fmt.Sprintf(`(%s AND %s)`, p.glob("device_id", device), p.glob("signal", signal))
+		} else {
+			column := map[string]string{"device": "device_id", "signal": "signal", "category": "COALESCE(category, '')"}[field]
+			clause = p.glob(column, value)
+		}
+		if op == "!=" {
+			clause = "(NOT " + clause + ")"
+		}
+		return clause, nil
+
+	case "type":
+		if op != ":" && op != "=" && op != "!=" {
+			return "", p.errorf(start, "operator %s is not supported for type", op)
+		}
+		valType, ok := map[string]int{
+			"boolean": valTypeBool, "bool": valTypeBool, "integer": valTypeInt, "int": valTypeInt,
+			"float": valTypeFloat, "string": valTypeString,
+		}[strings.ToLower(value)]
+		if !ok {
+			return "", p.errorf(valuePos, "unknown type %q", value)
+		}
+		p.args = append(p.args, valType)
+		if op == "!=" {
+			return "val_type <> ?", nil
+		}
+		return "val_type = ?", nil
+
+	case "value":
+		num, numErr := strconv.ParseFloat(value, 64)
+		switch op {
+		case "<", "<=", ">", ">=":
+			if numErr != nil {
+				return "", p.errorf(valuePos, "%s needs a number, got %q", op, value)
+			}
+			p.args = append(p.args, num)
+			return fmt.Sprintf("COALESCE(%s %s ?, false)", valueNumExpr, op), nil
+		}
+		var clause string
+		if numErr == nil {
+			p.args = append(p.args, num)
+			clause = fmt.Sprintf("COALESCE(%s = ?, false)", valueNumExpr)
+		} else {
+			clause = p.glob("("+valueTextExpr+")", value)
+		}
+		if op == "!=" {
+			clause = "(NOT " + clause + ")"
+		}
+		return clause, nil
+
+	default: // time
+		if op == "!=" {
+			return "", p.errorf(start, "operator != is not supported for time")
+		}
+		ms, timeOfDay, err := parseFilterTime(value)
+		if err != nil {
+			return "", p.errorf(valuePos, "%v", err)
+		}
+		if op == ":" {
+			op = "="
+		}
+		p.args = append(p.args, ms)
+		if timeOfDay {
+			return fmt.Sprintf("timestamp %% %d %s ?", msPerDay, op), nil
+		}
+		return fmt.Sprintf("timestamp %s ?", op), nil
+	}
+}
+
+// timeRange compiles "[from..to]" after "time:"; either bound may be omitted.
+func (p *filterParser) timeRange() (string, error) {
+	start := p.pos
+	if p.pos >= len(p.src) || p.src[p.pos] != '[' {
+		return "", p.errorf(start, "time: needs a range like [10:00..10:05]")
+	}
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 {
+		return "", p.errorf(start, "missing ']'")
+	}
+	body := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	sep := strings.Index(body, "..")
+	if sep < 0 {
+		return "", p.errorf(start, "time range needs '..'")
+	}
+	bounds := [2]string{strings.TrimSpace(body[:sep]), strings.TrimSpace(body[sep+2:])}
+	if bounds[0] == "" && bounds[1] == "" {
+		return "", p.errorf(start, "time range needs at least one bound")
+	}
+
+	var ms [2]int64
+	var kinds []bool
+	for i, b := range bounds {
+		if b == "" {
+			continue
+		}
+		var timeOfDay bool
+		var err error
+		if ms[i], timeOfDay, err = parseFilterTime(b); err != nil {
+			return "", p.errorf(start+1+i*(sep+2), "%v", err)
+		}
+		kinds = append(kinds, timeOfDay)
+	}
+	if len(kinds) == 2 && kinds[0] != kinds[1] {
+		return "", p.errorf(start, "time range mixes a time of day with a date")
+	}
+
+	column := "timestamp"
+	if kinds[0] {
+		column = fmt.Sprintf("(timestamp %% %d)", msPerDay)
+	}
+	switch {
+	case bounds[0] == "":
+		p.args = append(p.args, ms[1])
+		return column + " <= ?", nil
+	case bounds[1] == "":
+		p.args = append(p.args, ms[0])
+		return column + " >= ?", nil
+	case kinds[0] && ms[0] > ms[1]:
+		// Time of day range across midnight
+		p.args = append(p.args, ms[0], ms[1])
+		return fmt.Sprintf("(%s >= ? OR %s <= ?)", column, column), nil
+	default:
+		p.args = append(p.args, ms[0], ms[1])
+		return column + " BETWEEN ? AND ?", nil
+	}
+}
+
+// glob returns a case-insensitive match of column against a glob with * and ?.
+func (p *filterParser) glob(column, pattern string) string {
+	like := likeEscape(pattern)
+	like = strings.NewReplacer("*", "%", "?", "_").Replace(like)
+	p.args = append(p.args, like)
+	return column + ` ILIKE ? ESCAPE '\'`
+}
+
+// likeEscape escapes the LIKE wildcards of s.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// parseFilterTime parses Unix ms, a UTC date-time or a UTC time of day (returned as ms since midnight).
+func parseFilterTime(s string) (int64, bool, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, false, nil
+	}
+	for _, layout := range []string{"15:04:05.999", "15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return int64(t.Hour())*3600000 + int64(t.Minute())*60000 + int64(t.Second())*1000 +
+				int64(t.Nanosecond()/1e6), true, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UnixMilli(), false, nil
+		}
+	}
+	return 0, false, fmt.Errorf("invalid time %q", s)
+}
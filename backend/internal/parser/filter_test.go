@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCompileFilter_Errors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{"device:", 8},
+		{"(signal:Error", 1},
+		{"signal:Error)", 13},
+		{"value>abc", 7},
+		{"type:decimal", 6},
+		{"time:[10:00..nope]", 14},
+		{"time:10:00", 6},
+		{"device>3", 1},
+		{`value:"open`, 7},
+		{"signal:A AND", 13},
+	}
+	for _, tc := range tests {
+		_, err := CompileFilter(tc.src)
+		var exprErr *ExpressionError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: expected an ExpressionError, got %v", tc.src, err)
+			continue
+		}
+		if exprErr.Pos != tc.pos {
+			t.Errorf("%q: expected error at column %d, got %v", tc.src, tc.pos, exprErr)
+		}
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%q: expected ErrInvalidQuery", tc.src)
+		}
+	}
+
+	if f, err := CompileFilter("   "); f != nil || err != nil {
+		t.Errorf("Expected an empty filter to compile to nil, got %v, %v", f, err)
+	}
+}
+
+func TestDuckStore_QueryEntriesWithFilter(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	store.AddEntry(createTestEntry("B1FCNV01", "Error_Code", day.Add(10*time.Hour+time.Minute), 5, "OUTPUT"))
+	store.AddEntry(createTestEntry("B1FCNV01", "Error_Code", day.Add(10*time.Hour+10*time.Minute), 7, "OUTPUT"))
+	store.AddEntry(createTestEntry("B1FCNV02", "ErrorText", day.Add(10*time.Hour+2*time.Minute), "Jam", "INPUT"))
+	store.AddEntry(createTestEntry("B1FCNV02", "Run", day.Add(10*time.Hour+3*time.Minute), true, "OUTPUT"))
+	store.AddEntry(createTestEntry("LIFT_1", "Error_Code", day.Add(9*time.Hour), 9, "OUTPUT"))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{"device:B1FCNV* AND signal:Error* AND value>3 AND NOT category:INPUT AND time:[10:00..10:05]", 1},
+		{"device:b1fcnv*", 4},
+		{"signal:B1FCNV02::Error*", 1},
+		{"value:jam", 1},
+		{"value:true OR value=9", 2},
+		{"type:integer -device:LIFT_1", 2},
+		{"(device:LIFT_1 OR signal:Run) category:OUTPUT", 2},
+		{"time:[10:02..]", 3},
+		{"time:[..2024-03-01T09:30]", 1},
+		{"jam", 1},
+		{"Error_", 3},
+		{`category!=OUTPUT`, 1},
+	}
+	for _, tc := range tests {
+		f, err := CompileFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("%q: CompileFilter failed: %v", tc.filter, err)
+		}
+		_, total, err := store.QueryEntries(ctx, QueryParams{Filter: f}, 1, 100)
+		if err != nil {
+			t.Fatalf("%q: QueryEntries failed: %v", tc.filter, err)
+		}
+		if total != tc.want {
+			t.Errorf("%q: expected %d entries, got %d", tc.filter, tc.want, total)
+		}
+	}
+}
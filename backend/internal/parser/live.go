@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"context"
+	"sync"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// CommitInfo describes the entries of a store that are committed to DuckDB and thus
+// queryable, possibly while a parse is still adding entries. Entries have the ids
+// 0..Count-1; StartTime and EndTime (ms) are the time range they cover.
+type CommitInfo struct {
+	Count     int   `json:"count"`
+	StartTime int64 `json:"startTime"`
+	EndTime   int64 `json:"endTime"`
+}
+
+// liveState tracks committed entries for readers running alongside the parse.
+type liveState struct {
+	mu        sync.RWMutex
+	committed CommitInfo
+	hook      func(CommitInfo)
+}
+
+// SetCommitHook registers fn to be called after every committed batch, from the goroutine
+// adding entries. Set it before the first entry is added.
+func (ds *DuckStore) SetCommitHook(fn func(CommitInfo)) {
+	ds.live.mu.Lock()
+	ds.live.hook = fn
+	ds.live.mu.Unlock()
+}
+
+// Committed returns the entries committed so far. Unlike Len it is safe to call while
+// entries are being added.
+func (ds *DuckStore) Committed() CommitInfo {
+	ds.live.mu.RLock()
+	defer ds.live.mu.RUnlock()
+	return ds.live.committed
+}
+
+// commit publishes the entries flushed so far; called by flushBatch once a batch is in DuckDB.
+func (ds *DuckStore) commit() {
+	ds.mu.RLock()
+	info := CommitInfo{Count: ds.entryCount, StartTime: ds.minTs, EndTime: ds.maxTs}
+	ds.mu.RUnlock()
+	ds.live.mu.Lock()
+	ds.live.committed = info
+	hook := ds.live.hook
+	ds.live.mu.Unlock()
+	if hook != nil {
+		hook(info)
+	}
+}
+
+// GetCommittedEntries returns up to limit committed entries in insertion order starting
+// at id from, together with what was committed when they were read. It can be used while
+// the store is still being filled, as it only reads rows flushed before the last commit.
+func (ds *DuckStore) GetCommittedEntries(ctx context.Context, from, limit int) ([]models.LogEntry, CommitInfo, error) {
+	info := ds.Committed()
+	end := from + limit
+	if end > info.Count {
+		end = info.Count
+	}
+	entries, err := ds.GetEntries(ctx, from, end)
+	return entries, info, err
+}
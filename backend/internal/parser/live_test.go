@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_CommittedEntries(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+	store.batchSize = 2
+
+	var commits []CommitInfo
+	store.SetCommitHook(func(info CommitInfo) { commits = append(commits, info) })
+
+	base := time.UnixMilli(1_700_000_000_000)
+	for i := 0; i < 3; i++ {
+		store.AddEntry(createTestEntry("PLC", "Count", base.Add(time.Duration(i)*time.Second), i, ""))
+	}
+	ctx := context.Background()
+
+	// Only the first batch is committed; the third entry is still buffered
+	entries, info, err := store.GetCommittedEntries(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetCommittedEntries failed: %v", err)
+	}
+	if len(entries) != 2 || info.Count != 2 || info.EndTime != base.Add(time.Second).UnixMilli() {
+		t.Errorf("Expected 2 committed entries up to 1s, got %d (%+v)", len(entries), info)
+	}
+
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if len(commits) != 2 || commits[1].Count != 3 || commits[1].StartTime != base.UnixMilli() {
+		t.Errorf("Expected commits of 2 and 3 entries, got %+v", commits)
+	}
+	entries, _, err = store.GetCommittedEntries(ctx, 2, 10)
+	if err != nil || len(entries) != 1 || entries[0].Value != 2 {
+		t.Errorf("Expected the last entry after finalizing, got %+v (%v)", entries, err)
+	}
+}
+
+func TestDuckStore_ReadWhileAdding(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+	store.batchSize = 10
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		base := time.UnixMilli(1_700_000_000_000)
+		for i := 0; i < 200; i++ {
+			store.AddEntry(createTestEntry("PLC", "Count", base.Add(time.Duration(i)*time.Millisecond), i, ""))
+		}
+		store.Finalize()
+	}()
+
+	// Readers only ever see whole committed batches
+	ctx := context.Background()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		entries, info, err := store.GetCommittedEntries(ctx, 0, 1000)
+		if err != nil {
+			t.Fatalf("GetCommittedEntries failed: %v", err)
+		}
+		if len(entries) != info.Count || info.Count%10 != 0 {
+			t.Fatalf("Expected whole committed batches, got %d of %+v", len(entries), info)
+		}
+		store.Len()
+		store.GetSignals()
+		store.GetTimeRange()
+	}
+	if info := store.Committed(); info.Count != 200 {
+		t.Errorf("Expected 200 committed entries, got %+v", info)
+	}
+}
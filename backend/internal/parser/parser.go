@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -21,8 +22,9 @@ type Parser interface {
 	CanParse(filePath string) (bool, error)
 	// Parse parses the entire file and returns the result.
 	Parse(filePath string) (*models.ParsedLog, []*models.ParseError, error)
-	// ParseWithProgress parses with progress callbacks for large files.
-	ParseWithProgress(filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error)
+	// ParseWithProgress parses with progress callbacks for large files. It stops with the
+	// context error once ctx is cancelled and waits while a PauseGate of ctx is paused.
+	ParseWithProgress(ctx context.Context, filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error)
 }
 
 // Common utilities for parsing
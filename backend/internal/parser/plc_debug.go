@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -64,10 +65,10 @@ func (p *PLCDebugParser) CanParse(filePath string) (bool, error) {
 }
 
 func (p *PLCDebugParser) Parse(filePath string) (*models.ParsedLog, []*models.ParseError, error) {
-	return p.ParseWithProgress(filePath, nil)
+	return p.ParseWithProgress(context.Background(), filePath, nil)
 }
 
-func (p *PLCDebugParser) ParseWithProgress(filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
+func (p *PLCDebugParser) ParseWithProgress(ctx context.Context, filePath string, onProgress ProgressCallback) (*models.ParsedLog, []*models.ParseError, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, nil, err
@@ -98,6 +99,11 @@ func (p *PLCDebugParser) ParseWithProgress(filePath string, onProgress ProgressC
 
 	for scanner.Scan() {
 		lineNum++
+		if lineNum%checkpointLines == 0 {
+			if err := Checkpoint(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
 		line := scanner.Text()
 		bytesRead += int64(len(line)) + 1 // +1 for newline
 
@@ -140,7 +146,7 @@ func (p *PLCDebugParser) ParseWithProgress(filePath string, onProgress ProgressC
 }
 
 // ParseToDuckStore parses directly into a DuckStore for memory-efficient large file handling.
-func (p *PLCDebugParser) ParseToDuckStore(filePath string, store *DuckStore, onProgress ProgressCallback) ([]*models.ParseError, error) {
+func (p *PLCDebugParser) ParseToDuckStore(ctx context.Context, filePath string, store *DuckStore, onProgress ProgressCallback) ([]*models.ParseError, error) {
 	fmt.Printf("[Parse] Opening file: %s\n", filePath)
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -192,6 +198,11 @@ func (p *PLCDebugParser) ParseToDuckStore(filePath string, store *DuckStore, onP
 
 	for scanner.Scan() {
 		lineNum++
+		if lineNum%checkpointLines == 0 {
+			if err := Checkpoint(ctx); err != nil {
+				return nil, err
+			}
+		}
 
 		// Verbose logging for first few lines
 		if lineNum <= 5 {
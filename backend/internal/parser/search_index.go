@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search modes of QueryParams.SearchMode. Term and prefix searches use the search index;
+// substring and regex searches scan the entries table.
+const (
+	SearchSubstring = ""       // case-(in)sensitive substring of device, signal or value
+	SearchTerm      = "term"   // every search word is a whole word of device, signal, category or value
+	SearchPrefix    = "prefix" // every search word starts a word of device, signal, category or value
+)
+
+// Searchable columns in the search index, in the order of searchColumns.
+const (
+	searchDevice = iota
+	searchSignal
+	searchCategory
+	searchValue
+)
+
+var searchColumns = [...]string{"device_id", "signal", "category", "val_str"}
+
+// maxSearchInTexts caps the IN list of a column; a word (typically a short prefix) found
+// in more texts is matched with a word regex over the column instead.
+const maxSearchInTexts = 500
+
+// searchIndex is an inverted index from lower-case words to the distinct device ids, signal
+// names, categories and string values containing them. It indexes distinct texts rather than
+// rows, so it stays small on large sessions, and search clauses become IN lists that DuckDB
+// evaluates much faster than LIKE over every row. It lives in memory so read-only stores
+// can build it too.
+type searchIndex struct {
+	terms []string                 // sorted distinct words
+	texts map[string][]searchEntry // word -> texts containing it
+}
+
+type searchEntry struct {
+	column int
+	text   string
+}
+
+// searchTokens splits text into lower-case words of letters and digits.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SetSearchIndexOnFinalize makes Finalize build the search index right away instead of
+// on the first term or prefix search.
+func (ds *DuckStore) SetSearchIndexOnFinalize(enabled bool) {
+	ds.searchIndexOnFinalize = enabled
+}
+
+// getSearchIndex returns the search index, building it on first use.
+// Returns nil if the index cannot be built; callers then fall back to a scan.
+func (ds *DuckStore) getSearchIndex() *searchIndex {
+	ds.searchIndexMu.Lock()
+	defer ds.searchIndexMu.Unlock()
+	if ds.searchIdx == nil {
+		idx, err := ds.buildSearchIndex()
+		if err != nil {
+			fmt.Printf("[DuckStore] Warning: search index build failed: %v\n", err)
+			return nil
+		}
+		ds.searchIdx = idx
+	}
+	return ds.searchIdx
+}
+
+func (ds *DuckStore) buildSearchIndex() (*searchIndex, error) {
+	start := time.Now()
+	rows, err := ds.db.Query(`
+		SELECT DISTINCT 0, device_id FROM entries
+		UNION ALL SELECT DISTINCT 1, signal FROM entries
+		UNION ALL SELECT DISTINCT 2, category FROM entries WHERE category IS NOT NULL
+		UNION ALL SELECT DISTINCT 3, val_str FROM entries WHERE val_type = 3 AND val_str IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idx := &searchIndex{texts: make(map[string][]searchEntry)}
+	texts := 0
+	for rows.Next() {
+		var e searchEntry
+		if err := rows.Scan(&e.column, &e.text); err != nil {
+			return nil, err
+		}
+		texts++
+		seen := make(map[string]bool)
+		for _, term := range searchTokens(e.text) {
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+			if _, ok := idx.texts[term]; !ok {
+				idx.terms = append(idx.terms, term)
+			}
+			idx.texts[term] = append(idx.texts[term], e)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(idx.terms)
+
+	fmt.Printf("[DuckStore] Search index built in %v: %d words over %d texts\n", time.Since(start), len(idx.terms), texts)
+	return idx, nil
+}
+
+// lookup returns the texts containing a word, or a word starting with it if prefix is set.
+func (idx *searchIndex) lookup(word string, prefix bool) []searchEntry {
+	if !prefix {
+		return idx.texts[word]
+	}
+	var entries []searchEntry
+	for i := sort.SearchStrings(idx.terms, word); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		entries = append(entries, idx.texts[idx.terms[i]]...)
+	}
+	return entries
+}
+
+// whereClause returns a SQL predicate matching rows that contain every word of search.
+// Numeric and boolean words also match entries with that value.
+func (idx *searchIndex) whereClause(search string, prefix bool) (string, []interface{}) {
+	words := searchTokens(search)
+	if len(words) == 0 {
+		return "TRUE", nil
+	}
+
+	var clauses []string
+	var args []interface{}
+	for _, word := range words {
+		var byColumn [len(searchColumns)][]interface{}
+		seen := make(map[searchEntry]bool)
+		for _, e := range idx.lookup(word, prefix) {
+			if !seen[e] {
+				seen[e] = true
+				byColumn[e.column] = append(byColumn[e.column], e.text)
+			}
+		}
+
+		var alternatives []string
+		for column, texts := range byColumn {
+			if len(texts) == 0 {
+				continue
+			}
+			if len(texts) > maxSearchInTexts {
+				// A word of the column is (or starts with) the search word; words are runs
+				// of letters and digits as in searchTokens
+				pattern := `(^|[^\pL\pN])` + regexp.QuoteMeta(word)
+				if !prefix {
+					pattern += `($|[^\pL\pN])`
+				}
+				alternatives = append(alternatives, fmt.Sprintf("regexp_matches(%s, ?, 'i')", searchColumns[column]))
+				args = append(args, pattern)
+				continue
+			}
+			alternatives = append(alternatives, fmt.Sprintf("%s IN (%s)",
+				searchColumns[column], strings.TrimSuffix(strings.Repeat("?, ", len(texts)), ", ")))
+			args = append(args, texts...)
+		}
+		if n, err := strconv.ParseInt(word, 10, 64); err == nil && !prefix {
+			alternatives = append(alternatives, "(val_type = 1 AND val_int = ?)", "(val_type = 2 AND val_float = ?)")
+			args = append(args, n, float64(n))
+		}
+		if (word == "true" || word == "false") && !prefix {
+			alternatives = append(alternatives, "(val_type = 0 AND val_bool = ?)")
+			args = append(args, word == "true")
+		}
+
+		if len(alternatives) == 0 {
+			return "FALSE", nil
+		}
+		clauses = append(clauses, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	return strings.Join(clauses, " AND "), args
+}
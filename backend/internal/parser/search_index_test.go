@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_SearchIndex(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	store.AddEntry(createTestEntry("B1FCNV01", "Motor_Error", base, "Jam detected", "ERROR"))
+	store.AddEntry(createTestEntry("B1FCNV02", "Motor_Run", base.Add(time.Second), true, "OUTPUT"))
+	store.AddEntry(createTestEntry("B1FCNV02", "Speed", base.Add(2*time.Second), 42, "INPUT"))
+	store.AddEntry(createTestEntry("LIFT", "Status", base.Add(3*time.Second), "Motor overload", ""))
+	store.SetSearchIndexOnFinalize(true)
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params QueryParams
+		want   int
+	}{
+		{"term matches signal words and string values", QueryParams{Search: "motor", SearchMode: SearchTerm}, 3},
+		{"term needs whole words", QueryParams{Search: "mot", SearchMode: SearchTerm}, 0},
+		{"prefix matches word starts", QueryParams{Search: "mot", SearchMode: SearchPrefix}, 3},
+		{"every word must match", QueryParams{Search: "motor error", SearchMode: SearchTerm}, 1},
+		{"category is indexed", QueryParams{Search: "input", SearchMode: SearchTerm}, 1},
+		{"numbers match values", QueryParams{Search: "42", SearchMode: SearchTerm}, 1},
+		{"booleans match values", QueryParams{Search: "true", SearchMode: SearchTerm}, 1},
+		{"device prefix", QueryParams{Search: "b1fc", SearchMode: SearchPrefix}, 3},
+		{"regex falls back to a scan", QueryParams{Search: "^Motor_", SearchRegex: true, SearchMode: SearchTerm}, 2},
+		{"substring search is unchanged", QueryParams{Search: "otor"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, total, err := store.QueryEntries(ctx, tt.params, 1, 100)
+			if err != nil {
+				t.Fatalf("QueryEntries failed: %v", err)
+			}
+			if total != tt.want || len(entries) != tt.want {
+				t.Errorf("Expected %d entries, got total %d (%d on page)", tt.want, total, len(entries))
+			}
+		})
+	}
+}
+
+func TestDuckStore_SearchPrefixOfManyTexts(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	n := maxSearchInTexts + 100
+	for i := 0; i < n; i++ {
+		store.AddEntry(createTestEntry("PLC", fmt.Sprintf("Axis_Pos%d", i), base.Add(time.Duration(i)*time.Millisecond), i, ""))
+	}
+	store.AddEntry(createTestEntry("PLC", "Reposition", base, 1, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	// The prefix matches more signals than an IN list holds
+	clause, args := store.getSearchIndex().whereClause("pos", true)
+	if len(args) > maxSearchInTexts {
+		t.Errorf("Expected a bounded clause, got %d arguments: %s", len(args), clause)
+	}
+	_, total, err := store.QueryEntries(context.Background(), QueryParams{Search: "pos", SearchMode: SearchPrefix}, 1, 10)
+	if err != nil {
+		t.Fatalf("QueryEntries failed: %v", err)
+	}
+	if total != n {
+		t.Errorf("Expected %d entries with a word starting with pos, got %d", n, total)
+	}
+
+	// Whole words of as many texts are matched the same way
+	_, total, err = store.QueryEntries(context.Background(), QueryParams{Search: "axis", SearchMode: SearchTerm}, 1, 10)
+	if err != nil {
+		t.Fatalf("QueryEntries failed: %v", err)
+	}
+	if total != n {
+		t.Errorf("Expected %d entries with the word axis, got %d", n, total)
+	}
+}
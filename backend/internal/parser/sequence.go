@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SequenceStep is one step of a sequence pattern.
+// A step matches the first entry of Signal satisfying the condition strictly after the previous
+// matched step and at most MaxGap ms later (0 = no limit). With Edge set only value changes count,
+// so "goes ON" is {Condition: equals, Value: true, Edge: true}. A negated step requires that no such
+// entry occurs within MaxGap of the previous step; it does not advance the sequence.
+type SequenceStep struct {
+	Signal    string        `json:"signal"`
+	Condition ConditionType `json:"condition,omitempty"`
+	Value     interface{}   `json:"value,omitempty"`
+	Edge      bool          `json:"edge,omitempty"`
+	MaxGap    int64         `json:"maxGap,omitempty"`
+	Negate    bool          `json:"negate,omitempty"`
+}
+
+// SequenceQuery searches for occurrences of a sequence pattern. Start and End (Unix ms)
+// bound the timestamp of the first step; zero means unbounded.
+type SequenceQuery struct {
+	Steps    []SequenceStep `json:"steps"`
+	Start    int64          `json:"start,omitempty"`
+	End      int64          `json:"end,omitempty"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+}
+
+// SequenceMatch is one occurrence of a pattern. Timestamps is aligned with the steps;
+// negated steps have no timestamp.
+type SequenceMatch struct {
+	Timestamps []*int64 `json:"timestamps"`
+	Duration   int64    `json:"duration"`
+}
+
+// SequenceResult is a page of pattern occurrences.
+type SequenceResult struct {
+	Matches  []SequenceMatch `json:"matches"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+}
+
+// FindSequences returns the occurrences of a sequence pattern, ordered by the first step.
+// Each step is resolved with an ASOF join on the previous step, so the cost grows linearly
+// with the number of matching entries rather than with their product.
+func (ds *DuckStore) FindSequences(ctx context.Context, q SequenceQuery) (*SequenceResult, error) {
+	matchCTE, positive, args, err := sequenceSQL(q)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	result := &SequenceResult{Matches: []SequenceMatch{}, Page: q.Page, PageSize: q.PageSize}
+	if err := ds.db.QueryRowContext(ctx, matchCTE+"SELECT COUNT(*) FROM matches", args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("sequence count query failed: %w", err)
+	}
+
+	cols := make([]string, len(positive))
+	for i, step := range positive {
+		cols[i] = fmt.Sprintf("t%d", step)
+	}
+	query := matchCTE + fmt.Sprintf("SELECT %s FROM matches ORDER BY t0 LIMIT ? OFFSET ?", strings.Join(cols, ", "))
+	rows, err := ds.db.QueryContext(ctx, query, append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("sequence query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values := make([]int64, len(positive))
+		dest := make([]interface{}, len(positive))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		match := SequenceMatch{
+			Timestamps: make([]*int64, len(q.Steps)),
+			Duration:   values[len(values)-1] - values[0],
+		}
+		for i, step := range positive {
+			match.Timestamps[step] = &values[i]
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	return result, rows.Err()
+}
+
+// sequenceSQL builds a WITH clause defining a "matches" relation with one column t<i> per
+// positive step i. It also returns the indexes of the positive steps.
+func sequenceSQL(q SequenceQuery) (string, []int, []interface{}, error) {
+	if len(q.Steps) == 0 {
+		return "", nil, nil, fmt.Errorf("%w: pattern has no steps", ErrInvalidQuery)
+	}
+	if q.Steps[0].Negate {
+		return "", nil, nil, fmt.Errorf("%w: the first step cannot be negated", ErrInvalidQuery)
+	}
+
+	var ctes []string
+	var args []interface{}
+	var positive []int
+	prevCTE, prevCol := "", ""
+
+	for i, step := range q.Steps {
+		if step.Signal == "" {
+			return "", nil, nil, fmt.Errorf("%w: step %d has no signal", ErrInvalidQuery, i+1)
+		}
+		if step.MaxGap < 0 {
+			return "", nil, nil, fmt.Errorf("%w: step %d has a negative max gap", ErrInvalidQuery, i+1)
+		}
+		if step.Negate && step.MaxGap == 0 {
+			return "", nil, nil, fmt.Errorf("%w: negated step %d needs a max gap", ErrInvalidQuery, i+1)
+		}
+
+		condClause, condArgs, err := conditionSQL(step.Condition, step.Value)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("%w: step %d: %v", ErrInvalidQuery, i+1, err)
+		}
+		signalClause, signalArgs := signalMatchSQL(step.Signal)
+
+		// Events of this step
+		events := fmt.Sprintf("ev%d AS (SELECT DISTINCT timestamp AS ts FROM entries WHERE %s AND %s)",
+			i, signalClause, condClause)
+		if step.Edge {
+			events = fmt.Sprintf(`ev%d AS (
+				SELECT DISTINCT timestamp AS ts FROM (
+					SELECT *, LAG(v) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev
+					FROM (SELECT *, %s AS v FROM entries WHERE %s)
+				) WHERE prev IS NOT NULL AND v <> prev AND %s
+			)`, i, valueTextExpr, signalClause, condClause)
+		}
+		ctes = append(ctes, events)
+		args = append(args, signalArgs...)
+		args = append(args, condArgs...)
+
+		col := fmt.Sprintf("t%d", i)
+		switch {
+		case i == 0:
+			anchor := "m0 AS (SELECT ts AS t0 FROM ev0 WHERE TRUE"
+			if q.Start != 0 {
+				anchor += " AND ts >= ?"
+				args = append(args, q.Start)
+			}
+			if q.End != 0 {
+				anchor += " AND ts <= ?"
+				args = append(args, q.End)
+			}
+			ctes = append(ctes, anchor+")")
+		case step.Negate:
+			ctes = append(ctes, fmt.Sprintf(`m%d AS (
+				SELECT m.* FROM %s m ASOF LEFT JOIN ev%d e ON m.%s < e.ts
+				WHERE e.ts IS NULL OR e.ts - m.%s > ?
+			)`, i, prevCTE, i, prevCol, prevCol))
+			args = append(args, step.MaxGap)
+		default:
+			gap := ""
+			if step.MaxGap > 0 {
+				gap = fmt.Sprintf(" WHERE e.ts - m.%s <= ?", prevCol)
+			}
+			ctes = append(ctes, fmt.Sprintf("m%d AS (SELECT m.*, e.ts AS %s FROM %s m ASOF JOIN ev%d e ON m.%s < e.ts%s)",
+				i, col, prevCTE, i, prevCol, gap))
+			if step.MaxGap > 0 {
+				args = append(args, step.MaxGap)
+			}
+		}
+
+		prevCTE = fmt.Sprintf("m%d", i)
+		if !step.Negate {
+			prevCol = col
+			positive = append(positive, i)
+		}
+	}
+
+	cte := "WITH " + strings.Join(ctes, ",\n") + fmt.Sprintf(",\nmatches AS (SELECT * FROM %s)\n", prevCTE)
+	return cte, positive, args, nil
+}
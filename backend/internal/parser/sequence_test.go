@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_FindSequences(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	ms := func(off int) int64 { return base.UnixMilli() + int64(off) }
+	add := func(signal string, off int, v bool) {
+		store.AddEntry(createTestEntry("DEV", signal, at(off), v, ""))
+	}
+
+	// Handshake 1: A, B after 200ms, C after 1s -> not a failure
+	add("A", 0, false)
+	add("B", 0, false)
+	add("C", 0, false)
+	add("A", 1000, true)
+	add("B", 1200, true)
+	add("C", 2200, true)
+	// Handshake 2: A, B after 100ms, no C -> failure
+	add("A", 5000, false)
+	add("B", 5000, false)
+	add("C", 5000, false)
+	add("A", 6000, true)
+	add("B", 6100, true)
+	// Handshake 3: A, B too late
+	add("A", 9000, false)
+	add("B", 9000, false)
+	add("A", 10000, true)
+	add("A", 10100, true) // repeated ON, not an edge
+	add("B", 11000, true)
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	aOn := SequenceStep{Signal: "DEV::A", Condition: ConditionEquals, Value: true, Edge: true}
+	bOn := SequenceStep{Signal: "DEV::B", Condition: ConditionEquals, Value: true, Edge: true, MaxGap: 500}
+
+	t.Run("positive steps", func(t *testing.T) {
+		res, err := store.FindSequences(ctx, SequenceQuery{Steps: []SequenceStep{aOn, bOn}, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("FindSequences failed: %v", err)
+		}
+		if res.Total != 2 {
+			t.Fatalf("Expected 2 matches, got %+v", res.Matches)
+		}
+		m := res.Matches[0]
+		if *m.Timestamps[0] != ms(1000) || *m.Timestamps[1] != ms(1200) || m.Duration != 200 {
+			t.Errorf("Unexpected first match: %v %v", *m.Timestamps[0], *m.Timestamps[1])
+		}
+	})
+
+	t.Run("negated step", func(t *testing.T) {
+		cNotOn := SequenceStep{Signal: "DEV::C", Condition: ConditionEquals, Value: true, Edge: true, MaxGap: 2000, Negate: true}
+		res, err := store.FindSequences(ctx, SequenceQuery{Steps: []SequenceStep{aOn, bOn, cNotOn}, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("FindSequences failed: %v", err)
+		}
+		if res.Total != 1 || *res.Matches[0].Timestamps[0] != ms(6000) {
+			t.Fatalf("Expected only the failed handshake, got %+v", res.Matches)
+		}
+		if res.Matches[0].Timestamps[2] != nil {
+			t.Error("Expected no timestamp for the negated step")
+		}
+	})
+
+	t.Run("time range and paging", func(t *testing.T) {
+		res, err := store.FindSequences(ctx, SequenceQuery{
+			Steps: []SequenceStep{aOn, bOn}, Start: ms(2000), Page: 1, PageSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("FindSequences failed: %v", err)
+		}
+		if res.Total != 1 || *res.Matches[0].Timestamps[0] != ms(6000) {
+			t.Errorf("Expected one match after start, got %+v", res.Matches)
+		}
+	})
+
+	t.Run("rejects invalid patterns", func(t *testing.T) {
+		bad := [][]SequenceStep{
+			nil,
+			{{Signal: "DEV::A", Negate: true, MaxGap: 10}},
+			{aOn, {Signal: "DEV::C", Negate: true}},
+		}
+		for _, steps := range bad {
+			if _, err := store.FindSequences(ctx, SequenceQuery{Steps: steps, Page: 1, PageSize: 10}); err == nil {
+				t.Errorf("Expected error for %+v", steps)
+			}
+		}
+	})
+}
@@ -0,0 +1,404 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// maxDiffValueExamples caps the example values reported as present in only one run.
+const maxDiffValueExamples = 20
+
+// storeSeq numbers stores as they are opened (DuckStore.seq).
+var storeSeq atomic.Uint64
+
+// SignalPresence tells in which of the two compared stores a signal occurs.
+type SignalPresence string
+
+const (
+	PresenceBoth  SignalPresence = "both"
+	PresenceOnlyA SignalPresence = "only-a"
+	PresenceOnlyB SignalPresence = "only-b"
+)
+
+// DiffQuery compares store A with store B. Offset (ms) is added to the timestamps of B
+// to align the runs. Transitions whose timing differs by more than Tolerance ms count as
+// differences. With OnlyDiffering set, identical signals are left out of the page.
+type DiffQuery struct {
+	Offset        int64 `json:"offset"`
+	Tolerance     int64 `json:"tolerance"`
+	OnlyDiffering bool  `json:"onlyDiffering"`
+	Page          int   `json:"page"`
+	PageSize      int   `json:"pageSize"`
+}
+
+// SignalDiff compares one signal of two runs.
+// Equivalent transitions are the n-th change to the same value in both runs; TimingMean and
+// TimingMaxAbs summarize (B + offset) - A over them and Unmatched counts the changes without
+// a counterpart. ValuesOnlyA and ValuesOnlyB list up to 20 values seen in only one run.
+type SignalDiff struct {
+	Key          string         `json:"key"`
+	DeviceID     string         `json:"deviceId"`
+	SignalName   string         `json:"signalName"`
+	Presence     SignalPresence `json:"presence"`
+	ChangesA     int            `json:"changesA"`
+	ChangesB     int            `json:"changesB"`
+	ValueCountA  int            `json:"valueCountA"`
+	ValueCountB  int            `json:"valueCountB"`
+	ValuesOnlyA  []string       `json:"valuesOnlyA,omitempty"`
+	ValuesOnlyB  []string       `json:"valuesOnlyB,omitempty"`
+	Matched      int            `json:"matched"`
+	Unmatched    int            `json:"unmatched"`
+	TimingMean   float64        `json:"timingMean"`
+	TimingMaxAbs int64          `json:"timingMaxAbs"`
+	Differs      bool           `json:"differs"`
+}
+
+// SessionDiff is a page of signal comparisons ordered by key, with totals over all signals.
+type SessionDiff struct {
+	Offset    int64        `json:"offset"`
+	Signals   []SignalDiff `json:"signals"`
+	Total     int          `json:"total"`
+	Page      int          `json:"page"`
+	PageSize  int          `json:"pageSize"`
+	Common    int          `json:"common"`
+	OnlyInA   int          `json:"onlyInA"`
+	OnlyInB   int          `json:"onlyInB"`
+	Differing int          `json:"differing"`
+}
+
+// DiffRange is a time range (Unix ms, timeline of A) where the values of a signal differ.
+// A value is nil where the run has no value yet.
+type DiffRange struct {
+	Start  int64   `json:"start"`
+	End    int64   `json:"end"`
+	ValueA *string `json:"valueA"`
+	ValueB *string `json:"valueB"`
+}
+
+// SignalDiffDetail lists the differing time ranges of one signal.
+// Truncated is set when more ranges exist than the limit.
+type SignalDiffDetail struct {
+	Key       string      `json:"key"`
+	Offset    int64       `json:"offset"`
+	Ranges    []DiffRange `json:"ranges"`
+	Truncated bool        `json:"truncated"`
+}
+
+// DiffStores compares every recorded signal of store a with store b.
+// Both stores stream their value changes sorted by signal, value and time, so the
+// comparison is a single merge pass that keeps only per-signal totals in memory.
+func DiffStores(ctx context.Context, a, b *DuckStore, q DiffQuery) (*SessionDiff, error) {
+	release, err := acquireQueryPair(ctx, a, b)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rowsA, err := a.diffRows(ctx, "ORDER BY 1, 2, 3", "")
+	if err != nil {
+		return nil, err
+	}
+	defer rowsA.Close()
+	rowsB, err := b.diffRows(ctx, "ORDER BY 1, 2, 3", "")
+	if err != nil {
+		return nil, err
+	}
+	defer rowsB.Close()
+
+	ra, rb := &diffCursor{rows: rowsA}, &diffCursor{rows: rowsB}
+	if err := ra.next(); err != nil {
+		return nil, err
+	}
+	if err := rb.next(); err != nil {
+		return nil, err
+	}
+
+	result := &SessionDiff{Offset: q.Offset, Signals: []SignalDiff{}, Page: q.Page, PageSize: q.PageSize}
+	var diffs []SignalDiff
+	for ra.ok || rb.ok {
+		key := ra.key
+		if !ra.ok || (rb.ok && rb.key < ra.key) {
+			key = rb.key
+		}
+		d := SignalDiff{Key: key, Presence: PresenceBoth}
+		d.DeviceID, d.SignalName, _ = strings.Cut(key, "::")
+		var timingSum int64
+
+		for (ra.ok && ra.key == key) || (rb.ok && rb.key == key) {
+			value := ra.value
+			if !(ra.ok && ra.key == key) || (rb.ok && rb.key == key && rb.value < ra.value) {
+				value = rb.value
+			}
+			inA, inB := ra.at(key, value), rb.at(key, value)
+			switch {
+			case inA && !inB:
+				d.ValueCountA++
+				if len(d.ValuesOnlyA) < maxDiffValueExamples {
+					d.ValuesOnlyA = append(d.ValuesOnlyA, value)
+				}
+			case inB && !inA:
+				d.ValueCountB++
+				if len(d.ValuesOnlyB) < maxDiffValueExamples {
+					d.ValuesOnlyB = append(d.ValuesOnlyB, value)
+				}
+			default:
+				d.ValueCountA++
+				d.ValueCountB++
+			}
+
+			// Pair the n-th change to this value in A with the n-th in B
+			for ra.at(key, value) || rb.at(key, value) {
+				if ra.at(key, value) && ra.first {
+					if err := ra.next(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if rb.at(key, value) && rb.first {
+					if err := rb.next(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				switch {
+				case ra.at(key, value) && rb.at(key, value):
+					delta := rb.ts + q.Offset - ra.ts
+					d.ChangesA++
+					d.ChangesB++
+					d.Matched++
+					timingSum += delta
+					if delta < 0 {
+						delta = -delta
+					}
+					if delta > d.TimingMaxAbs {
+						d.TimingMaxAbs = delta
+					}
+					if err := ra.next(); err != nil {
+						return nil, err
+					}
+					if err := rb.next(); err != nil {
+						return nil, err
+					}
+				case ra.at(key, value):
+					d.ChangesA++
+					d.Unmatched++
+					if err := ra.next(); err != nil {
+						return nil, err
+					}
+				default:
+					d.ChangesB++
+					d.Unmatched++
+					if err := rb.next(); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		switch {
+		case d.ValueCountB == 0:
+			d.Presence = PresenceOnlyA
+			result.OnlyInA++
+		case d.ValueCountA == 0:
+			d.Presence = PresenceOnlyB
+			result.OnlyInB++
+		default:
+			result.Common++
+		}
+		if d.Matched > 0 {
+			d.TimingMean = float64(timingSum) / float64(d.Matched)
+		}
+		d.Differs = d.Presence != PresenceBoth || d.Unmatched > 0 || len(d.ValuesOnlyA) > 0 ||
+			len(d.ValuesOnlyB) > 0 || d.TimingMaxAbs > q.Tolerance
+		if d.Differs {
+			result.Differing++
+		}
+		if d.Differs || !q.OnlyDiffering {
+			diffs = append(diffs, d)
+		}
+	}
+	if err := ra.err(); err != nil {
+		return nil, err
+	}
+	if err := rb.err(); err != nil {
+		return nil, err
+	}
+
+	result.Total = len(diffs)
+	start := (q.Page - 1) * q.PageSize
+	if start >= 0 && start < len(diffs) {
+		end := start + q.PageSize
+		if end > len(diffs) {
+			end = len(diffs)
+		}
+		result.Signals = diffs[start:end]
+	}
+	return result, nil
+}
+
+// DiffSignal returns the time ranges where a signal holds different values in the two stores,
+// with B shifted by offset, merged into contiguous ranges. At most limit ranges are returned.
+func DiffSignal(ctx context.Context, a, b *DuckStore, key string, offset int64, limit int) (*SignalDiffDetail, error) {
+	parts := strings.Split(key, "::")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w: signal %q must be deviceId::signalName", ErrInvalidQuery, key)
+	}
+
+	release, err := acquireQueryPair(ctx, a, b)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rowsA, err := a.diffRows(ctx, "ORDER BY timestamp", "WHERE device_id = ? AND signal = ?", parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+	defer rowsA.Close()
+	rowsB, err := b.diffRows(ctx, "ORDER BY timestamp", "WHERE device_id = ? AND signal = ?", parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+	defer rowsB.Close()
+
+	ra, rb := &diffCursor{rows: rowsA}, &diffCursor{rows: rowsB, offset: offset}
+	if err := ra.next(); err != nil {
+		return nil, err
+	}
+	if err := rb.next(); err != nil {
+		return nil, err
+	}
+
+	detail := &SignalDiffDetail{Key: key, Offset: offset, Ranges: []DiffRange{}}
+	var valA, valB *string
+	var open *DiffRange
+	for ra.ok || rb.ok {
+		ts := ra.ts
+		if !ra.ok || (rb.ok && rb.ts < ra.ts) {
+			ts = rb.ts
+		}
+		for ra.ok && ra.ts == ts {
+			v := ra.value
+			valA = &v
+			if err := ra.next(); err != nil {
+				return nil, err
+			}
+		}
+		for rb.ok && rb.ts == ts {
+			v := rb.value
+			valB = &v
+			if err := rb.next(); err != nil {
+				return nil, err
+			}
+		}
+
+		same := valA != nil && valB != nil && *valA == *valB
+		switch {
+		case same && open != nil:
+			open.End = ts
+			detail.Ranges = append(detail.Ranges, *open)
+			open = nil
+		case !same && open == nil:
+			if len(detail.Ranges) >= limit {
+				detail.Truncated = true
+				return detail, nil
+			}
+			open = &DiffRange{Start: ts, ValueA: valA, ValueB: valB}
+		}
+	}
+	if err := ra.err(); err != nil {
+		return nil, err
+	}
+	if err := rb.err(); err != nil {
+		return nil, err
+	}
+	if open != nil {
+		open.End = a.maxTs
+		if b.maxTs+offset > open.End {
+			open.End = b.maxTs + offset
+		}
+		detail.Ranges = append(detail.Ranges, *open)
+	}
+	return detail, nil
+}
+
+// acquireQueryPair takes a query slot of both stores before either is queried and returns
+// the function that frees them. Slots are taken in the order the stores were opened, so
+// two diffs of the same stores in opposite directions never each hold the slot the other
+// waits for.
+func acquireQueryPair(ctx context.Context, a, b *DuckStore) (func(), error) {
+	if a == b {
+		return nil, fmt.Errorf("%w: cannot diff a store with itself", ErrInvalidQuery)
+	}
+	first, second := a, b
+	if b.seq < a.seq {
+		first, second = b, a
+	}
+	select {
+	case first.querySem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case second.querySem <- struct{}{}:
+	case <-ctx.Done():
+		<-first.querySem
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-second.querySem
+		<-first.querySem
+	}, nil
+}
+
+// diffRows streams the value changes (and the first value) of every recorded signal as
+// (key, value, timestamp, first) rows. The caller holds a query slot (acquireQueryPair).
+func (ds *DuckStore) diffRows(ctx context.Context, order, where string, args ...interface{}) (*sql.Rows, error) {
+	query := fmt.Sprintf(`
+		SELECT device_id || '::' || signal, v, timestamp, prev IS NULL FROM (
+			SELECT device_id, signal, timestamp, v,
+				LAG(v) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev
+			FROM (SELECT *, %s AS v FROM entries %s)
+		) WHERE prev IS NULL OR v <> prev
+		%s
+	`, valueTextExpr, where, order)
+	rows, err := ds.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("diff query failed: %w", err)
+	}
+	return rows, nil
+}
+
+// diffCursor walks the rows of diffRows one at a time; ok is false once exhausted.
+type diffCursor struct {
+	rows   *sql.Rows
+	offset int64
+	ok     bool
+	key    string
+	value  string
+	ts     int64
+	first  bool
+}
+
+func (c *diffCursor) next() error {
+	c.ok = c.rows.Next()
+	if !c.ok {
+		return c.rows.Err()
+	}
+	if err := c.rows.Scan(&c.key, &c.value, &c.ts, &c.first); err != nil {
+		return err
+	}
+	c.ts += c.offset
+	return nil
+}
+
+func (c *diffCursor) at(key, value string) bool {
+	return c.ok && c.key == key && c.value == value
+}
+
+func (c *diffCursor) err() error {
+	return c.rows.Err()
+}
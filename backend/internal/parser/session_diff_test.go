@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiffStores(t *testing.T) {
+	good, cleanupGood := createTestStore(t)
+	defer cleanupGood()
+	bad, cleanupBad := createTestStore(t)
+	defer cleanupBad()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	// The bad run was recorded 5s later and its motor starts 300ms late on the second cycle
+	for _, cycle := range []int{0, 10_000} {
+		good.AddEntry(createTestEntry("PLC", "Motor", at(cycle), false, ""))
+		good.AddEntry(createTestEntry("PLC", "Motor", at(cycle+1000), true, ""))
+		delay := 0
+		if cycle > 0 {
+			delay = 300
+		}
+		bad.AddEntry(createTestEntry("PLC", "Motor", at(5000+cycle), false, ""))
+		bad.AddEntry(createTestEntry("PLC", "Motor", at(5000+cycle+1000+delay), true, ""))
+	}
+	good.AddEntry(createTestEntry("PLC", "State", at(0), "IDLE", ""))
+	good.AddEntry(createTestEntry("PLC", "State", at(2000), "RUN", ""))
+	bad.AddEntry(createTestEntry("PLC", "State", at(5000), "IDLE", ""))
+	bad.AddEntry(createTestEntry("PLC", "State", at(7000), "FAULT", ""))
+	good.AddEntry(createTestEntry("PLC", "Same", at(0), 1, ""))
+	bad.AddEntry(createTestEntry("PLC", "Same", at(5000), 1, ""))
+	bad.AddEntry(createTestEntry("PLC", "Alarm", at(7000), true, ""))
+	if err := good.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if err := bad.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	diff, err := DiffStores(ctx, good, bad, DiffQuery{Offset: -5000, Tolerance: 100, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("DiffStores failed: %v", err)
+	}
+	if diff.Total != 4 || diff.Common != 3 || diff.OnlyInB != 1 || diff.Differing != 3 {
+		t.Fatalf("Expected 3 common signals, Alarm only in B and 3 differing, got %+v", diff)
+	}
+	bySignal := make(map[string]SignalDiff)
+	for _, d := range diff.Signals {
+		bySignal[d.SignalName] = d
+	}
+
+	motor := bySignal["Motor"]
+	if motor.ChangesA != 3 || motor.ChangesB != 3 || motor.Matched != 3 || motor.Unmatched != 0 {
+		t.Errorf("Expected 3 matched Motor transitions, got %+v", motor)
+	}
+	if motor.TimingMaxAbs != 300 || motor.TimingMean != 100 || !motor.Differs {
+		t.Errorf("Expected Motor transitions up to 300ms late, got %+v", motor)
+	}
+	state := bySignal["State"]
+	if len(state.ValuesOnlyA) != 1 || state.ValuesOnlyA[0] != "RUN" || len(state.ValuesOnlyB) != 1 || state.ValuesOnlyB[0] != "FAULT" {
+		t.Errorf("Expected RUN only in A and FAULT only in B, got %+v", state)
+	}
+	if bySignal["Alarm"].Presence != PresenceOnlyB {
+		t.Errorf("Expected Alarm only in B, got %+v", bySignal["Alarm"])
+	}
+	if bySignal["Same"].Differs {
+		t.Errorf("Expected Same to match, got %+v", bySignal["Same"])
+	}
+
+	only, err := DiffStores(ctx, good, bad, DiffQuery{Offset: -5000, Tolerance: 100, OnlyDiffering: true, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("DiffStores failed: %v", err)
+	}
+	if only.Total != 3 {
+		t.Errorf("Expected 3 differing signals, got %d", only.Total)
+	}
+
+	detail, err := DiffSignal(ctx, good, bad, "PLC::Motor", -5000, 100)
+	if err != nil {
+		t.Fatalf("DiffSignal failed: %v", err)
+	}
+	if len(detail.Ranges) != 1 {
+		t.Fatalf("Expected one differing Motor range, got %+v", detail.Ranges)
+	}
+	r := detail.Ranges[0]
+	if r.Start != base.UnixMilli()+11_000 || r.End != base.UnixMilli()+11_300 || *r.ValueA != "true" || *r.ValueB != "false" {
+		t.Errorf("Expected Motor to differ from 11s to 11.3s, got %+v", r)
+	}
+
+	state2, err := DiffSignal(ctx, good, bad, "PLC::State", -5000, 100)
+	if err != nil {
+		t.Fatalf("DiffSignal failed: %v", err)
+	}
+	if len(state2.Ranges) != 1 || state2.Ranges[0].Start != base.UnixMilli()+2000 {
+		t.Errorf("Expected State to differ from 2s on, got %+v", state2.Ranges)
+	}
+}
+
+func TestDiffStores_OppositeDirections(t *testing.T) {
+	defer SetResourceLimits(DefaultResourceLimits)
+	SetResourceLimits(ResourceLimits{QueriesPerStore: 1})
+
+	a, cleanupA := createTestStore(t)
+	defer cleanupA()
+	b, cleanupB := createTestStore(t)
+	defer cleanupB()
+	for i, store := range []*DuckStore{a, b} {
+		store.AddEntry(createTestEntry("PLC", "Motor", time.UnixMilli(int64(i)), true, ""))
+		if err := store.Finalize(); err != nil {
+			t.Fatalf("Finalize failed: %v", err)
+		}
+	}
+
+	// With one query slot per store, diffs in both directions must not each hold the
+	// slot the other waits for
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := DiffStores(ctx, a, b, DiffQuery{Page: 1, PageSize: 10})
+			errs <- err
+		}()
+		go func() {
+			_, err := DiffSignal(ctx, b, a, "PLC::Motor", 0, 10)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+	}
+
+	if _, err := DiffStores(ctx, a, a, DiffQuery{Page: 1, PageSize: 10}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected a diff of a store with itself to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// signalStatsTopValues is the number of most frequent values reported for string signals.
+const signalStatsTopValues = 10
+
+// SignalStats summarizes the behaviour of one signal over the whole store.
+// Timestamps are Unix milliseconds.
+type SignalStats struct {
+	Key         string            `json:"key"`
+	DeviceID    string            `json:"deviceId"`
+	SignalName  string            `json:"signalName"`
+	SignalType  models.SignalType `json:"signalType"`
+	Count       int               `json:"count"`
+	FirstSeen   int64             `json:"firstSeen"`
+	LastSeen    int64             `json:"lastSeen"`
+	ChangeCount int               `json:"changeCount"`
+	FirstChange *int64            `json:"firstChange,omitempty"`
+	LastChange  *int64            `json:"lastChange,omitempty"`
+	Distinct    int               `json:"distinctValues"`
+	Numeric     *NumericStats     `json:"numeric,omitempty"`
+	Boolean     *BooleanStats     `json:"boolean,omitempty"`
+	TopValues   []ValueCount      `json:"topValues,omitempty"`
+}
+
+// NumericStats holds the value distribution of an integer or float signal.
+type NumericStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+}
+
+// BooleanStats holds time-weighted state information of a boolean signal.
+// Each sample holds until the next sample of the signal, the last one until the end of the store.
+type BooleanStats struct {
+	TrueRatio    float64 `json:"trueRatio"`
+	TrueTime     int64   `json:"trueTime"`
+	RisingEdges  int     `json:"risingEdges"`
+	FallingEdges int     `json:"fallingEdges"`
+}
+
+// ValueCount is a value and the number of entries carrying it.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// GetSignalStats returns statistics for the given signal keys (deviceId::signal), or for all
+// signals when none are given. Unknown signals are skipped. Results are cached per store.
+func (ds *DuckStore) GetSignalStats(ctx context.Context, signals []string) ([]SignalStats, error) {
+	for _, key := range signals {
+		if len(strings.Split(key, "::")) != 2 {
+			return nil, fmt.Errorf("%w: signal %q must be deviceId::signalName", ErrInvalidQuery, key)
+		}
+	}
+
+	ds.statsCacheMu.RLock()
+	complete := ds.statsComplete
+	var missing []string
+	for _, key := range signals {
+		if !ds.hasSignal(key) {
+			continue
+		}
+		if _, ok := ds.statsCache[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	ds.statsCacheMu.RUnlock()
+
+	if (len(signals) == 0 && !complete) || len(missing) > 0 {
+		select {
+		case ds.querySem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		computed, err := ds.computeSignalStats(ctx, missing)
+		<-ds.querySem
+		if err != nil {
+			return nil, err
+		}
+
+		ds.statsCacheMu.Lock()
+		for key, st := range computed {
+			ds.statsCache[key] = st
+		}
+		if len(signals) == 0 {
+			ds.statsComplete = true
+		}
+		ds.statsCacheMu.Unlock()
+	}
+
+	ds.statsCacheMu.RLock()
+	defer ds.statsCacheMu.RUnlock()
+
+	result := make([]SignalStats, 0, len(signals))
+	if len(signals) == 0 {
+		for _, st := range ds.statsCache {
+			result = append(result, st)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+		return result, nil
+	}
+	for _, key := range signals {
+		if st, ok := ds.statsCache[key]; ok {
+			result = append(result, st)
+		}
+	}
+	return result, nil
+}
+
+// computeSignalStats runs the statistics queries for the given signal keys (all signals if empty).
+func (ds *DuckStore) computeSignalStats(ctx context.Context, signals []string) (map[string]SignalStats, error) {
+	filter := "TRUE"
+	var filterArgs []interface{}
+	if len(signals) > 0 {
+		clauses := make([]string, 0, len(signals))
+		for _, key := range signals {
+			clause, args := signalMatchSQL(key)
+			clauses = append(clauses, clause)
+			filterArgs = append(filterArgs, args...)
+		}
+		filter = "(" + strings.Join(clauses, " OR ") + ")"
+	}
+
+	stats := make(map[string]SignalStats)
+	valTypes := make(map[string]int)
+
+	// Counts, change points and distinct values
+	baseQuery := fmt.Sprintf(`
+		WITH seq AS (
+			SELECT device_id, signal, timestamp, val_type, v,
+				LAG(v) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev
+			FROM (SELECT id, device_id, signal, timestamp, val_type, %s AS v FROM entries WHERE %s)
+		)
+		SELECT device_id, signal, MODE(val_type), COUNT(*), MIN(timestamp), MAX(timestamp),
+			COUNT(*) FILTER (WHERE prev IS NOT NULL AND v <> prev),
+			MIN(timestamp) FILTER (WHERE prev IS NOT NULL AND v <> prev),
+			MAX(timestamp) FILTER (WHERE prev IS NOT NULL AND v <> prev),
+			COUNT(DISTINCT v)
+		FROM seq GROUP BY device_id, signal
+	`, valueTextExpr, filter)
+	rows, err := ds.db.QueryContext(ctx, baseQuery, filterArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("signal stats query failed: %w", err)
+	}
+	for rows.Next() {
+		var st SignalStats
+		var valType int
+		var firstChange, lastChange sql.NullInt64
+		if err := rows.Scan(&st.DeviceID, &st.SignalName, &valType, &st.Count, &st.FirstSeen, &st.LastSeen,
+			&st.ChangeCount, &firstChange, &lastChange, &st.Distinct); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		st.Key = st.DeviceID + "::" + st.SignalName
+		st.SignalType = valTypeToSignalType(valType)
+		if firstChange.Valid {
+			st.FirstChange = &firstChange.Int64
+			st.LastChange = &lastChange.Int64
+		}
+		stats[st.Key] = st
+		valTypes[st.Key] = valType
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Value distribution of numeric signals
+	numericQuery := fmt.Sprintf(`
+		SELECT device_id, signal, MIN(n), MAX(n), AVG(n), COALESCE(STDDEV_POP(n), 0),
+			QUANTILE_CONT(n, 0.5), QUANTILE_CONT(n, 0.9), QUANTILE_CONT(n, 0.99)
+		FROM (SELECT device_id, signal, %s AS n FROM entries WHERE %s AND val_type IN (%d, %d))
+		GROUP BY device_id, signal
+	`, valueNumExpr, filter, valTypeInt, valTypeFloat)
+	rows, err = ds.db.QueryContext(ctx, numericQuery, filterArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("numeric signal stats query failed: %w", err)
+	}
+	for rows.Next() {
+		var deviceID, signal string
+		var n NumericStats
+		if err := rows.Scan(&deviceID, &signal, &n.Min, &n.Max, &n.Mean, &n.StdDev, &n.P50, &n.P90, &n.P99); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := deviceID + "::" + signal
+		if st, ok := stats[key]; ok && (valTypes[key] == valTypeInt || valTypes[key] == valTypeFloat) {
+			st.Numeric = &n
+			stats[key] = st
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Time-weighted true ratio and edges of boolean signals
+	boolQuery := fmt.Sprintf(`
+		SELECT device_id, signal,
+			CAST(COALESCE(SUM(CASE WHEN val_bool THEN next_ts - timestamp ELSE 0 END), 0) AS BIGINT),
+			CAST(COALESCE(SUM(next_ts - timestamp), 0) AS BIGINT),
+			COUNT(*) FILTER (WHERE val_bool),
+			COUNT(*) FILTER (WHERE val_bool AND prev = false),
+			COUNT(*) FILTER (WHERE NOT val_bool AND prev = true)
+		FROM (
+			SELECT device_id, signal, timestamp, val_bool,
+				COALESCE(LEAD(timestamp) OVER w, ?) AS next_ts,
+				LAG(val_bool) OVER w AS prev
+			FROM entries WHERE %s AND val_type = %d
+			WINDOW w AS (PARTITION BY device_id, signal ORDER BY timestamp, id)
+		)
+		GROUP BY device_id, signal
+	`, filter, valTypeBool)
+	ds.mu.RLock()
+	maxTs := ds.maxTs
+	ds.mu.RUnlock()
+	boolArgs := append([]interface{}{maxTs}, filterArgs...)
+	rows, err = ds.db.QueryContext(ctx, boolQuery, boolArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("boolean signal stats query failed: %w", err)
+	}
+	for rows.Next() {
+		var deviceID, signal string
+		var trueTime, totalTime int64
+		var trueSamples int
+		var b BooleanStats
+		if err := rows.Scan(&deviceID, &signal, &trueTime, &totalTime, &trueSamples, &b.RisingEdges, &b.FallingEdges); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := deviceID + "::" + signal
+		st, ok := stats[key]
+		if !ok || valTypes[key] != valTypeBool {
+			continue
+		}
+		b.TrueTime = trueTime
+		if totalTime > 0 {
+			b.TrueRatio = float64(trueTime) / float64(totalTime)
+		} else if st.Count > 0 {
+			// All samples share the last timestamp: fall back to the sample ratio
+			b.TrueRatio = float64(trueSamples) / float64(st.Count)
+		}
+		st.Boolean = &b
+		stats[key] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Most frequent values of string signals
+	topQuery := fmt.Sprintf(`
+		SELECT device_id, signal, v, cnt FROM (
+			SELECT device_id, signal, COALESCE(val_str, '') AS v, COUNT(*) AS cnt,
+				ROW_NUMBER() OVER (PARTITION BY device_id, signal ORDER BY COUNT(*) DESC, COALESCE(val_str, '')) AS rn
+			FROM entries WHERE %s AND val_type = %d
+			GROUP BY device_id, signal, COALESCE(val_str, '')
+		)
+		WHERE rn <= ? ORDER BY device_id, signal, rn
+	`, filter, valTypeString)
+	topArgs := append(append([]interface{}{}, filterArgs...), signalStatsTopValues)
+	rows, err = ds.db.QueryContext(ctx, topQuery, topArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("string signal stats query failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deviceID, signal string
+		var vc ValueCount
+		if err := rows.Scan(&deviceID, &signal, &vc.Value, &vc.Count); err != nil {
+			return nil, err
+		}
+		key := deviceID + "::" + signal
+		if st, ok := stats[key]; ok && valTypes[key] == valTypeString {
+			st.TopValues = append(st.TopValues, vc)
+			stats[key] = st
+		}
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_GetSignalStats(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	// Boolean: ON for 300ms, OFF for 100ms, ON for the last 600ms
+	store.AddEntry(createTestEntry("DEV", "Run", at(0), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(100), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(300), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(400), true, ""))
+	// Integer
+	for i, v := range []int{10, 20, 20, 30, 40} {
+		store.AddEntry(createTestEntry("DEV", "Speed", at(i*100), v, ""))
+	}
+	// String
+	for i, v := range []string{"A", "B", "A", "A", "C"} {
+		store.AddEntry(createTestEntry("DEV", "Carrier", at(i*100), v, ""))
+	}
+	// Marks the end of the store
+	store.AddEntry(createTestEntry("OTHER", "Tick", at(1000), 1, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("all signals", func(t *testing.T) {
+		stats, err := store.GetSignalStats(ctx, nil)
+		if err != nil {
+			t.Fatalf("GetSignalStats failed: %v", err)
+		}
+		if len(stats) != 4 {
+			t.Fatalf("Expected 4 signals, got %d", len(stats))
+		}
+		if stats[0].Key != "DEV::Carrier" {
+			t.Errorf("Expected results sorted by key, got %s first", stats[0].Key)
+		}
+	})
+
+	t.Run("boolean", func(t *testing.T) {
+		stats, err := store.GetSignalStats(ctx, []string{"DEV::Run"})
+		if err != nil {
+			t.Fatalf("GetSignalStats failed: %v", err)
+		}
+		if len(stats) != 1 || stats[0].Boolean == nil {
+			t.Fatalf("Expected boolean stats, got %+v", stats)
+		}
+		st := stats[0]
+		if st.Count != 4 || st.ChangeCount != 2 || *st.FirstChange != base.UnixMilli()+300 || *st.LastChange != base.UnixMilli()+400 {
+			t.Errorf("Unexpected change stats: %+v", st)
+		}
+		if st.Boolean.TrueTime != 900 || st.Boolean.TrueRatio != 0.9 {
+			t.Errorf("Expected 900ms true (ratio 0.9), got %+v", st.Boolean)
+		}
+		if st.Boolean.RisingEdges != 1 || st.Boolean.FallingEdges != 1 {
+			t.Errorf("Unexpected edges: %+v", st.Boolean)
+		}
+	})
+
+	t.Run("numeric", func(t *testing.T) {
+		stats, _ := store.GetSignalStats(ctx, []string{"DEV::Speed"})
+		if len(stats) != 1 || stats[0].Numeric == nil {
+			t.Fatalf("Expected numeric stats, got %+v", stats)
+		}
+		n := stats[0].Numeric
+		if n.Min != 10 || n.Max != 40 || n.Mean != 24 || n.P50 != 20 {
+			t.Errorf("Unexpected numeric stats: %+v", n)
+		}
+		if stats[0].ChangeCount != 3 || stats[0].Distinct != 4 {
+			t.Errorf("Expected 3 changes and 4 distinct values, got %+v", stats[0])
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		stats, _ := store.GetSignalStats(ctx, []string{"DEV::Carrier", "DEV::Missing"})
+		if len(stats) != 1 {
+			t.Fatalf("Expected unknown signals to be skipped, got %+v", stats)
+		}
+		st := stats[0]
+		if st.Distinct != 3 || len(st.TopValues) != 3 {
+			t.Fatalf("Expected 3 distinct values, got %+v", st)
+		}
+		if st.TopValues[0].Value != "A" || st.TopValues[0].Count != 3 {
+			t.Errorf("Expected A to be the top value, got %+v", st.TopValues[0])
+		}
+	})
+
+	t.Run("rejects malformed keys", func(t *testing.T) {
+		if _, err := store.GetSignalStats(ctx, []string{"Run"}); err == nil {
+			t.Error("Expected error for key without device")
+		}
+	})
+}
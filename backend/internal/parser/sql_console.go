@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+// SQL console limits.
+const (
+	DefaultSQLRowLimit = 1000
+	MaxSQLRowLimit     = 100000
+	DefaultSQLTimeout  = 30 * time.Second
+	MaxSQLTimeout      = 5 * time.Minute
+)
+
+// ErrQueryTimeout is returned when a console query exceeds its timeout.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// sqlViews are read-only views offered to console queries next to the entries table.
+// They are added as CTEs, so they work on read-only stores and cost nothing when unused.
+var sqlViews = map[string]string{
+	// signals: one row per signal with its sample count and time range
+	"signals": `SELECT device_id, signal, device_id || '::' || signal AS key, MODE(val_type) AS val_type,
+		COUNT(*) AS samples, MIN(timestamp) AS first_ts, MAX(timestamp) AS last_ts
+		FROM entries GROUP BY device_id, signal`,
+	// changes: entries whose value differs from the previous value of the same signal
+	"changes": `SELECT * EXCLUDE (rn) FROM (
+			SELECT id, timestamp, device_id, signal, category, val_type, val_bool, val_int, val_float, val_str,
+				` + valueTextExpr + ` AS value,
+				LAG(` + valueTextExpr + `) OVER w AS prev_value,
+				timestamp - LAG(timestamp) OVER w AS since_prev,
+				ROW_NUMBER() OVER w AS rn
+			FROM entries WINDOW w AS (PARTITION BY device_id, signal ORDER BY timestamp, id)
+		) WHERE rn = 1 OR value <> prev_value`,
+}
+
+// sqlTableFunctions are the table functions console queries may call; everything else
+// (read_csv, glob, query, pragma_*, ...) could reach outside the session.
+var sqlTableFunctions = map[string]bool{"range": true, "generate_series": true, "unnest": true}
+
+// sqlBlockedFunctions are scalar functions that expose the server environment or settings.
+var sqlBlockedFunctions = map[string]bool{"getenv": true, "current_setting": true, "read_blob": true, "read_text": true}
+
+// SQLQuery is a read-only console query. Limit caps the returned rows and Timeout (ms)
+// the run time; zero takes the defaults.
+type SQLQuery struct {
+	SQL     string `json:"sql"`
+	Limit   int    `json:"limit,omitempty"`
+	Timeout int64  `json:"timeout,omitempty"`
+}
+
+// SQLColumn describes a result column.
+type SQLColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SQLResult is a columnar query result: Data[i] holds the values of Columns[i].
+// Truncated is set when the query returned more rows than the limit.
+type SQLResult struct {
+	Columns   []SQLColumn     `json:"columns"`
+	Data      [][]interface{} `json:"data"`
+	RowCount  int             `json:"rowCount"`
+	Truncated bool            `json:"truncated"`
+	ElapsedMs int64           `json:"elapsedMs"`
+}
+
+// RunSQL runs a read-only SELECT against the entries table and the signals and changes views.
+// The statement is checked on DuckDB's parse tree: only a single SELECT reading the entries
+// table, the views, its own CTEs and the range, generate_series and unnest table functions
+// is accepted. Cancelling ctx interrupts the query.
+func (ds *DuckStore) RunSQL(ctx context.Context, q SQLQuery) (*SQLResult, error) {
+	limit, timeout := q.limits()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	query, err := ds.consoleQuery(ctx, q.SQL, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	rows, err := ds.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, sqlRunError(ctx, err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	result := &SQLResult{Columns: make([]SQLColumn, len(types)), Data: make([][]interface{}, len(types))}
+	for i, t := range types {
+		result.Columns[i] = SQLColumn{Name: t.Name(), Type: t.DatabaseTypeName()}
+		result.Data[i] = []interface{}{}
+	}
+
+	values := make([]interface{}, len(types))
+	dest := make([]interface{}, len(types))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if result.RowCount == limit {
+			result.Truncated = true
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			result.Data[i] = append(result.Data[i], jsonSQLValue(v))
+		}
+		result.RowCount++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqlRunError(ctx, err)
+	}
+	result.ElapsedMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// limits returns the effective row limit and timeout of the query.
+func (q SQLQuery) limits() (int, time.Duration) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSQLRowLimit
+	}
+	if limit > MaxSQLRowLimit {
+		limit = MaxSQLRowLimit
+	}
+	timeout := time.Duration(q.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = DefaultSQLTimeout
+	}
+	if timeout > MaxSQLTimeout {
+		timeout = MaxSQLTimeout
+	}
+	return limit, timeout
+}
+
+// consoleQuery checks a console statement and wraps it with the views it uses and a
+// limit of one row more than requested, so truncation can be detected.
+func (ds *DuckStore) consoleQuery(ctx context.Context, src string, limit int) (string, error) {
+	views, err := ds.checkSQL(ctx, src)
+	if err != nil {
+		return "", err
+	}
+
+	var ctes []string
+	for _, name := range views {
+		ctes = append(ctes, name+" AS ("+sqlViews[name]+")")
+	}
+	query := fmt.Sprintf("SELECT * FROM (\n%s\n) LIMIT %d", strings.TrimRight(strings.TrimSpace(src), ";"), limit+1)
+	if len(ctes) > 0 {
+		query = "WITH " + strings.Join(ctes, ", ") + "\n" + query
+	}
+	return query, nil
+}
+
+// checkSQL validates a console statement on its parse tree and returns the views it uses.
+func (ds *DuckStore) checkSQL(ctx context.Context, src string) ([]string, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	var serialized string
+	if err := ds.db.QueryRowContext(ctx, "SELECT CAST(json_serialize_sql(?::VARCHAR) AS VARCHAR)", src).Scan(&serialized); err != nil {
+		return nil, sqlRunError(ctx, err)
+	}
+	var tree struct {
+		Error      bool              `json:"error"`
+		Message    string            `json:"error_message"`
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal([]byte(serialized), &tree); err != nil {
+		return nil, fmt.Errorf("failed to read query parse tree: %w", err)
+	}
+	if tree.Error {
+		if strings.Contains(tree.Message, "Only SELECT") {
+			return nil, fmt.Errorf("%w: only SELECT statements are allowed", ErrInvalidQuery)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidQuery, tree.Message)
+	}
+	if len(tree.Statements) != 1 {
+		return nil, fmt.Errorf("%w: exactly one statement is allowed", ErrInvalidQuery)
+	}
+
+	var root interface{}
+	if err := json.Unmarshal(tree.Statements[0], &root); err != nil {
+		return nil, fmt.Errorf("failed to read query parse tree: %w", err)
+	}
+
+	// CTE names defined anywhere in the statement may be referenced like tables
+	ctes := make(map[string]bool)
+	walkSQLTree(root, func(node map[string]interface{}) error {
+		if m, ok := node["cte_map"].(map[string]interface{}); ok {
+			entries, _ := m["map"].([]interface{})
+			for _, e := range entries {
+				if kv, ok := e.(map[string]interface{}); ok {
+					if key, ok := kv["key"].(string); ok {
+						ctes[strings.ToLower(key)] = true
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	used := make(map[string]bool)
+	err := walkSQLTree(root, func(node map[string]interface{}) error {
+		switch node["type"] {
+		case "BASE_TABLE":
+			name := strings.ToLower(fmt.Sprint(node["table_name"]))
+			schema, _ := node["schema_name"].(string)
+			catalog, _ := node["catalog_name"].(string)
+			switch {
+			case schema != "" || catalog != "":
+				return fmt.Errorf("%w: table %s.%s is not allowed", ErrInvalidQuery, schema, name)
+			case ctes[name] || name == "entries":
+			case sqlViews[name] != "":
+				used[name] = true
+			default:
+				return fmt.Errorf("%w: unknown table %q (use entries, signals or changes)", ErrInvalidQuery, name)
+			}
+		case "FUNCTION":
+			name := strings.ToLower(fmt.Sprint(node["function_name"]))
+			if sqlBlockedFunctions[name] {
+				return fmt.Errorf("%w: function %s is not allowed", ErrInvalidQuery, name)
+			}
+		case "TABLE_FUNCTION":
+			fn, _ := node["function"].(map[string]interface{})
+			name := strings.ToLower(fmt.Sprint(fn["function_name"]))
+			if !sqlTableFunctions[name] {
+				return fmt.Errorf("%w: table function %s is not allowed", ErrInvalidQuery, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]string, 0, len(used))
+	for name := range used {
+		views = append(views, name)
+	}
+	sort.Strings(views)
+	return views, nil
+}
+
+// walkSQLTree calls visit for every object of a serialized parse tree.
+func walkSQLTree(node interface{}, visit func(map[string]interface{}) error) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if err := visit(n); err != nil {
+			return err
+		}
+		for _, child := range n {
+			if err := walkSQLTree(child, visit); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if err := walkSQLTree(child, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sqlRunError reports timeouts and cancellations distinctly. Parser and binder errors
+// (including unknown names, which DuckDB reports as catalog errors while binding) are
+// caused by the query text and count as invalid queries; other failures are returned
+// as they are.
+func sqlRunError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrQueryTimeout
+	case ctx.Err() != nil:
+		return ctx.Err()
+	}
+	var duckErr *duckdb.Error
+	if errors.As(err, &duckErr) {
+		switch duckErr.Type {
+		case duckdb.ErrorTypeParser, duckdb.ErrorTypeBinder, duckdb.ErrorTypeCatalog:
+			return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+	}
+	return fmt.Errorf("SQL query failed: %w", err)
+}
+
+// jsonSQLValue converts scanned DuckDB values to JSON encodable ones.
+func jsonSQLValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return nil
+		}
+	case duckdb.Decimal:
+		return x.Float64()
+	case time.Time:
+		return x.UnixMilli()
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = jsonSQLValue(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = jsonSQLValue(e)
+		}
+		return out
+	case duckdb.Map:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[fmt.Sprint(k)] = jsonSQLValue(e)
+		}
+		return out
+	}
+	return v
+}
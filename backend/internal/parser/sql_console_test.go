@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_RunSQL(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	store.AddEntry(createTestEntry("PLC", "Motor", base, false, ""))
+	store.AddEntry(createTestEntry("PLC", "Motor", base.Add(time.Second), true, ""))
+	store.AddEntry(createTestEntry("PLC", "Motor", base.Add(2*time.Second), true, ""))
+	store.AddEntry(createTestEntry("PLC", "Speed", base, 1.5, ""))
+	store.AddEntry(createTestEntry("PLC", "Speed", base.Add(time.Second), 2.5, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	res, err := store.RunSQL(ctx, SQLQuery{SQL: "SELECT signal, COUNT(*) AS n, AVG(val_float) AS avg FROM entries GROUP BY signal ORDER BY signal;"})
+	if err != nil {
+		t.Fatalf("RunSQL failed: %v", err)
+	}
+	if len(res.Columns) != 3 || res.Columns[1].Name != "n" || res.Columns[1].Type != "BIGINT" {
+		t.Fatalf("Unexpected columns %+v", res.Columns)
+	}
+	if res.RowCount != 2 || res.Truncated || res.Data[0][0] != "Motor" || res.Data[1][1] != int64(2) || res.Data[2][1] != 2.0 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+
+	res, err = store.RunSQL(ctx, SQLQuery{SQL: "SELECT signal, value FROM changes ORDER BY timestamp, signal", Limit: 3})
+	if err != nil {
+		t.Fatalf("RunSQL on changes failed: %v", err)
+	}
+	if res.RowCount != 3 || !res.Truncated {
+		t.Errorf("Expected 3 of 4 changes and truncation, got %+v", res)
+	}
+
+	res, err = store.RunSQL(ctx, SQLQuery{SQL: "WITH s AS (SELECT * FROM signals) SELECT samples FROM s WHERE signal = 'Motor'"})
+	if err != nil {
+		t.Fatalf("RunSQL with CTE failed: %v", err)
+	}
+	if res.RowCount != 1 || res.Data[0][0] != int64(3) {
+		t.Errorf("Expected 3 Motor samples, got %+v", res)
+	}
+
+	rejected := []string{
+		"",
+		"DELETE FROM entries",
+		"CREATE TABLE x AS SELECT 1",
+		"SELECT 1; SELECT 2",
+		"SELECT * FROM other_table",
+		"SELECT * FROM main.entries",
+		"SELECT * FROM read_csv('/etc/passwd')",
+		"SELECT * FROM '/etc/passwd'",
+		"SELECT * FROM entries WHERE signal IN (SELECT column0 FROM read_csv('x.csv'))",
+		"SELECT getenv('HOME')",
+		"SELECT nope FROM entries",
+	}
+	for _, sql := range rejected {
+		if _, err := store.RunSQL(ctx, SQLQuery{SQL: sql}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%q: expected ErrInvalidQuery, got %v", sql, err)
+		}
+	}
+
+	// Failures while running a valid query are not blamed on the query text
+	if _, err := store.RunSQL(ctx, SQLQuery{SQL: "SELECT CAST(signal || 'x' AS INTEGER) FROM entries"}); err == nil || errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected a runtime failure, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.RunSQL(cancelled, SQLQuery{SQL: "SELECT COUNT(*) FROM range(1000000000)"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := store.RunSQL(ctx, SQLQuery{SQL: "SELECT COUNT(*) FROM range(100000) a, range(100000) b", Timeout: 50}); !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Expected ErrQueryTimeout, got %v", err)
+	}
+}
@@ -0,0 +1,397 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Transition rule types (same model as the frontend Transition view)
+const (
+	TransitionRuleAToB           = "a-to-b"
+	TransitionRuleCycle          = "cycle"
+	TransitionRuleValuePopulated = "value-populated"
+)
+
+// Transition result statuses
+const (
+	TransitionStatusOK       = "ok"
+	TransitionStatusAbove    = "above"
+	TransitionStatusBelow    = "below"
+	TransitionStatusNoTarget = "no-target"
+)
+
+// TransitionRule defines how to measure a transition or cycle time.
+type TransitionRule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Enabled *bool  `json:"enabled,omitempty"` // nil means enabled
+
+	StartSignal    string        `json:"startSignal"`
+	StartCondition ConditionType `json:"startCondition"`
+	StartValue     interface{}   `json:"startValue"`
+
+	EndSignal    string        `json:"endSignal,omitempty"`
+	EndCondition ConditionType `json:"endCondition,omitempty"`
+	EndValue     interface{}   `json:"endValue,omitempty"`
+
+	TargetDuration *float64 `json:"targetDuration,omitempty"` // ms
+	Tolerance      *float64 `json:"tolerance,omitempty"`      // ms
+}
+
+// TransitionQuery selects the rules to evaluate and how to page and aggregate the results.
+type TransitionQuery struct {
+	Rules         []TransitionRule `json:"rules"`
+	Start         int64            `json:"start,omitempty"`  // Unix ms, 0 = unbounded
+	End           int64            `json:"end,omitempty"`    // Unix ms, 0 = unbounded
+	Status        string           `json:"status,omitempty"` // Only return results with this status
+	Page          int              `json:"page"`
+	PageSize      int              `json:"pageSize"`
+	BucketMinutes int              `json:"bucketMinutes,omitempty"` // Trend bucket size, 0 = no trends
+}
+
+// TransitionResult is one measured transition.
+type TransitionResult struct {
+	RuleID    string `json:"ruleId"`
+	RuleName  string `json:"ruleName"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	Duration  int64  `json:"duration"`
+	Status    string `json:"status"`
+}
+
+// TransitionStats summarizes all results of a rule.
+type TransitionStats struct {
+	RuleID       string  `json:"ruleId"`
+	RuleName     string  `json:"ruleName"`
+	Count        int     `json:"count"`
+	Min          float64 `json:"min"`
+	Max          float64 `json:"max"`
+	Average      float64 `json:"average"`
+	StdDev       float64 `json:"stdDev"`
+	WithinTarget int     `json:"withinTarget"`
+	AboveTarget  int     `json:"aboveTarget"`
+	BelowTarget  int     `json:"belowTarget"`
+}
+
+// TransitionTrendPoint aggregates the results of a rule within one time bucket.
+type TransitionTrendPoint struct {
+	RuleID      string  `json:"ruleId"`
+	BucketStart int64   `json:"bucketStart"`
+	Count       int     `json:"count"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Average     float64 `json:"average"`
+}
+
+// TransitionReport is the response of a transition analysis.
+type TransitionReport struct {
+	Results  []TransitionResult     `json:"results"`
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"pageSize"`
+	Stats    []TransitionStats      `json:"stats"`
+	Trends   []TransitionTrendPoint `json:"trends,omitempty"`
+}
+
+// GetTransitions evaluates transition rules inside DuckDB.
+// Each rule becomes a window-function query over the matching entries; the unioned
+// results are then paged, summarized per rule and optionally bucketed into trends.
+func (ds *DuckStore) GetTransitions(ctx context.Context, q TransitionQuery) (*TransitionReport, error) {
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	report := &TransitionReport{
+		Results:  []TransitionResult{},
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		Stats:    []TransitionStats{},
+	}
+
+	var rules []TransitionRule
+	for _, r := range q.Rules {
+		if r.Enabled == nil || *r.Enabled {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return report, nil
+	}
+
+	var parts []string
+	var args []interface{}
+	for i, rule := range rules {
+		part, partArgs, err := transitionPairsSQL(i, rule, q.Start, q.End)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %q: %v", ErrInvalidQuery, rule.Name, err)
+		}
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+
+	resultsCTE := fmt.Sprintf(`
+		WITH pairs AS (%s),
+		results AS (
+			SELECT rule_idx, start_ts, end_ts, end_ts - start_ts AS duration, %s AS status
+			FROM pairs
+		)
+	`, strings.Join(parts, " UNION ALL "), transitionStatusSQL(rules))
+
+	// Per-rule statistics
+	statsQuery := resultsCTE + `
+		SELECT rule_idx, COUNT(*), MIN(duration), MAX(duration), AVG(duration), COALESCE(STDDEV_POP(duration), 0),
+			COUNT(*) FILTER (WHERE status = 'ok'),
+			COUNT(*) FILTER (WHERE status = 'above'),
+			COUNT(*) FILTER (WHERE status = 'below')
+		FROM results GROUP BY rule_idx
+	`
+	rows, err := ds.db.QueryContext(ctx, statsQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("transition stats query failed: %w", err)
+	}
+	byRule := make(map[int]TransitionStats, len(rules))
+	for rows.Next() {
+		var idx int
+		var s TransitionStats
+		if err := rows.Scan(&idx, &s.Count, &s.Min, &s.Max, &s.Average, &s.StdDev,
+			&s.WithinTarget, &s.AboveTarget, &s.BelowTarget); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byRule[idx] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		s := byRule[i]
+		s.RuleID = rule.ID
+		s.RuleName = rule.Name
+		report.Stats = append(report.Stats, s)
+
+		switch q.Status {
+		case "":
+			report.Total += s.Count
+		case TransitionStatusOK:
+			report.Total += s.WithinTarget
+		case TransitionStatusAbove:
+			report.Total += s.AboveTarget
+		case TransitionStatusBelow:
+			report.Total += s.BelowTarget
+		case TransitionStatusNoTarget:
+			if rule.TargetDuration == nil {
+				report.Total += s.Count
+			}
+		}
+	}
+
+	// Paged results
+	if report.Total > 0 && q.PageSize > 0 {
+		pageQuery := resultsCTE + "SELECT rule_idx, start_ts, end_ts, duration, status FROM results"
+		pageArgs := append([]interface{}{}, args...)
+		if q.Status != "" {
+			pageQuery += " WHERE status = ?"
+			pageArgs = append(pageArgs, q.Status)
+		}
+		pageQuery += " ORDER BY start_ts, rule_idx LIMIT ? OFFSET ?"
+		pageArgs = append(pageArgs, q.PageSize, (q.Page-1)*q.PageSize)
+
+		rows, err := ds.db.QueryContext(ctx, pageQuery, pageArgs...)
+		if err != nil {
+			return nil, fmt.Errorf("transition results query failed: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var idx int
+			var r TransitionResult
+			if err := rows.Scan(&idx, &r.StartTime, &r.EndTime, &r.Duration, &r.Status); err != nil {
+				return nil, err
+			}
+			r.RuleID = rules[idx].ID
+			r.RuleName = rules[idx].Name
+			report.Results = append(report.Results, r)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Time-bucket trends
+	if q.BucketMinutes > 0 {
+		bucketMs := int64(q.BucketMinutes) * 60 * 1000
+		trendQuery := resultsCTE + fmt.Sprintf(`
+			SELECT rule_idx, (start_ts // %d) * %d AS bucket, COUNT(*), MIN(duration), MAX(duration), AVG(duration)
+			FROM results GROUP BY rule_idx, bucket ORDER BY rule_idx, bucket
+		`, bucketMs, bucketMs)
+
+		rows, err := ds.db.QueryContext(ctx, trendQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("transition trend query failed: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var idx int
+			var p TransitionTrendPoint
+			if err := rows.Scan(&idx, &p.BucketStart, &p.Count, &p.Min, &p.Max, &p.Average); err != nil {
+				return nil, err
+			}
+			p.RuleID = rules[idx].ID
+			report.Trends = append(report.Trends, p)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// transitionPairsSQL builds a subquery returning (rule_idx, start_ts, end_ts) for one rule.
+func transitionPairsSQL(idx int, rule TransitionRule, startMs, endMs int64) (string, []interface{}, error) {
+	if rule.StartSignal == "" {
+		return "", nil, fmt.Errorf("startSignal is required")
+	}
+
+	var timeClause string
+	var timeArgs []interface{}
+	if startMs > 0 {
+		timeClause += " AND timestamp >= ?"
+		timeArgs = append(timeArgs, startMs)
+	}
+	if endMs > 0 {
+		timeClause += " AND timestamp <= ?"
+		timeArgs = append(timeArgs, endMs)
+	}
+
+	switch rule.Type {
+	case TransitionRuleCycle:
+		sig, sigArgs := signalMatchSQL(rule.StartSignal)
+		cond, condArgs, err := conditionSQL(rule.StartCondition, rule.StartValue)
+		if err != nil {
+			return "", nil, err
+		}
+		// Every match closes the cycle opened by the previous match
+		query := fmt.Sprintf(`
+			SELECT %d AS rule_idx, start_ts, end_ts FROM (
+				SELECT LAG(timestamp) OVER (ORDER BY timestamp, id) AS start_ts, timestamp AS end_ts
+				FROM entries WHERE %s AND %s%s
+			) WHERE start_ts IS NOT NULL
+		`, idx, sig, cond, timeClause)
+		args := append(append(append([]interface{}{}, sigArgs...), condArgs...), timeArgs...)
+		return query, args, nil
+
+	case TransitionRuleAToB:
+		if rule.EndSignal == "" {
+			return "", nil, fmt.Errorf("endSignal is required")
+		}
+		return startEndPairsSQL(idx, rule.StartSignal, rule.StartCondition, rule.StartValue,
+			rule.EndSignal, rule.EndCondition, rule.EndValue, timeClause, timeArgs)
+
+	case TransitionRuleValuePopulated:
+		// From the first empty value to the first non-empty value that follows it
+		return startEndPairsSQL(idx, rule.StartSignal, ConditionEmpty, nil,
+			rule.StartSignal, ConditionNotEmpty, nil, timeClause, timeArgs)
+
+	default:
+		return "", nil, fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+}
+
+// startEndPairsSQL pairs start and end events the same way the frontend state machine does:
+// a start opens a transition (later starts are ignored while one is open) and the next end
+// closes it (ends are ignored while none is open).
+//
+// The open/closed state before each event is derived with window functions: start-only rows
+// always leave the state open and end-only rows always leave it closed, while rows matching
+// both toggle it. So the state before a row is the state left by the last start-only/end-only
+// row, flipped once per "both" row in between.
+func startEndPairsSQL(idx int, startSignal string, startCond ConditionType, startValue interface{},
+	endSignal string, endCond ConditionType, endValue interface{}, timeClause string, timeArgs []interface{}) (string, []interface{}, error) {
+
+	startSig, startSigArgs := signalMatchSQL(startSignal)
+	startCondSQL, startCondArgs, err := conditionSQL(startCond, startValue)
+	if err != nil {
+		return "", nil, err
+	}
+	endSig, endSigArgs := signalMatchSQL(endSignal)
+	endCondSQL, endCondArgs, err := conditionSQL(endCond, endValue)
+	if err != nil {
+		return "", nil, err
+	}
+
+	isStart := fmt.Sprintf("(%s AND %s)", startSig, startCondSQL)
+	isEnd := fmt.Sprintf("(%s AND %s)", endSig, endCondSQL)
+	startArgs := append(append([]interface{}{}, startSigArgs...), startCondArgs...)
+	endArgs := append(append([]interface{}{}, endSigArgs...), endCondArgs...)
+
+	query := fmt.Sprintf(`
+		SELECT %d AS rule_idx, start_ts, end_ts FROM (
+			SELECT role, LAG(timestamp) OVER (ORDER BY timestamp, id) AS start_ts, timestamp AS end_ts
+			FROM (
+				SELECT timestamp, id,
+					CASE WHEN is_s AND NOT open_before THEN 1 WHEN is_e AND open_before THEN 2 END AS role
+				FROM (
+					SELECT timestamp, id, is_s, is_e,
+						(COALESCE(last_fixed, 0) = 1) <> ((both_incl - is_both - COALESCE(both_at_fixed, 0)) %% 2 = 1) AS open_before
+					FROM (
+						SELECT timestamp, id, is_s, is_e, is_both, both_incl,
+							LAST_VALUE(fixed IGNORE NULLS) OVER prev AS last_fixed,
+							LAST_VALUE(CASE WHEN fixed IS NOT NULL THEN both_incl END IGNORE NULLS) OVER prev AS both_at_fixed
+						FROM (
+							SELECT timestamp, id, is_s, is_e,
+								CASE WHEN is_s AND NOT is_e THEN 1 WHEN is_e AND NOT is_s THEN 0 END AS fixed,
+								CASE WHEN is_s AND is_e THEN 1 ELSE 0 END AS is_both,
+								SUM(CASE WHEN is_s AND is_e THEN 1 ELSE 0 END) OVER (ORDER BY timestamp, id ROWS UNBOUNDED PRECEDING) AS both_incl
+							FROM (
+								SELECT timestamp, id, %s AS is_s, %s AS is_e
+								FROM entries WHERE (%s OR %s)%s
+							)
+						)
+						WINDOW prev AS (ORDER BY timestamp, id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING)
+					)
+				)
+			) WHERE role IS NOT NULL
+		) WHERE role = 2
+	`, idx, isStart, isEnd, isStart, isEnd, timeClause)
+
+	var args []interface{}
+	args = append(args, startArgs...)
+	args = append(args, endArgs...)
+	args = append(args, startArgs...)
+	args = append(args, endArgs...)
+	args = append(args, timeArgs...)
+	return query, args, nil
+}
+
+// transitionStatusSQL builds the CASE expression classifying durations against each rule's target.
+func transitionStatusSQL(rules []TransitionRule) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	hasTarget := false
+	for i, rule := range rules {
+		if rule.TargetDuration == nil {
+			continue
+		}
+		tol := 0.0
+		if rule.Tolerance != nil {
+			tol = *rule.Tolerance
+		}
+		lower := *rule.TargetDuration - tol
+		upper := *rule.TargetDuration + tol
+		hasTarget = true
+		fmt.Fprintf(&b, " WHEN rule_idx = %d THEN CASE WHEN duration BETWEEN %g AND %g THEN 'ok' WHEN duration > %g THEN 'above' ELSE 'below' END",
+			i, lower, upper, upper)
+	}
+	if !hasTarget {
+		return "'no-target'"
+	}
+	b.WriteString(" ELSE 'no-target' END")
+	return b.String()
+}
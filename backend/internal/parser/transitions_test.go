@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_GetTransitions(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	// Sensor goes ON, motor follows; a second ON while waiting is ignored
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(0), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(50), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Motor", at(100), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Motor", at(150), true, "")) // ignored: nothing open
+	store.AddEntry(createTestEntry("DEV", "Sensor", at(1000), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Motor", at(1300), true, ""))
+	// Carrier ID cleared then populated
+	store.AddEntry(createTestEntry("DEV", "CarrierID", at(200), "", ""))
+	store.AddEntry(createTestEntry("DEV", "CarrierID", at(250), "", ""))
+	store.AddEntry(createTestEntry("DEV", "CarrierID", at(400), "C001", ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	target := 100.0
+	tolerance := 10.0
+	ctx := context.Background()
+
+	t.Run("a-to-b", func(t *testing.T) {
+		report, err := store.GetTransitions(ctx, TransitionQuery{
+			Rules: []TransitionRule{{
+				ID: "r1", Name: "sensor to motor", Type: TransitionRuleAToB,
+				StartSignal: "DEV::Sensor", StartCondition: ConditionEquals, StartValue: "ON",
+				EndSignal: "DEV::Motor", EndCondition: ConditionEquals, EndValue: true,
+				TargetDuration: &target, Tolerance: &tolerance,
+			}},
+			Page: 1, PageSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("GetTransitions failed: %v", err)
+		}
+		if report.Total != 2 || len(report.Results) != 2 {
+			t.Fatalf("Expected 2 results, got total=%d results=%+v", report.Total, report.Results)
+		}
+		if report.Results[0].Duration != 100 || report.Results[0].Status != TransitionStatusOK {
+			t.Errorf("Expected first transition of 100ms within target, got %+v", report.Results[0])
+		}
+		if report.Results[1].Duration != 300 || report.Results[1].Status != TransitionStatusAbove {
+			t.Errorf("Expected second transition of 300ms above target, got %+v", report.Results[1])
+		}
+
+		stats := report.Stats[0]
+		if stats.Count != 2 || stats.Min != 100 || stats.Max != 300 || stats.Average != 200 || stats.StdDev != 100 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.WithinTarget != 1 || stats.AboveTarget != 1 {
+			t.Errorf("Unexpected target compliance: %+v", stats)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		report, err := store.GetTransitions(ctx, TransitionQuery{
+			Rules: []TransitionRule{{
+				ID: "c", Name: "sensor cycle", Type: TransitionRuleCycle,
+				StartSignal: "Sensor", StartCondition: ConditionEquals, StartValue: true,
+			}},
+			Page: 1, PageSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("GetTransitions failed: %v", err)
+		}
+		if report.Total != 2 {
+			t.Fatalf("Expected 2 cycles, got %d", report.Total)
+		}
+		if report.Results[0].Duration != 50 || report.Results[1].Duration != 950 {
+			t.Errorf("Unexpected cycle durations: %+v", report.Results)
+		}
+		if report.Results[0].Status != TransitionStatusNoTarget {
+			t.Errorf("Expected no-target status, got %s", report.Results[0].Status)
+		}
+	})
+
+	t.Run("value-populated", func(t *testing.T) {
+		report, err := store.GetTransitions(ctx, TransitionQuery{
+			Rules: []TransitionRule{{
+				ID: "v", Name: "carrier populated", Type: TransitionRuleValuePopulated,
+				StartSignal: "DEV::CarrierID",
+			}},
+			Page: 1, PageSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("GetTransitions failed: %v", err)
+		}
+		if report.Total != 1 || report.Results[0].Duration != 200 {
+			t.Errorf("Expected one 200ms result, got %+v", report.Results)
+		}
+	})
+
+	t.Run("paging, status filter and trends", func(t *testing.T) {
+		report, err := store.GetTransitions(ctx, TransitionQuery{
+			Rules: []TransitionRule{{
+				ID: "r1", Name: "sensor to motor", Type: TransitionRuleAToB,
+				StartSignal: "DEV::Sensor", StartCondition: ConditionEquals, StartValue: true,
+				EndSignal: "DEV::Motor", EndCondition: ConditionEquals, EndValue: true,
+				TargetDuration: &target, Tolerance: &tolerance,
+			}},
+			Status:        TransitionStatusAbove,
+			Page:          1,
+			PageSize:      1,
+			BucketMinutes: 1,
+		})
+		if err != nil {
+			t.Fatalf("GetTransitions failed: %v", err)
+		}
+		if report.Total != 1 || len(report.Results) != 1 || report.Results[0].Duration != 300 {
+			t.Errorf("Expected only the above-target result, got %+v", report.Results)
+		}
+		if len(report.Trends) == 0 {
+			t.Error("Expected trend points")
+		}
+	})
+
+	t.Run("rejects unknown rule type", func(t *testing.T) {
+		_, err := store.GetTransitions(ctx, TransitionQuery{
+			Rules: []TransitionRule{{Name: "bad", Type: "bogus", StartSignal: "DEV::Sensor"}},
+		})
+		if err == nil {
+			t.Error("Expected error for unknown rule type")
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// MaxWaveformWidth bounds the number of buckets per signal of a waveform query.
+const MaxWaveformWidth = 10000
+
+// Waveform is a level-of-detail view of signals over a time range, sized for a pixel width.
+// Values before Start and after End are not included; combine with GetBoundaryValues
+// to draw the segments leading into and out of the range.
+type Waveform struct {
+	Start    int64            `json:"start"`
+	End      int64            `json:"end"`
+	BucketMs int64            `json:"bucketMs"`
+	Signals  []SignalWaveform `json:"signals"`
+}
+
+// SignalWaveform holds the downsampled data of one signal.
+// Boolean signals are returned as Spans, all others as Buckets.
+type SignalWaveform struct {
+	Key        string            `json:"key"`
+	DeviceID   string            `json:"deviceId"`
+	SignalName string            `json:"signalName"`
+	SignalType models.SignalType `json:"signalType"`
+	Buckets    []WaveformBucket  `json:"buckets,omitempty"`
+	Spans      []WaveformSpan    `json:"spans,omitempty"`
+}
+
+// WaveformBucket summarizes the entries of a signal falling into one bucket.
+// Min and Max are only set for numeric signals. Transitions counts value changes
+// inside the range; the first entry of the range is not counted as a transition.
+type WaveformBucket struct {
+	Start       int64       `json:"start"`
+	FirstTs     int64       `json:"firstTs"`
+	LastTs      int64       `json:"lastTs"`
+	Count       int         `json:"count"`
+	Transitions int         `json:"transitions"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	First       interface{} `json:"first"`
+	Last        interface{} `json:"last"`
+}
+
+// WaveformSpan is a period in which a boolean signal holds a value.
+// Mixed spans cover buckets where the signal toggles faster than one pixel can show;
+// Value is then the state at the end of the span.
+type WaveformSpan struct {
+	Start       int64 `json:"start"`
+	End         int64 `json:"end"`
+	Value       bool  `json:"value"`
+	Mixed       bool  `json:"mixed,omitempty"`
+	Transitions int   `json:"transitions,omitempty"`
+
+	bucket int64
+}
+
+// waveformRow is one (signal, bucket) row of the waveform query.
+type waveformRow struct {
+	deviceID, signal string
+	valType          int
+	bucket           int64
+	count            int
+	transitions      int
+	firstInRange     bool
+	firstTs, lastTs  int64
+	firstChangeTs    int64
+	lastChangeTs     int64
+	min, max         sql.NullFloat64
+	firstNum         sql.NullFloat64
+	lastNum          sql.NullFloat64
+	firstText        string
+	lastText         string
+}
+
+// GetWaveform returns min/max/first/last buckets (and merged spans for booleans) for the given
+// signals in [startTs, endTs], using width buckets. An empty signal list selects all signals.
+func (ds *DuckStore) GetWaveform(ctx context.Context, startTs, endTs time.Time, width int, signals []string) (*Waveform, error) {
+	startMs := startTs.UnixMilli()
+	endMs := endTs.UnixMilli()
+	if endMs < startMs {
+		return nil, fmt.Errorf("%w: end must not be before start", ErrInvalidQuery)
+	}
+	if width < 1 || width > MaxWaveformWidth {
+		return nil, fmt.Errorf("%w: width must be between 1 and %d", ErrInvalidQuery, MaxWaveformWidth)
+	}
+
+	select {
+	case ds.querySem <- struct{}{}:
+		defer func() { <-ds.querySem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	bucketMs := (endMs - startMs + int64(width)) / int64(width)
+	if bucketMs < 1 {
+		bucketMs = 1
+	}
+
+	filter := "TRUE"
+	var filterArgs []interface{}
+	if len(signals) > 0 {
+		var clauses []string
+		for _, key := range signals {
+			clause, args := signalMatchSQL(key)
+			clauses = append(clauses, clause)
+			filterArgs = append(filterArgs, args...)
+		}
+		filter = "(" + strings.Join(clauses, " OR ") + ")"
+	}
+
+	query := fmt.Sprintf(`
+		WITH sel AS (
+			SELECT id, device_id, signal, timestamp, val_type, %s AS v, %s AS n,
+				(timestamp - ?) // ? AS bucket
+			FROM entries WHERE timestamp >= ? AND timestamp <= ? AND %s
+		), seq AS (
+			SELECT *, LAG(v) OVER (PARTITION BY device_id, signal ORDER BY timestamp, id) AS prev FROM sel
+		)
+		SELECT device_id, signal, MIN(val_type), bucket, COUNT(*),
+			COUNT(*) FILTER (WHERE prev IS NOT NULL AND v <> prev),
+			BOOL_OR(prev IS NULL),
+			MIN(timestamp), MAX(timestamp),
+			MIN(timestamp) FILTER (WHERE prev IS NULL OR v <> prev),
+			MAX(timestamp) FILTER (WHERE prev IS NULL OR v <> prev),
+			MIN(n), MAX(n),
+			FIRST(n ORDER BY timestamp, id), LAST(n ORDER BY timestamp, id),
+			FIRST(v ORDER BY timestamp, id), LAST(v ORDER BY timestamp, id)
+		FROM seq
+		GROUP BY device_id, signal, bucket
+		ORDER BY device_id, signal, bucket
+	`, valueTextExpr, valueNumExpr, filter)
+	args := append([]interface{}{startMs, bucketMs, startMs, endMs}, filterArgs...)
+
+	rows, err := ds.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("waveform query failed: %w", err)
+	}
+	defer rows.Close()
+
+	waveform := &Waveform{Start: startMs, End: endMs, BucketMs: bucketMs, Signals: []SignalWaveform{}}
+	var current *SignalWaveform
+	for rows.Next() {
+		var r waveformRow
+		var firstChangeTs, lastChangeTs sql.NullInt64
+		if err := rows.Scan(&r.deviceID, &r.signal, &r.valType, &r.bucket, &r.count, &r.transitions, &r.firstInRange,
+			&r.firstTs, &r.lastTs, &firstChangeTs, &lastChangeTs, &r.min, &r.max,
+			&r.firstNum, &r.lastNum, &r.firstText, &r.lastText); err != nil {
+			return nil, err
+		}
+		r.firstChangeTs = firstChangeTs.Int64
+		r.lastChangeTs = lastChangeTs.Int64
+
+		if current == nil || current.DeviceID != r.deviceID || current.SignalName != r.signal {
+			waveform.Signals = append(waveform.Signals, SignalWaveform{
+				Key:        r.deviceID + "::" + r.signal,
+				DeviceID:   r.deviceID,
+				SignalName: r.signal,
+				SignalType: valTypeToSignalType(r.valType),
+			})
+			current = &waveform.Signals[len(waveform.Signals)-1]
+		}
+
+		if r.valType == valTypeBool {
+			current.Spans = appendWaveformSpans(current.Spans, r)
+		} else {
+			current.Buckets = append(current.Buckets, waveformBucket(r, startMs, bucketMs))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range waveform.Signals {
+		if spans := waveform.Signals[i].Spans; len(spans) > 0 {
+			spans[len(spans)-1].End = endMs
+		}
+	}
+	sort.SliceStable(waveform.Signals, func(i, j int) bool { return waveform.Signals[i].Key < waveform.Signals[j].Key })
+	return waveform, nil
+}
+
+// waveformBucket converts a bucket row of a non-boolean signal.
+func waveformBucket(r waveformRow, startMs, bucketMs int64) WaveformBucket {
+	b := WaveformBucket{
+		Start:       startMs + r.bucket*bucketMs,
+		FirstTs:     r.firstTs,
+		LastTs:      r.lastTs,
+		Count:       r.count,
+		Transitions: r.transitions,
+	}
+	if (r.valType == valTypeInt || r.valType == valTypeFloat) && r.min.Valid {
+		b.Min = &r.min.Float64
+		b.Max = &r.max.Float64
+		b.First = r.firstNum.Float64
+		b.Last = r.lastNum.Float64
+	} else {
+		b.First = r.firstText
+		b.Last = r.lastText
+	}
+	return b
+}
+
+// appendWaveformSpans folds a bucket row of a boolean signal into the span list.
+// Each new span ends the previous one; adjacent mixed buckets merge into a single mixed span.
+func appendWaveformSpans(spans []WaveformSpan, r waveformRow) []WaveformSpan {
+	changes := r.transitions
+	if r.firstInRange {
+		changes++
+	}
+	last := r.lastText == "true"
+
+	switch {
+	case changes == 0:
+		return spans
+	case changes == 1:
+		return appendSpan(spans, WaveformSpan{Start: r.firstChangeTs, Value: last, bucket: r.bucket})
+	case changes == 2:
+		// Booleans alternate, so both change points are known exactly
+		spans = appendSpan(spans, WaveformSpan{Start: r.firstChangeTs, Value: !last, bucket: r.bucket})
+		return appendSpan(spans, WaveformSpan{Start: r.lastChangeTs, Value: last, bucket: r.bucket})
+	}
+
+	// Several changes in one bucket: drop the short clean span left by a mixed previous bucket
+	n := len(spans)
+	if n >= 2 && spans[n-2].Mixed && spans[n-2].bucket == r.bucket-1 && !spans[n-1].Mixed {
+		spans = spans[:n-1]
+		prev := &spans[n-2]
+		prev.Transitions += r.transitions
+		prev.Value = last
+		prev.bucket = r.bucket
+		prev.End = r.lastChangeTs
+	} else {
+		spans = appendSpan(spans, WaveformSpan{
+			Start: r.firstChangeTs, Value: last, Mixed: true, Transitions: r.transitions, bucket: r.bucket,
+		})
+		spans[len(spans)-1].End = r.lastChangeTs
+	}
+	return appendSpan(spans, WaveformSpan{Start: r.lastChangeTs, Value: last, bucket: r.bucket})
+}
+
+func appendSpan(spans []WaveformSpan, span WaveformSpan) []WaveformSpan {
+	if n := len(spans); n > 0 {
+		spans[n-1].End = span.Start
+	}
+	return append(spans, span)
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuckStore_GetWaveform(t *testing.T) {
+	store, cleanup := createTestStore(t)
+	defer cleanup()
+
+	base := time.UnixMilli(1_700_000_000_000)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	ms := func(off int) int64 { return base.UnixMilli() + int64(off) }
+
+	// Integer ramp with a spike in the first bucket
+	for i, v := range []int{1, 9, 2, 2, 3, 4, 5, 6, 7, 8} {
+		store.AddEntry(createTestEntry("DEV", "Level", at(i*100), v, ""))
+	}
+	// Boolean: clean change, then chattering across buckets 1-2, then steady
+	store.AddEntry(createTestEntry("DEV", "Run", at(0), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(50), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(150), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(210), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(230), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(260), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(280), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(410), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(420), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(430), false, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(440), true, ""))
+	store.AddEntry(createTestEntry("DEV", "Run", at(700), false, ""))
+	if err := store.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// 1000ms range over 5 pixels: 200ms buckets
+	wf, err := store.GetWaveform(ctx, at(0), at(999), 5, []string{"DEV::Level", "DEV::Run"})
+	if err != nil {
+		t.Fatalf("GetWaveform failed: %v", err)
+	}
+	if wf.BucketMs != 200 || len(wf.Signals) != 2 {
+		t.Fatalf("Expected 200ms buckets for 2 signals, got %d ms, %d signals", wf.BucketMs, len(wf.Signals))
+	}
+
+	t.Run("numeric buckets", func(t *testing.T) {
+		level := wf.Signals[0]
+		if level.Key != "DEV::Level" || len(level.Buckets) != 5 {
+			t.Fatalf("Expected 5 Level buckets, got %+v", level)
+		}
+		b := level.Buckets[0]
+		if *b.Min != 1 || *b.Max != 9 || b.First != 1.0 || b.Last != 9.0 || b.Count != 2 || b.Transitions != 1 {
+			t.Errorf("Unexpected first bucket: %+v", b)
+		}
+		if b := level.Buckets[1]; b.Transitions != 1 || b.Start != ms(200) {
+			t.Errorf("Expected 2->2 not to count as a transition, got %+v", b)
+		}
+	})
+
+	t.Run("boolean spans", func(t *testing.T) {
+		spans := wf.Signals[1].Spans
+		want := []WaveformSpan{
+			{Start: ms(0), End: ms(150), Value: false},
+			{Start: ms(150), End: ms(210), Value: true},
+			{Start: ms(210), End: ms(440), Value: true, Mixed: true, Transitions: 8},
+			{Start: ms(440), End: ms(700), Value: true},
+			{Start: ms(700), End: ms(999), Value: false},
+		}
+		if len(spans) != len(want) {
+			t.Fatalf("Expected %d spans, got %+v", len(want), spans)
+		}
+		for i, w := range want {
+			got := spans[i]
+			if got.Start != w.Start || got.End != w.End || got.Value != w.Value || got.Mixed != w.Mixed || got.Transitions != w.Transitions {
+				t.Errorf("Span %d: expected %+v, got %+v", i, w, got)
+			}
+		}
+	})
+
+	t.Run("rejects invalid width", func(t *testing.T) {
+		if _, err := store.GetWaveform(ctx, at(0), at(999), 0, nil); err == nil {
+			t.Error("Expected error for zero width")
+		}
+	})
+}
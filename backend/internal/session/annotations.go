@@ -0,0 +1,252 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// AnnotationFilter narrows an annotation listing.
+type AnnotationFilter struct {
+	Start  int64  // Unix ms, 0 = unbounded
+	End    int64  // Unix ms, 0 = unbounded
+	Tag    string // Only annotations carrying this tag
+	Author string // Only annotations by this author
+}
+
+func (f AnnotationFilter) matches(a *models.Annotation) bool {
+	start, end := f.Start, f.End
+	if end == 0 {
+		end = 1<<63 - 1
+	}
+	if !a.Overlaps(start, end) {
+		return false
+	}
+	if f.Tag != "" && !a.HasTag(f.Tag) {
+		return false
+	}
+	if f.Author != "" && a.Author != f.Author {
+		return false
+	}
+	return true
+}
+
+// AnnotationStore persists annotations per file ID as JSON files next to the parsed DuckDB files.
+// The parsed DuckDB is opened read-only once complete, so annotations live in a sidecar file
+// (annotations_<fileID>.json) instead of a table inside it.
+type AnnotationStore struct {
+	dir string
+	mu  sync.RWMutex
+	// cache holds loaded annotations per file ID (fileID -> annotations sorted by StartTime)
+	cache map[string][]*models.Annotation
+}
+
+// NewAnnotationStore creates an annotation store rooted at dir.
+func NewAnnotationStore(dir string) *AnnotationStore {
+	os.MkdirAll(dir, 0755)
+	return &AnnotationStore{
+		dir:   dir,
+		cache: make(map[string][]*models.Annotation),
+	}
+}
+
+func (as *AnnotationStore) path(fileID string) string {
+	return filepath.Join(as.dir, fmt.Sprintf("annotations_%s.json", fileID))
+}
+
+// load returns the annotations for a file, reading them from disk on first access.
+// Caller must hold as.mu (write lock).
+func (as *AnnotationStore) load(fileID string) ([]*models.Annotation, error) {
+	if list, ok := as.cache[fileID]; ok {
+		return list, nil
+	}
+
+	data, err := os.ReadFile(as.path(fileID))
+	if os.IsNotExist(err) {
+		as.cache[fileID] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+
+	var list []*models.Annotation
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode annotations: %w", err)
+	}
+	as.cache[fileID] = list
+	return list, nil
+}
+
+// save writes the annotations for a file to disk via a temp file and rename.
+// Caller must hold as.mu (write lock).
+func (as *AnnotationStore) save(fileID string, list []*models.Annotation) error {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].StartTime < list[j].StartTime
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode annotations: %w", err)
+	}
+
+	tmpPath := as.path(fileID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	if err := os.Rename(tmpPath, as.path(fileID)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+
+	as.cache[fileID] = list
+	return nil
+}
+
+// List returns the annotations for a file that match the filter, ordered by start time.
+func (as *AnnotationStore) List(fileID string, filter AnnotationFilter) ([]models.Annotation, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	list, err := as.load(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Annotation, 0, len(list))
+	for _, a := range list {
+		if filter.matches(a) {
+			result = append(result, *a)
+		}
+	}
+	return result, nil
+}
+
+// Get returns a single annotation.
+func (as *AnnotationStore) Get(fileID, annotationID string) (*models.Annotation, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	list, err := as.load(fileID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		if a.ID == annotationID {
+			copied := *a
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("annotation not found: %s", annotationID)
+}
+
+// Add stores a new annotation for a file and returns it with ID and timestamps filled in.
+func (as *AnnotationStore) Add(fileID string, a models.Annotation) (*models.Annotation, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	list, err := as.load(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	a.ID = uuid.New().String()
+	a.FileID = fileID
+	a.CreatedAt = now
+	a.UpdatedAt = now
+
+	stored := a
+	if err := as.save(fileID, append(list, &stored)); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Update replaces the editable fields of an existing annotation.
+func (as *AnnotationStore) Update(fileID, annotationID string, a models.Annotation) (*models.Annotation, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	list, err := as.load(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, existing := range list {
+		if existing.ID != annotationID {
+			continue
+		}
+		a.ID = existing.ID
+		a.FileID = fileID
+		a.CreatedAt = existing.CreatedAt
+		a.UpdatedAt = time.Now()
+
+		updated := make([]*models.Annotation, len(list))
+		copy(updated, list)
+		stored := a
+		updated[i] = &stored
+		if err := as.save(fileID, updated); err != nil {
+			return nil, err
+		}
+		return &a, nil
+	}
+	return nil, fmt.Errorf("annotation not found: %s", annotationID)
+}
+
+// Delete removes a single annotation.
+func (as *AnnotationStore) Delete(fileID, annotationID string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	list, err := as.load(fileID)
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range list {
+		if existing.ID != annotationID {
+			continue
+		}
+		updated := make([]*models.Annotation, 0, len(list)-1)
+		updated = append(updated, list[:i]...)
+		updated = append(updated, list[i+1:]...)
+		return as.save(fileID, updated)
+	}
+	return fmt.Errorf("annotation not found: %s", annotationID)
+}
+
+// FileIDs returns the IDs of the files that have an annotation file on disk.
+func (as *AnnotationStore) FileIDs() []string {
+	return sidecarFileIDs(as.dir, "annotations_")
+}
+
+// sidecarFileIDs returns the file IDs of the <prefix><fileID>.json files in dir.
+func sidecarFileIDs(dir, prefix string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, prefix+"*.json"))
+	ids := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name := filepath.Base(path)
+		ids = append(ids, name[len(prefix):len(name)-len(".json")])
+	}
+	return ids
+}
+
+// DeleteAll removes every annotation of a file (call when the file is deleted).
+func (as *AnnotationStore) DeleteAll(fileID string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	delete(as.cache, fileID)
+	if err := os.Remove(as.path(fileID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete annotations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+func TestAnnotationStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewAnnotationStore(dir)
+
+	point, err := store.Add("file-1", models.Annotation{
+		Kind:      models.AnnotationKindPoint,
+		StartTime: 2000,
+		Text:      "jam detected",
+		Author:    "alice",
+		Tags:      []string{"jam"},
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if point.ID == "" || point.FileID != "file-1" {
+		t.Fatalf("Expected ID and file ID to be set, got %+v", point)
+	}
+
+	if _, err := store.Add("file-1", models.Annotation{
+		Kind:      models.AnnotationKindRange,
+		StartTime: 500,
+		EndTime:   1500,
+		Text:      "warm-up",
+	}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	t.Run("lists sorted by start time", func(t *testing.T) {
+		list, err := store.List("file-1", AnnotationFilter{})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("Expected 2 annotations, got %d", len(list))
+		}
+		if list[0].StartTime != 500 || list[1].StartTime != 2000 {
+			t.Errorf("Expected annotations sorted by start time, got %d, %d", list[0].StartTime, list[1].StartTime)
+		}
+	})
+
+	t.Run("filters by time range overlap", func(t *testing.T) {
+		list, _ := store.List("file-1", AnnotationFilter{Start: 1000, End: 1200})
+		if len(list) != 1 || list[0].Text != "warm-up" {
+			t.Errorf("Expected only the overlapping range, got %+v", list)
+		}
+	})
+
+	t.Run("filters by tag", func(t *testing.T) {
+		list, _ := store.List("file-1", AnnotationFilter{Tag: "jam"})
+		if len(list) != 1 || list[0].ID != point.ID {
+			t.Errorf("Expected only the tagged annotation, got %+v", list)
+		}
+	})
+
+	t.Run("updates keep identity", func(t *testing.T) {
+		updated, err := store.Update("file-1", point.ID, models.Annotation{
+			Kind:      models.AnnotationKindPoint,
+			StartTime: 2500,
+			Text:      "jam cleared",
+		})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.ID != point.ID || !updated.CreatedAt.Equal(point.CreatedAt) {
+			t.Errorf("Expected ID and creation time to be preserved")
+		}
+	})
+
+	t.Run("persists across store instances", func(t *testing.T) {
+		reopened := NewAnnotationStore(dir)
+		got, err := reopened.Get("file-1", point.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Text != "jam cleared" || got.StartTime != 2500 {
+			t.Errorf("Expected updated annotation after reload, got %+v", got)
+		}
+	})
+
+	t.Run("deletes", func(t *testing.T) {
+		if err := store.Delete("file-1", point.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := store.Delete("file-1", point.ID); err == nil {
+			t.Error("Expected error deleting a missing annotation")
+		}
+		if err := store.DeleteAll("file-1"); err != nil {
+			t.Fatalf("DeleteAll failed: %v", err)
+		}
+		list, _ := NewAnnotationStore(dir).List("file-1", AnnotationFilter{})
+		if len(list) != 0 {
+			t.Errorf("Expected no annotations after DeleteAll, got %d", len(list))
+		}
+	})
+}
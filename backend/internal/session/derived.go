@@ -0,0 +1,134 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/plc-visualizer/backend/internal/parser"
+)
+
+// DerivedStore persists derived signal definitions per file ID as JSON files next to the
+// parsed DuckDB files (derived_<fileID>.json), so they are restored whenever the parsed file
+// is opened again. Like annotations they live in a sidecar because the parsed DuckDB is
+// opened read-only once complete.
+type DerivedStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewDerivedStore creates a derived signal store rooted at dir.
+func NewDerivedStore(dir string) *DerivedStore {
+	os.MkdirAll(dir, 0755)
+	return &DerivedStore{dir: dir}
+}
+
+func (ds *DerivedStore) path(fileID string) string {
+	return filepath.Join(ds.dir, fmt.Sprintf("derived_%s.json", fileID))
+}
+
+// load reads the definitions of a file. Caller must hold ds.mu.
+func (ds *DerivedStore) load(fileID string) ([]parser.DerivedSignal, error) {
+	data, err := os.ReadFile(ds.path(fileID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read derived signals: %w", err)
+	}
+	var defs []parser.DerivedSignal
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("failed to decode derived signals: %w", err)
+	}
+	return defs, nil
+}
+
+// save writes the definitions of a file via a temp file and rename, removing the file
+// once no definitions are left. Caller must hold ds.mu.
+func (ds *DerivedStore) save(fileID string, defs []parser.DerivedSignal) error {
+	if len(defs) == 0 {
+		if err := os.Remove(ds.path(fileID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to write derived signals: %w", err)
+		}
+		return nil
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Key() < defs[j].Key() })
+
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode derived signals: %w", err)
+	}
+	tmpPath := ds.path(fileID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write derived signals: %w", err)
+	}
+	if err := os.Rename(tmpPath, ds.path(fileID)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write derived signals: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved definitions of a file sorted by key.
+func (ds *DerivedStore) List(fileID string) ([]parser.DerivedSignal, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.load(fileID)
+}
+
+// Put saves a definition of a file, replacing one with the same key.
+func (ds *DerivedStore) Put(fileID string, def parser.DerivedSignal) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	defs, err := ds.load(fileID)
+	if err != nil {
+		return err
+	}
+	for i, existing := range defs {
+		if existing.Key() == def.Key() {
+			defs[i] = def
+			return ds.save(fileID, defs)
+		}
+	}
+	return ds.save(fileID, append(defs, def))
+}
+
+// Remove deletes the saved definition of a file with the given key, if any.
+func (ds *DerivedStore) Remove(fileID, key string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	defs, err := ds.load(fileID)
+	if err != nil {
+		return err
+	}
+	kept := defs[:0]
+	for _, def := range defs {
+		if def.Key() != key {
+			kept = append(kept, def)
+		}
+	}
+	if len(kept) == len(defs) {
+		return nil
+	}
+	return ds.save(fileID, kept)
+}
+
+// FileIDs returns the IDs of the files that have saved definitions.
+func (ds *DerivedStore) FileIDs() []string {
+	return sidecarFileIDs(ds.dir, "derived_")
+}
+
+// DeleteAll removes every definition of a file (call when the file is deleted).
+func (ds *DerivedStore) DeleteAll(fileID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if err := os.Remove(ds.path(fileID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete derived signals: %w", err)
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package session
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,15 +17,30 @@ import (
 	"github.com/plc-visualizer/backend/internal/parser"
 )
 
-// MaxSessions limits concurrent sessions to prevent memory exhaustion
+// MaxSessions is the default limit of sessions kept to prevent memory exhaustion
 const MaxSessions = 10
 
+// DefaultMaxConcurrentParses is the default number of parses running at once
+const DefaultMaxConcurrentParses = 3
+
 // SessionMaxAge is how long to keep completed sessions before cleanup
 const SessionMaxAge = 30 * time.Minute
 
 // SessionKeepAliveWindow is how long to keep sessions that are actively being used
 const SessionKeepAliveWindow = 5 * time.Minute
 
+// ExportProgressRetention is how long the progress of a finished export stays queryable
+const ExportProgressRetention = time.Minute
+
+// Errors returned by session lookups that need to be told apart by callers.
+var (
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrSessionNotReady       = errors.New("session has no queryable data yet")
+	ErrDerivedSignalNotFound = errors.New("derived signal not found")
+	ErrQueryNotFound         = errors.New("query not found")
+	ErrSessionState          = errors.New("not possible in the current session state")
+)
+
 // Manager handles active log parsing sessions.
 type Manager struct {
 	sessions    map[string]*SessionState
@@ -29,6 +48,55 @@ type Manager struct {
 	registry    *parser.Registry
 	tempDir     string
 	parsedStore *PersistentParsedStore
+	annotations *AnnotationStore
+	presets     *PresetStore
+	derived     *DerivedStore
+
+	// autoAnomalies runs anomaly detection in the background once a parse is finalized
+	autoAnomalies bool
+	// searchIndex builds the search index in Finalize instead of on the first word search
+	searchIndex bool
+
+	// sqlQueries holds the cancel functions of running SQL console queries by query id
+	sqlQueries map[string]context.CancelFunc
+	sqlMu      sync.Mutex
+
+	// exports tracks the progress of entries exports by session and export id
+	exports  map[string]*models.ExportProgress
+	exportMu sync.Mutex
+
+	// subscribers are woken when the progress or committed entries of a session change
+	subscribers map[string]map[chan struct{}]struct{}
+	subMu       sync.Mutex
+
+	// Parse scheduling, guarded by mu: at most maxParses parses run at once, the others
+	// wait in queue. maxSessions bounds the sessions kept before evicting finished ones.
+	// opening counts started parses whose DuckStore is not open yet, so the budget is not
+	// promised to more parses than it can admit.
+	queue       []*parseJob
+	running     int
+	opening     int
+	jobSeq      uint64
+	maxParses   int
+	maxSessions int
+}
+
+// parseJob is a parse waiting for a slot. Jobs run by descending priority, then in
+// submission order. A resume job continues a paused parse instead of starting one.
+type parseJob struct {
+	sessionID string
+	priority  int
+	seq       uint64
+	run       func()
+	resume    bool
+}
+
+// parseSlot is the slot of a started parse. Pausing gives it up and resuming takes
+// one again, so held tells whether the parse still counts as running, and opening
+// whether it still counts against the DuckDB budget before its store is open.
+type parseSlot struct {
+	held    bool
+	opening bool
 }
 
 // SessionState holds the session metadata and the DuckDB-backed storage.
@@ -36,7 +104,21 @@ type SessionState struct {
 	Session      *models.ParseSession
 	Result       *models.ParsedLog // Legacy: used for backward compatibility with non-DuckDB parsers
 	DuckStore    *parser.DuckStore // Memory-efficient storage for large files
+	Live         *parser.DuckStore // Store being filled by a running parse; its committed entries are readable
 	LastAccessed time.Time         // Last time the session was accessed (for keep-alive)
+
+	cancel context.CancelFunc // Cancels the running parse
+	pause  *parser.PauseGate  // Pauses the running parse at its next checkpoint
+	slot   *parseSlot         // Slot of the started parse
+}
+
+// newParseContext returns the context of a session's parse goroutine, cancelled by
+// CancelSession and paused by PauseSession.
+func (state *SessionState) newParseContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancel = cancel
+	state.pause = parser.NewPauseGate()
+	return parser.WithPauseGate(ctx, state.pause)
 }
 
 // NewManager creates a new session manager.
@@ -53,45 +135,126 @@ func NewManager() *Manager {
 
 // NewManagerWithTempDir creates a session manager with a specific temp directory.
 func NewManagerWithTempDir(tempDir string) *Manager {
+	parsedStore := NewPersistentParsedStore()
 	return &Manager{
 		sessions:    make(map[string]*SessionState),
 		registry:    parser.GetGlobalRegistry(),
 		tempDir:     tempDir,
-		parsedStore: NewPersistentParsedStore(),
+		parsedStore: parsedStore,
+		annotations: NewAnnotationStore(parsedStore.parsedDir),
+		presets:     NewPresetStore(parsedStore.parsedDir),
+		derived:     NewDerivedStore(parsedStore.parsedDir),
+		sqlQueries:  make(map[string]context.CancelFunc),
+		exports:     make(map[string]*models.ExportProgress),
+		subscribers: make(map[string]map[chan struct{}]struct{}),
+		maxParses:   DefaultMaxConcurrentParses,
+		maxSessions: MaxSessions,
+	}
+}
+
+// SetMaxConcurrentParses sets how many parses may run at once; further sessions are queued.
+// Values below 1 select DefaultMaxConcurrentParses.
+func (m *Manager) SetMaxConcurrentParses(n int) {
+	if n < 1 {
+		n = DefaultMaxConcurrentParses
+	}
+	m.mu.Lock()
+	m.maxParses = n
+	m.dispatchLocked()
+	m.mu.Unlock()
+}
+
+// SetMaxSessions sets how many sessions are kept before the least recently accessed
+// finished sessions are evicted. Values below 1 select MaxSessions.
+func (m *Manager) SetMaxSessions(n int) {
+	if n < 1 {
+		n = MaxSessions
+	}
+	m.mu.Lock()
+	m.maxSessions = n
+	m.mu.Unlock()
+}
+
+// SetAutoDetectAnomalies enables anomaly detection with the default thresholds after each parse.
+func (m *Manager) SetAutoDetectAnomalies(enabled bool) {
+	m.mu.Lock()
+	m.autoAnomalies = enabled
+	m.mu.Unlock()
+}
+
+// SetBuildSearchIndex builds the search index of new parses in Finalize rather than on first use.
+func (m *Manager) SetBuildSearchIndex(enabled bool) {
+	m.mu.Lock()
+	m.searchIndex = enabled
+	m.mu.Unlock()
+}
+
+// detectAnomaliesAsync warms the anomaly cache of a freshly parsed or reopened store.
+// Must be called with m.mu held.
+func (m *Manager) detectAnomaliesAsync(sessionID string, store *parser.DuckStore) {
+	if !m.autoAnomalies || !store.Acquire() {
+		return
 	}
+	go func() {
+		defer store.Release()
+		start := time.Now()
+		anomalies, err := store.DetectAnomalies(context.Background(), parser.AnomalyConfig{})
+		if err != nil {
+			fmt.Printf("[Session %s] Anomaly detection failed: %v\n", shortID(sessionID), err)
+			return
+		}
+		fmt.Printf("[Session %s] Detected %d anomalies in %d ms\n", shortID(sessionID), len(anomalies), time.Since(start).Milliseconds())
+	}()
 }
 
-// StartSession begins the parsing process for a file.
-// If the file has already been parsed and stored persistently, it will be loaded instantly.
-func (m *Manager) StartSession(fileID, filePath string) (*models.ParseSession, error) {
-	// Clean up old sessions if at limit
-	m.cleanupOldSessionsIfNeeded()
+// StartSession begins the parsing process for a file; queued parses of higher priority
+// start first. If the file has already been parsed and stored persistently, it will be
+// loaded instantly.
+func (m *Manager) StartSession(fileID, filePath string, priority int) (*models.ParseSession, error) {
+	// Loading an already parsed file opens its store right away, so it is refused if the
+	// DuckDB budget is used up; parses wait in the queue for the budget instead
+	parsed := m.parsedStore.IsParsed(fileID)
+	if err := m.cleanupOldSessionsIfNeeded(parsed); err != nil {
+		return nil, err
+	}
 
 	sessionID := uuid.New().String()
 
 	session := models.NewParseSession(sessionID, fileID)
-	session.Status = models.SessionStatusParsing
+	session.Priority = priority
 
 	state := &SessionState{
 		Session:      session,
 		LastAccessed: time.Now(),
 	}
+	ctx := state.newParseContext()
 
 	m.mu.Lock()
 	m.sessions[sessionID] = state
 	m.mu.Unlock()
 
-	// Check if this file has already been parsed and stored persistently
-	if m.parsedStore.IsParsed(fileID) {
+	// Check if this file has already been parsed and stored persistently; loading it is
+	// quick, so it does not wait for a parse slot
+	if parsed {
+		m.mu.Lock()
+		session.Status = models.SessionStatusParsing
+		m.mu.Unlock()
 		fmt.Printf("[Session %s] File %s already parsed! Loading from persistent storage...\n",
 			shortID(sessionID), shortID(fileID))
 		go m.loadFromPersistentStore(sessionID, fileID)
 	} else {
-		// Run parsing in a background goroutine
-		go m.runParse(sessionID, filePath, fileID)
+		// Parse in a background goroutine once a parse slot is free
+		m.enqueueParse(sessionID, func() { m.runParse(ctx, sessionID, filePath, fileID) })
 	}
 
-	return session, nil
+	return m.sessionSnapshot(session), nil
+}
+
+// sessionSnapshot copies a session under m.mu, since parse goroutines keep updating it.
+func (m *Manager) sessionSnapshot(session *models.ParseSession) *models.ParseSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return session.Clone()
 }
 
 // closeExistingStoresForFile closes DuckStore connections held by other sessions
@@ -137,16 +300,18 @@ func (m *Manager) loadFromPersistentStore(sessionID, fileID string) {
 		return
 	}
 
+	m.restoreDerivedSignals(fileID, store)
 	elapsed := time.Since(start).Milliseconds()
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	state, ok := m.sessions[sessionID]
-	if !ok {
+	if !ok || state.Session.Status == models.SessionStatusCancelled {
 		store.Close()
 		return
 	}
+	defer m.notifySession(sessionID)
 
 	state.DuckStore = store
 	state.Session.Status = models.SessionStatusComplete
@@ -161,11 +326,14 @@ func (m *Manager) loadFromPersistentStore(sessionID, fileID string) {
 		state.Session.EndTime = tr.End.UnixMilli()
 	}
 
+	// Detected anomalies are cached per open store, so a reopened file is scanned again
+	m.detectAnomaliesAsync(sessionID, store)
+
 	fmt.Printf("[Session %s] Loaded from persistent store in %d ms: %d entries, %d signals\n",
 		sessionID[:8], elapsed, store.Len(), len(store.GetSignals()))
 }
 
-func (m *Manager) runParse(sessionID, filePath, fileID string) {
+func (m *Manager) runParse(ctx context.Context, sessionID, filePath, fileID string) {
 	// Recover from panics to prevent backend crash
 	defer func() {
 		if r := recover(); r != nil {
@@ -176,6 +344,10 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 		parser.ResetGlobalIntern()
 	}()
 
+	if ctx.Err() != nil {
+		return // Cancelled before it started
+	}
+
 	start := time.Now()
 	fmt.Printf("[Parse %s] Starting parse of %s\n", sessionID[:8], filePath)
 
@@ -198,7 +370,9 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 	m.mu.Lock()
 	if state, ok := m.sessions[sessionID]; ok {
 		state.Session.Progress = 10
-		state.Session.Status = models.SessionStatusParsing
+		if state.Session.Status == models.SessionStatusPending {
+			state.Session.Status = models.SessionStatusParsing
+		}
 	}
 	m.mu.Unlock()
 
@@ -224,6 +398,7 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 			state.Session.EntryCount = lines
 		}
 		m.mu.Unlock()
+		m.notifySession(sessionID)
 
 		// Log memory usage every 500K lines
 		if lines%500000 == 0 {
@@ -245,12 +420,16 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 
 	// Try DuckDB-backed parsing for memory efficiency
 	if plcParser, ok := p.(*parser.PLCDebugParser); ok {
-		m.runParseToDuckStore(sessionID, filePath, fileID, plcParser, progressCb, start)
+		m.runParseToDuckStore(ctx, sessionID, filePath, fileID, plcParser, progressCb, start)
 		return
 	}
 
 	// Fallback to legacy in-memory parsing for other parsers
-	result, parseErrors, err := p.ParseWithProgress(filePath, progressCb)
+	result, parseErrors, err := p.ParseWithProgress(ctx, filePath, progressCb)
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("[Parse %s] Cancelled\n", sessionID[:8])
+		return
+	}
 	if err != nil {
 		fmt.Printf("[Parse %s] ERROR: parse failed: %v\n", sessionID[:8], err)
 		m.updateSessionError(sessionID, fmt.Sprintf("parse failed: %v", err))
@@ -265,9 +444,10 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 	defer m.mu.Unlock()
 
 	state, ok := m.sessions[sessionID]
-	if !ok {
+	if !ok || state.Session.Status == models.SessionStatusCancelled {
 		return
 	}
+	defer m.notifySession(sessionID)
 
 	state.Result = result
 	state.Session.Status = models.SessionStatusComplete
@@ -292,7 +472,7 @@ func (m *Manager) runParse(sessionID, filePath, fileID string) {
 }
 
 // runParseToDuckStore handles DuckDB-backed parsing for memory efficiency
-func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *parser.PLCDebugParser, progressCb parser.ProgressCallback, start time.Time) {
+func (m *Manager) runParseToDuckStore(ctx context.Context, sessionID, filePath, fileID string, p *parser.PLCDebugParser, progressCb parser.ProgressCallback, start time.Time) {
 	// Recover from panics to prevent backend crash
 	defer func() {
 		if r := recover(); r != nil {
@@ -304,18 +484,28 @@ func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *par
 	// Create persistent DuckStore for this file
 	fmt.Printf("[Parse %s] Creating persistent DuckDB store for file %s...\n", shortID(sessionID), shortID(fileID))
 	store, err := m.parsedStore.CreateForFile(fileID)
+	m.storeOpened(sessionID)
 	if err != nil {
 		fmt.Printf("[Parse %s] ERROR: failed to create DuckStore: %v\n", sessionID[:8], err)
 		m.updateSessionError(sessionID, fmt.Sprintf("failed to create storage: %v", err))
 		return
 	}
+	m.mu.RLock()
+	store.SetSearchIndexOnFinalize(m.searchIndex)
+	m.mu.RUnlock()
+	m.streamCommits(sessionID, store)
 	fmt.Printf("[Parse %s] DuckDB store created, starting parse...\n", sessionID[:8])
 
 	// Parse directly to DuckStore
-	parseErrors, err := p.ParseToDuckStore(filePath, store, progressCb)
+	parseErrors, err := p.ParseToDuckStore(ctx, filePath, store, progressCb)
 	if err != nil {
+		m.endLive(sessionID)
 		store.Close()
-		m.parsedStore.Delete(fileID) // Clean up on failure
+		m.parsedStore.Delete(fileID) // Clean up the partial store on failure or cancellation
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("[Parse %s] Cancelled, partial store removed\n", sessionID[:8])
+			return
+		}
 		fmt.Printf("[Parse %s] ERROR: parse failed: %v\n", sessionID[:8], err)
 		m.updateSessionError(sessionID, fmt.Sprintf("parse failed: %v", err))
 		return
@@ -326,6 +516,7 @@ func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *par
 	// Mark as successfully parsed for future reuse
 	m.parsedStore.MarkComplete(fileID)
 	store.SetPersistent(true) // Don't delete the persistent DB file on session cleanup
+	m.restoreDerivedSignals(fileID, store)
 
 	elapsed := time.Since(start).Milliseconds()
 
@@ -333,12 +524,18 @@ func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *par
 	defer m.mu.Unlock()
 
 	state, ok := m.sessions[sessionID]
-	if !ok {
+	if !ok || state.Session.Status == models.SessionStatusCancelled {
+		// Cancelled after the parse had finished; a persistent store stays cached for reuse
+		if ok {
+			state.Live = nil
+		}
 		store.Close()
 		return
 	}
+	defer m.notifySession(sessionID)
 
 	state.DuckStore = store
+	state.Live = nil
 	state.Session.Status = models.SessionStatusComplete
 	state.Session.Progress = 100
 	state.Session.EntryCount = store.Len()
@@ -351,6 +548,8 @@ func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *par
 		state.Session.EndTime = tr.End.UnixMilli()
 	}
 
+	m.detectAnomaliesAsync(sessionID, store)
+
 	errs := make([]models.ParseError, 0, len(parseErrors))
 	for _, e := range parseErrors {
 		if e != nil {
@@ -361,11 +560,12 @@ func (m *Manager) runParseToDuckStore(sessionID, filePath, fileID string, p *par
 }
 
 func (m *Manager) updateSessionError(sessionID, reason string) {
+	defer m.notifySession(sessionID)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	state, ok := m.sessions[sessionID]
-	if !ok {
+	if !ok || state.Session.Status == models.SessionStatusCancelled {
 		return
 	}
 
@@ -375,41 +575,57 @@ func (m *Manager) updateSessionError(sessionID, reason string) {
 	})
 }
 
-// cleanupOldSessionsIfNeeded removes oldest completed sessions if at capacity
-func (m *Manager) cleanupOldSessionsIfNeeded() {
+// cleanupOldSessionsIfNeeded evicts the least recently accessed finished sessions while
+// at capacity and, if needStore, while the DuckDB budget cannot admit another store. It
+// returns parser.ErrResourceBudget if the store cannot be opened even after evicting.
+func (m *Manager) cleanupOldSessionsIfNeeded(needStore bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if len(m.sessions) < MaxSessions {
+	m.evictLocked(func() bool {
+		return len(m.sessions) < m.maxSessions && (!needStore || m.storeRoomLocked())
+	})
+	if needStore && !m.storeRoomLocked() {
+		return parser.ErrResourceBudget
+	}
+	return nil
+}
+
+// storeRoomLocked reports whether the DuckDB budget can admit another store besides those
+// of started parses that have not opened theirs yet. m.mu must be held.
+func (m *Manager) storeRoomLocked() bool {
+	return parser.StoreHeadroom() > m.opening
+}
+
+// evictLocked closes the least recently accessed finished sessions that are not in use
+// until enough reports true. m.mu must be held.
+func (m *Manager) evictLocked(enough func() bool) {
+	if enough() {
 		return
 	}
 
-	// Find oldest completed/error sessions
-	var toDelete []string
+	var finished []string
 	for id, state := range m.sessions {
-		if state.Session.Status == models.SessionStatusComplete ||
-			state.Session.Status == models.SessionStatusError {
-			toDelete = append(toDelete, id)
+		if state.Session.Status.Finished() && (state.DuckStore == nil || !state.DuckStore.InUse()) {
+			finished = append(finished, id)
 		}
 	}
+	sort.Slice(finished, func(i, j int) bool {
+		return m.sessions[finished[i]].LastAccessed.Before(m.sessions[finished[j]].LastAccessed)
+	})
 
-	// Sort by completion time (we don't track this, so just delete oldest by map order)
-	// Delete enough to get below limit
-	toFree := len(m.sessions) - MaxSessions + 1
-	deleted := 0
-	for _, id := range toDelete {
-		if deleted >= toFree {
+	// Delete enough to get below limit and within the budget
+	for _, id := range finished {
+		if enough() {
 			break
 		}
-		if state, ok := m.sessions[id]; ok {
-			// Close DuckStore to free resources
-			if state.DuckStore != nil {
-				state.DuckStore.Close()
-			}
-			delete(m.sessions, id)
-			deleted++
-			fmt.Printf("[Manager] Cleaned up old session %s to free memory\n", id[:8])
+		state := m.sessions[id]
+		// Close DuckStore to free resources
+		if state.DuckStore != nil {
+			state.DuckStore.Close()
 		}
+		delete(m.sessions, id)
+		fmt.Printf("[Manager] Evicted least recently used session %s to free memory\n", shortID(id))
 	}
 }
 
@@ -423,14 +639,13 @@ func (m *Manager) CleanupOldSessions(maxAge time.Duration) {
 	keepAliveCutoff := time.Now().Add(-SessionKeepAliveWindow)
 
 	for id, state := range m.sessions {
-		// Only clean up completed/error sessions
-		if state.Session.Status != models.SessionStatusComplete &&
-			state.Session.Status != models.SessionStatusError {
+		// Only clean up finished sessions
+		if !state.Session.Status.Finished() {
 			continue
 		}
 
 		// Don't clean up sessions that are actively being used
-		if state.LastAccessed.After(keepAliveCutoff) {
+		if state.LastAccessed.After(keepAliveCutoff) || (state.DuckStore != nil && state.DuckStore.InUse()) {
 			continue
 		}
 
@@ -451,9 +666,10 @@ func (m *Manager) CleanupOldSessions(maxAge time.Duration) {
 				id[:8], time.Since(state.LastAccessed).Round(time.Second))
 		}
 	}
+	m.dispatchLocked() // Queued parses may fit in the budget now
 }
 
-// GetSession returns a session by ID.
+// GetSession returns a copy of a session by ID.
 func (m *Manager) GetSession(id string) (*models.ParseSession, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -462,7 +678,7 @@ func (m *Manager) GetSession(id string) (*models.ParseSession, bool) {
 	if !ok {
 		return nil, false
 	}
-	return state.Session, true
+	return state.Session.Clone(), true
 }
 
 // TouchSession updates the LastAccessed timestamp for a session.
@@ -480,6 +696,369 @@ func (m *Manager) TouchSession(id string) bool {
 	return true
 }
 
+// enqueueParse queues run as the parse of a session and starts it when a slot is free.
+func (m *Manager) enqueueParse(sessionID string, run func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sessions[sessionID]
+	if !ok {
+		return
+	}
+	m.jobSeq++
+	m.queue = append(m.queue, &parseJob{sessionID: sessionID, priority: state.Session.Priority, seq: m.jobSeq, run: run})
+	m.dispatchLocked()
+}
+
+// dispatchLocked starts queued parses while slots are free and renumbers the queue
+// positions of those still waiting. A new parse also needs room in the DuckDB budget,
+// evicting finished sessions if necessary; otherwise it stays at the head of the queue
+// until a store is closed. m.mu must be held.
+func (m *Manager) dispatchLocked() {
+	sort.SliceStable(m.queue, func(i, j int) bool {
+		if m.queue[i].priority != m.queue[j].priority {
+			return m.queue[i].priority > m.queue[j].priority
+		}
+		return m.queue[i].seq < m.queue[j].seq
+	})
+
+	for m.running < m.maxParses && len(m.queue) > 0 {
+		job := m.queue[0]
+		m.queue = m.queue[1:]
+		state, ok := m.sessions[job.sessionID]
+		if !ok {
+			continue
+		}
+		if job.resume {
+			if state.Session.Status != models.SessionStatusPaused || state.slot == nil {
+				continue
+			}
+			state.slot.held = true
+			state.pause.Resume()
+		} else {
+			if state.Session.Status != models.SessionStatusPending {
+				continue
+			}
+			m.evictLocked(m.storeRoomLocked)
+			if !m.storeRoomLocked() {
+				m.queue = append([]*parseJob{job}, m.queue...)
+				break
+			}
+			slot := &parseSlot{held: true, opening: true}
+			m.opening++
+			state.slot = slot
+			go func() {
+				defer m.parseDone(slot)
+				job.run()
+			}()
+		}
+		state.Session.Status = models.SessionStatusParsing
+		state.Session.QueuePosition = 0
+		m.running++
+		m.notifySession(job.sessionID)
+	}
+
+	for i, job := range m.queue {
+		if state, ok := m.sessions[job.sessionID]; ok && state.Session.QueuePosition != i+1 {
+			state.Session.QueuePosition = i + 1
+			m.notifySession(job.sessionID)
+		}
+	}
+}
+
+// parseDone frees the slot of a finished parse for the next queued one, unless the
+// parse gave it up when paused.
+func (m *Manager) parseDone(slot *parseSlot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storeOpenedLocked(slot)
+	m.releaseSlotLocked(slot)
+}
+
+// storeOpened marks the store of a session's parse as open (or failed to open), so the
+// parse no longer holds back others waiting for the DuckDB budget.
+func (m *Manager) storeOpened(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.sessions[sessionID]; ok {
+		m.storeOpenedLocked(state.slot)
+	}
+}
+
+// storeOpenedLocked drops the budget reservation of a slot. m.mu must be held.
+func (m *Manager) storeOpenedLocked(slot *parseSlot) {
+	if slot == nil || !slot.opening {
+		return
+	}
+	slot.opening = false
+	m.opening--
+}
+
+// releaseSlotLocked gives up a held slot and starts the next queued parse. m.mu must be held.
+func (m *Manager) releaseSlotLocked(slot *parseSlot) {
+	if slot == nil || !slot.held {
+		return
+	}
+	slot.held = false
+	m.running--
+	m.dispatchLocked()
+}
+
+// queuedLocked reports whether a session waits in the queue. m.mu must be held.
+func (m *Manager) queuedLocked(sessionID string) bool {
+	for _, job := range m.queue {
+		if job.sessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// dequeueLocked removes a waiting session from the queue. m.mu must be held.
+func (m *Manager) dequeueLocked(sessionID string) {
+	for i, job := range m.queue {
+		if job.sessionID == sessionID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			if state, ok := m.sessions[sessionID]; ok {
+				state.Session.QueuePosition = 0
+			}
+			m.dispatchLocked()
+			return
+		}
+	}
+}
+
+// SetPriority changes the priority of a session; a queued session moves accordingly.
+func (m *Manager) SetPriority(id string, priority int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	state.Session.Priority = priority
+	for _, job := range m.queue {
+		if job.sessionID == id {
+			job.priority = priority
+		}
+	}
+	m.dispatchLocked()
+	return nil
+}
+
+// CancelSession stops the parse of a pending, parsing or paused session, which then stays
+// listed as cancelled; the parse goroutine removes partially written stores. Finished
+// sessions are closed and removed.
+func (m *Manager) CancelSession(id string) error {
+	defer m.notifySession(id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if !state.Session.Status.Finished() {
+		if state.cancel != nil {
+			state.cancel()
+		}
+		m.dequeueLocked(id)
+		state.Session.Status = models.SessionStatusCancelled
+		fmt.Printf("[Manager] Cancelled parse of session %s\n", shortID(id))
+		return nil
+	}
+
+	// Console queries are interrupted; the store is closed once they and any running
+	// export have released it
+	m.cancelSQLQueries(id)
+	if state.DuckStore != nil {
+		state.DuckStore.Close()
+	}
+	if state.cancel != nil {
+		state.cancel()
+	}
+	delete(m.sessions, id)
+	m.dispatchLocked() // Queued parses may fit in the budget now
+	return nil
+}
+
+// cancelSQLQueries interrupts the running console queries of a session.
+func (m *Manager) cancelSQLQueries(id string) {
+	m.sqlMu.Lock()
+	defer m.sqlMu.Unlock()
+
+	prefix := id + "/"
+	for key, cancel := range m.sqlQueries {
+		if strings.HasPrefix(key, prefix) {
+			cancel()
+		}
+	}
+}
+
+// PauseSession pauses a parsing session at the parser's next checkpoint and frees its
+// parse slot for queued sessions.
+func (m *Manager) PauseSession(id string) error {
+	return m.setPaused(id, true)
+}
+
+// ResumeSession continues a paused session once a parse slot is free; until then it stays
+// paused with a queue position.
+func (m *Manager) ResumeSession(id string) error {
+	return m.setPaused(id, false)
+}
+
+func (m *Manager) setPaused(id string, paused bool) error {
+	defer m.notifySession(id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	switch {
+	case state.pause == nil:
+		return ErrSessionState
+	case paused && state.Session.Status == models.SessionStatusParsing:
+		state.pause.Pause()
+		state.Session.Status = models.SessionStatusPaused
+		m.releaseSlotLocked(state.slot)
+	case paused && state.Session.Status == models.SessionStatusPaused && m.queuedLocked(id):
+		// Pausing again while waiting to resume stays paused
+		m.dequeueLocked(id)
+	case !paused && state.Session.Status == models.SessionStatusPaused && !m.queuedLocked(id):
+		m.jobSeq++
+		m.queue = append(m.queue, &parseJob{sessionID: id, priority: state.Session.Priority, seq: m.jobSeq, resume: true})
+		m.dispatchLocked()
+	default:
+		return ErrSessionState
+	}
+	return nil
+}
+
+// SubscribeSession returns a channel that is signalled whenever the progress or the
+// committed entries of a session change, and a function to unsubscribe. Signals are
+// coalesced, so subscribers re-read the session state when woken.
+func (m *Manager) SubscribeSession(id string) (<-chan struct{}, func(), bool) {
+	m.mu.RLock()
+	_, ok := m.sessions[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil, false
+	}
+
+	ch := make(chan struct{}, 1)
+	m.subMu.Lock()
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = make(map[chan struct{}]struct{})
+	}
+	m.subscribers[id][ch] = struct{}{}
+	m.subMu.Unlock()
+
+	return ch, func() {
+		m.subMu.Lock()
+		delete(m.subscribers[id], ch)
+		if len(m.subscribers[id]) == 0 {
+			delete(m.subscribers, id)
+		}
+		m.subMu.Unlock()
+	}, true
+}
+
+// notifySession wakes the subscribers of a session without blocking on slow ones.
+func (m *Manager) notifySession(id string) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	for ch := range m.subscribers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// streamCommits makes the entries of a store being filled for a session readable as soon
+// as they are committed, growing the session's time range and waking subscribers.
+func (m *Manager) streamCommits(sessionID string, store *parser.DuckStore) {
+	m.mu.Lock()
+	if state, ok := m.sessions[sessionID]; ok {
+		state.Live = store
+	}
+	m.mu.Unlock()
+
+	store.SetCommitHook(func(info parser.CommitInfo) {
+		m.mu.Lock()
+		if state, ok := m.sessions[sessionID]; ok && state.Live == store {
+			state.Session.StartTime = info.StartTime
+			state.Session.EndTime = info.EndTime
+		}
+		m.mu.Unlock()
+		m.notifySession(sessionID)
+	})
+}
+
+// endLive detaches the store of a failed parse before it is closed.
+func (m *Manager) endLive(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.sessions[sessionID]; ok {
+		state.Live = nil
+	}
+}
+
+// GetLiveEntries returns up to limit entries of a session starting at index from, and what
+// has been committed so far. While the session is parsing only committed entries are
+// returned; before the first commit there are none.
+func (m *Manager) GetLiveEntries(ctx context.Context, id string, from, limit int) ([]models.LogEntry, parser.CommitInfo, error) {
+	// The store is acquired rather than read under the lock, so that live reads do not
+	// hold up the commits of the parse; a cancelled parse closes it once they are done
+	m.mu.RLock()
+	state, ok := m.sessions[id]
+	var store *parser.DuckStore
+	var result *models.ParsedLog
+	if ok {
+		store, result = state.DuckStore, state.Result
+		if store == nil {
+			store = state.Live
+		}
+		if store != nil && !store.Acquire() {
+			store = nil
+		}
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return nil, parser.CommitInfo{}, ErrSessionNotFound
+	}
+
+	if store != nil {
+		defer store.Release()
+		return store.GetCommittedEntries(ctx, from, limit)
+	}
+	if result == nil {
+		return []models.LogEntry{}, parser.CommitInfo{}, nil
+	}
+
+	// Legacy in-memory results are complete once set
+	info := parser.CommitInfo{Count: len(result.Entries)}
+	if result.TimeRange != nil {
+		info.StartTime = result.TimeRange.Start.UnixMilli()
+		info.EndTime = result.TimeRange.End.UnixMilli()
+	}
+	if from >= info.Count {
+		return []models.LogEntry{}, info, nil
+	}
+	end := from + limit
+	if end > info.Count {
+		end = info.Count
+	}
+	return result.Entries[from:end], info, nil
+}
+
 // QueryEntries returns filtered, sorted and paginated entries for a session.
 func (m *Manager) QueryEntries(ctx context.Context, id string, params parser.QueryParams, page, pageSize int) ([]models.LogEntry, int, bool) {
 	m.mu.RLock()
@@ -509,6 +1088,22 @@ func (m *Manager) QueryEntries(ctx context.Context, id string, params parser.Que
 	return entries, total, ok
 }
 
+// QueryEntriesCursor returns a page of filtered, sorted entries after (or before) an opaque cursor.
+func (m *Manager) QueryEntriesCursor(ctx context.Context, id string, params parser.QueryParams, cursor string, backward bool, limit int) (*parser.EntryPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.QueryEntriesCursor(ctx, params, cursor, backward, limit)
+}
+
 // GetCategories returns all unique categories for a session.
 func (m *Manager) GetCategories(ctx context.Context, id string) ([]string, bool) {
 	m.mu.RLock()
@@ -562,6 +1157,23 @@ func (m *Manager) GetIndexByTime(ctx context.Context, id string, params parser.Q
 	return 0, false
 }
 
+// CursorAtTime returns an entries cursor right before the first record matching filters
+// where timestamp >= ts, or "" if there is none.
+func (m *Manager) CursorAtTime(ctx context.Context, id string, params parser.QueryParams, ts int64) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return "", ErrSessionNotReady
+	}
+
+	return state.DuckStore.CursorAtTime(ctx, params, ts)
+}
+
 // GetTimeTree returns distinct date/hour/minute combos for the jump-to-time UI.
 func (m *Manager) GetTimeTree(ctx context.Context, id string, params parser.QueryParams) ([]parser.TimeTreeEntry, bool) {
 	m.mu.RLock()
@@ -774,14 +1386,18 @@ func (m *Manager) GetSignals(id string) ([]string, bool) {
 }
 
 // StartMultiSession begins the parsing process for multiple files and merges them.
-func (m *Manager) StartMultiSession(fileIDs []string, filePaths []string) (*models.ParseSession, error) {
+func (m *Manager) StartMultiSession(fileIDs []string, filePaths []string, priority int) (*models.ParseSession, error) {
 	if len(fileIDs) == 0 || len(fileIDs) != len(filePaths) {
 		return nil, fmt.Errorf("mismatched fileIDs and filePaths")
 	}
 
 	// For single file, delegate to StartSession
 	if len(fileIDs) == 1 {
-		return m.StartSession(fileIDs[0], filePaths[0])
+		return m.StartSession(fileIDs[0], filePaths[0], priority)
+	}
+
+	if err := m.cleanupOldSessionsIfNeeded(false); err != nil {
+		return nil, err
 	}
 
 	sessionID := uuid.New().String()
@@ -789,23 +1405,25 @@ func (m *Manager) StartMultiSession(fileIDs []string, filePaths []string) (*mode
 	// Use first file ID as primary, but indicate merged
 	session := models.NewParseSession(sessionID, fileIDs[0])
 	session.FileIDs = fileIDs // Store all file IDs for merged sessions
-	session.Status = models.SessionStatusParsing
+	session.Priority = priority
 
 	state := &SessionState{
-		Session: session,
+		Session:      session,
+		LastAccessed: time.Now(),
 	}
+	ctx := state.newParseContext()
 
 	m.mu.Lock()
 	m.sessions[sessionID] = state
 	m.mu.Unlock()
 
-	// Run parsing in a background goroutine
-	go m.runMultiParse(sessionID, fileIDs, filePaths)
+	// Parse in a background goroutine once a parse slot is free
+	m.enqueueParse(sessionID, func() { m.runMultiParse(ctx, sessionID, fileIDs, filePaths) })
 
-	return session, nil
+	return m.sessionSnapshot(session), nil
 }
 
-func (m *Manager) runMultiParse(sessionID string, fileIDs, filePaths []string) {
+func (m *Manager) runMultiParse(ctx context.Context, sessionID string, fileIDs, filePaths []string) {
 	start := time.Now()
 
 	// Parse all files
@@ -824,7 +1442,10 @@ func (m *Manager) runMultiParse(sessionID string, fileIDs, filePaths []string) {
 			parserName = p.Name()
 		}
 
-		result, parseErrors, err := p.Parse(filePath)
+		result, parseErrors, err := p.ParseWithProgress(ctx, filePath, nil)
+		if errors.Is(err, context.Canceled) {
+			return
+		}
 		if err != nil {
 			m.updateSessionError(sessionID, fmt.Sprintf("parse failed for file %d: %v", i, err))
 			return
@@ -853,18 +1474,31 @@ func (m *Manager) runMultiParse(sessionID string, fileIDs, filePaths []string) {
 	// Store merged results in DuckStore for consistent querying
 	// This ensures GetChunk, GetValuesAtTime, etc. work without fallback logic
 	store, err := parser.NewDuckStore(m.tempDir, sessionID)
+	m.storeOpened(sessionID)
 	if err != nil {
 		m.updateSessionError(sessionID, fmt.Sprintf("failed to create DuckStore for merged session: %v", err))
 		return
 	}
+	m.mu.RLock()
+	store.SetSearchIndexOnFinalize(m.searchIndex)
+	m.mu.RUnlock()
+	m.streamCommits(sessionID, store)
 	
 	// Add all merged entries to DuckStore
 	for i := range merged.Entries {
+		if i%10000 == 0 {
+			if err := parser.Checkpoint(ctx); err != nil {
+				m.endLive(sessionID)
+				store.Close()
+				return
+			}
+		}
 		store.AddEntry(&merged.Entries[i])
 	}
 	
 	// Finalize to flush remaining entries and create indexes
 	if err := store.Finalize(); err != nil {
+		m.endLive(sessionID)
 		store.Close()
 		m.updateSessionError(sessionID, fmt.Sprintf("failed to finalize DuckStore: %v", err))
 		return
@@ -876,12 +1510,18 @@ func (m *Manager) runMultiParse(sessionID string, fileIDs, filePaths []string) {
 	defer m.mu.Unlock()
 
 	state, ok := m.sessions[sessionID]
-	if !ok {
+	if !ok || state.Session.Status == models.SessionStatusCancelled {
+		// Cancelled after the parse had finished; the temporary merged store is discarded
+		if ok {
+			state.Live = nil
+		}
 		store.Close()
 		return
 	}
+	defer m.notifySession(sessionID)
 
 	state.DuckStore = store
+	state.Live = nil
 	state.Session.Status = models.SessionStatusComplete
 	state.Session.Progress = 100
 	state.Session.EntryCount = store.Len()
@@ -894,10 +1534,19 @@ func (m *Manager) runMultiParse(sessionID string, fileIDs, filePaths []string) {
 		state.Session.StartTime = tr.Start.UnixMilli()
 		state.Session.EndTime = tr.End.UnixMilli()
 	}
+
+	m.detectAnomaliesAsync(sessionID, store)
 }
 
-// DeleteParsedFile removes the parsed DuckDB for a file (call when original file is deleted).
+// DeleteParsedFile removes the parsed DuckDB, annotations and derived signals for a file
+// (call when original file is deleted).
 func (m *Manager) DeleteParsedFile(fileID string) error {
+	if err := m.annotations.DeleteAll(fileID); err != nil {
+		fmt.Printf("[Manager] Warning: failed to delete annotations for file %s: %v\n", shortID(fileID), err)
+	}
+	if err := m.derived.DeleteAll(fileID); err != nil {
+		fmt.Printf("[Manager] Warning: failed to delete derived signals for file %s: %v\n", shortID(fileID), err)
+	}
 	return m.parsedStore.Delete(fileID)
 }
 
@@ -906,7 +1555,531 @@ func (m *Manager) GetParsedStoreStats() map[string]interface{} {
 	return m.parsedStore.Stats()
 }
 
-// CleanupOrphanedParsed removes parsed DBs that don't have corresponding raw files.
+// CleanupOrphanedParsed removes parsed DBs, annotations and derived signals that don't have
+// corresponding raw files. It returns the number of parsed DBs removed.
 func (m *Manager) CleanupOrphanedParsed(rawFileIDs []string) int {
+	valid := make(map[string]bool, len(rawFileIDs))
+	for _, id := range rawFileIDs {
+		valid[id] = true
+	}
+	for _, fileID := range m.annotations.FileIDs() {
+		if valid[fileID] {
+			continue
+		}
+		if err := m.annotations.DeleteAll(fileID); err != nil {
+			fmt.Printf("[Manager] Warning: failed to delete orphaned annotations for file %s: %v\n", shortID(fileID), err)
+		}
+	}
+	for _, fileID := range m.derived.FileIDs() {
+		if valid[fileID] {
+			continue
+		}
+		if err := m.derived.DeleteAll(fileID); err != nil {
+			fmt.Printf("[Manager] Warning: failed to delete orphaned derived signals for file %s: %v\n", shortID(fileID), err)
+		}
+	}
 	return m.parsedStore.CleanupOrphaned(rawFileIDs)
 }
+
+// sessionFileIDs returns the source file IDs of a session (all files for merged sessions).
+func (m *Manager) sessionFileIDs(id string) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if len(state.Session.FileIDs) > 0 {
+		return append([]string(nil), state.Session.FileIDs...), true
+	}
+	return []string{state.Session.FileID}, true
+}
+
+// ListAnnotations returns the annotations stored for a file.
+func (m *Manager) ListAnnotations(fileID string, filter AnnotationFilter) ([]models.Annotation, error) {
+	return m.annotations.List(fileID, filter)
+}
+
+// GetAnnotation returns a single annotation of a file.
+func (m *Manager) GetAnnotation(fileID, annotationID string) (*models.Annotation, error) {
+	return m.annotations.Get(fileID, annotationID)
+}
+
+// AddAnnotation stores a new annotation for a file.
+func (m *Manager) AddAnnotation(fileID string, a models.Annotation) (*models.Annotation, error) {
+	return m.annotations.Add(fileID, a)
+}
+
+// UpdateAnnotation replaces an existing annotation of a file.
+func (m *Manager) UpdateAnnotation(fileID, annotationID string, a models.Annotation) (*models.Annotation, error) {
+	return m.annotations.Update(fileID, annotationID, a)
+}
+
+// DeleteAnnotation removes an annotation from a file.
+func (m *Manager) DeleteAnnotation(fileID, annotationID string) error {
+	return m.annotations.Delete(fileID, annotationID)
+}
+
+// ListPresets returns the filter presets visible in a workspace, or all presets if all is set.
+func (m *Manager) ListPresets(workspace string, all bool) ([]models.FilterPreset, error) {
+	return m.presets.List(workspace, all)
+}
+
+// GetPreset returns a single filter preset.
+func (m *Manager) GetPreset(id string) (*models.FilterPreset, error) {
+	return m.presets.Get(id)
+}
+
+// AddPreset stores a new filter preset.
+func (m *Manager) AddPreset(p models.FilterPreset) (*models.FilterPreset, error) {
+	return m.presets.Add(p)
+}
+
+// UpdatePreset replaces an existing filter preset.
+func (m *Manager) UpdatePreset(id string, p models.FilterPreset) (*models.FilterPreset, error) {
+	return m.presets.Update(id, p)
+}
+
+// DeletePreset removes a filter preset.
+func (m *Manager) DeletePreset(id string) error {
+	return m.presets.Delete(id)
+}
+
+// GetSessionAnnotations returns the annotations of every source file of a session,
+// so merged sessions show the annotations made on each of their files.
+func (m *Manager) GetSessionAnnotations(id string, filter AnnotationFilter) ([]models.Annotation, bool) {
+	fileIDs, ok := m.sessionFileIDs(id)
+	if !ok {
+		return nil, false
+	}
+
+	result := make([]models.Annotation, 0)
+	for _, fileID := range fileIDs {
+		list, err := m.annotations.List(fileID, filter)
+		if err != nil {
+			fmt.Printf("[Manager] Warning: failed to load annotations for file %s: %v\n", shortID(fileID), err)
+			continue
+		}
+		result = append(result, list...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartTime < result[j].StartTime
+	})
+	return result, true
+}
+
+// GetTransitions evaluates transition/cycle-time rules over a session's entries.
+func (m *Manager) GetTransitions(ctx context.Context, id string, q parser.TransitionQuery) (*parser.TransitionReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.GetTransitions(ctx, q)
+}
+
+// GetSignalStats returns per-signal statistics for a session (all signals if none are given).
+func (m *Manager) GetSignalStats(ctx context.Context, id string, signals []string) ([]parser.SignalStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.GetSignalStats(ctx, signals)
+}
+
+// GetWaveform returns a downsampled view of signals in a time range for a session.
+func (m *Manager) GetWaveform(ctx context.Context, id string, startTs, endTs time.Time, width int, signals []string) (*parser.Waveform, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.GetWaveform(ctx, startTs, endTs, width, signals)
+}
+
+// GetEdges returns a page of edges of one signal for a session.
+func (m *Manager) GetEdges(ctx context.Context, id string, q parser.EdgeQuery) (*parser.EdgeList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.GetEdges(ctx, q)
+}
+
+// FindEdge returns the next (forward) or previous edge of one signal relative to a timestamp.
+func (m *Manager) FindEdge(ctx context.Context, id string, q parser.EdgeQuery, from int64, forward bool) (*parser.Edge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.FindEdge(ctx, q, from, forward)
+}
+
+// FindSequences returns the occurrences of a sequence pattern in a session.
+func (m *Manager) FindSequences(ctx context.Context, id string, q parser.SequenceQuery) (*parser.SequenceResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.FindSequences(ctx, q)
+}
+
+// restoreDerivedSignals adds the saved derived signals of a file to its store before the
+// store is handed to a session. Definitions that no longer compile are skipped.
+func (m *Manager) restoreDerivedSignals(fileID string, store *parser.DuckStore) {
+	defs, err := m.derived.List(fileID)
+	if err != nil {
+		fmt.Printf("[Manager] Warning: failed to load derived signals for file %s: %v\n", shortID(fileID), err)
+		return
+	}
+	for _, def := range defs {
+		if _, err := store.SetDerivedSignal(context.Background(), def); err != nil {
+			fmt.Printf("[Manager] Warning: skipping derived signal %s of file %s: %v\n", def.Key(), shortID(fileID), err)
+		}
+	}
+}
+
+// derivedFileID returns the file whose derived signals a session shares, or "" for merged
+// sessions, whose stores are not persisted.
+func derivedFileID(state *SessionState) string {
+	if len(state.Session.FileIDs) > 0 {
+		return ""
+	}
+	return state.Session.FileID
+}
+
+// SetDerivedSignal adds or replaces a derived signal of a session. On single-file sessions
+// the definition is saved with the parsed file and restored when it is loaded again.
+func (m *Manager) SetDerivedSignal(ctx context.Context, id string, def parser.DerivedSignal) (parser.DerivedSignal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return def, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return def, ErrSessionNotReady
+	}
+
+	def, err := state.DuckStore.SetDerivedSignal(ctx, def)
+	if err != nil {
+		return def, err
+	}
+	if fileID := derivedFileID(state); fileID != "" {
+		if err := m.derived.Put(fileID, def); err != nil {
+			return def, err
+		}
+	}
+	return def, nil
+}
+
+// ListDerivedSignals returns the derived signals of a session.
+func (m *Manager) ListDerivedSignals(id string) ([]parser.DerivedSignal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return []parser.DerivedSignal{}, nil
+	}
+
+	return state.DuckStore.DerivedSignals(), nil
+}
+
+// DeleteDerivedSignal removes a derived signal of a session by key.
+func (m *Manager) DeleteDerivedSignal(id string, key string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if state.DuckStore == nil || !state.DuckStore.RemoveDerivedSignal(key) {
+		return ErrDerivedSignalNotFound
+	}
+	if fileID := derivedFileID(state); fileID != "" {
+		return m.derived.Remove(fileID, key)
+	}
+	return nil
+}
+
+// FindCorrelations ranks the signals of a session that change before a target signal.
+func (m *Manager) FindCorrelations(ctx context.Context, id string, q parser.CorrelationQuery) (*parser.CorrelationResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.FindCorrelations(ctx, q)
+}
+
+// GetAnomalies returns a page of the anomalies detected in a session.
+func (m *Manager) GetAnomalies(ctx context.Context, id string, q parser.AnomalyQuery) (*parser.AnomalyReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil {
+		return nil, ErrSessionNotReady
+	}
+
+	return state.DuckStore.GetAnomalies(ctx, q)
+}
+
+// DiffSessions compares the signals of session id (A) with those of session otherID (B).
+func (m *Manager) DiffSessions(ctx context.Context, id, otherID string, q parser.DiffQuery) (*parser.SessionDiff, error) {
+	a, b, done, err := m.diffStores(id, otherID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return parser.DiffStores(ctx, a, b, q)
+}
+
+// DiffSessionSignal returns the time ranges where a signal differs between two sessions.
+func (m *Manager) DiffSessionSignal(ctx context.Context, id, otherID, key string, offset int64, limit int) (*parser.SignalDiffDetail, error) {
+	a, b, done, err := m.diffStores(id, otherID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return parser.DiffSignal(ctx, a, b, key, offset, limit)
+}
+
+// diffStores acquires the stores of two different sessions for a diff, which streams
+// both stores and can take long, so it runs without the session lock. done must be
+// called when the diff has ended.
+func (m *Manager) diffStores(id, otherID string) (*parser.DuckStore, *parser.DuckStore, func(), error) {
+	// A diff takes a query slot of each store, which one store could not grant twice
+	// without deadlocking once its slots are in use
+	if id == otherID {
+		return nil, nil, nil, fmt.Errorf("%w: cannot diff a session with itself", parser.ErrInvalidQuery)
+	}
+	a, err := m.acquireStore(id)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s", err, id)
+	}
+	b, err := m.acquireStore(otherID)
+	if err != nil {
+		m.releaseStore(id, a)
+		return nil, nil, nil, fmt.Errorf("%w: %s", err, otherID)
+	}
+	return a, b, func() {
+		m.releaseStore(id, a)
+		m.releaseStore(otherID, b)
+	}, nil
+}
+
+// RunSQL runs a read-only SQL console query against a session. A non-empty queryID lets
+// CancelSQL interrupt the query while it runs.
+func (m *Manager) RunSQL(ctx context.Context, id, queryID string, q parser.SQLQuery) (*parser.SQLResult, error) {
+	store, ctx, done, err := m.sqlConsole(ctx, id, queryID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return store.RunSQL(ctx, q)
+}
+
+// RunSQLArrow runs a console query like RunSQL and writes the result to w as an Arrow IPC stream.
+func (m *Manager) RunSQLArrow(ctx context.Context, id, queryID string, w io.Writer, q parser.SQLQuery) (int, bool, error) {
+	store, ctx, done, err := m.sqlConsole(ctx, id, queryID)
+	if err != nil {
+		return 0, false, err
+	}
+	defer done()
+	return store.RunSQLArrow(ctx, w, q)
+}
+
+// sqlConsole returns the store of a session for a console query and, for a non-empty
+// queryID, a context CancelSQL can cancel. done must be called when the query has ended.
+func (m *Manager) sqlConsole(ctx context.Context, id, queryID string) (*parser.DuckStore, context.Context, func(), error) {
+	// Console queries can run for minutes, so instead of holding the lock for the whole
+	// query the store is acquired, which defers closing it until the query has ended
+	store, err := m.acquireStore(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if queryID == "" {
+		return store, ctx, func() { m.releaseStore(id, store) }, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	key := id + "/" + queryID
+	m.sqlMu.Lock()
+	m.sqlQueries[key] = cancel
+	m.sqlMu.Unlock()
+	return store, ctx, func() {
+		cancel()
+		m.sqlMu.Lock()
+		delete(m.sqlQueries, key)
+		m.sqlMu.Unlock()
+		m.releaseStore(id, store)
+	}, nil
+}
+
+// acquireStore returns the store of a finished session for a read that runs without
+// holding m.mu. The caller must hand it back with releaseStore.
+func (m *Manager) acquireStore(id string) (*parser.DuckStore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if state.DuckStore == nil || !state.DuckStore.Acquire() {
+		return nil, ErrSessionNotReady
+	}
+	state.LastAccessed = time.Now()
+	return state.DuckStore, nil
+}
+
+// releaseStore ends a read started with acquireStore; a long read counts as an access
+// of the session until it ends.
+func (m *Manager) releaseStore(id string, store *parser.DuckStore) {
+	store.Release()
+	m.TouchSession(id)
+}
+
+// CancelSQL interrupts a running SQL console query.
+func (m *Manager) CancelSQL(id, queryID string) error {
+	m.sqlMu.Lock()
+	defer m.sqlMu.Unlock()
+
+	cancel, ok := m.sqlQueries[id+"/"+queryID]
+	if !ok {
+		return ErrQueryNotFound
+	}
+	cancel()
+	return nil
+}
+
+// ExportEntries streams the matching entries of a session to w. A non-empty exportID makes
+// the progress queryable with GetExportProgress while the export runs and shortly after.
+func (m *Manager) ExportEntries(ctx context.Context, id, exportID string, w io.Writer, q parser.ExportQuery) (int, error) {
+	// Exports can run for minutes, so instead of holding the lock for the whole export
+	// the store is acquired, which defers closing it until the export has ended
+	store, err := m.acquireStore(id)
+	if err != nil {
+		return 0, err
+	}
+	defer m.releaseStore(id, store)
+
+	if exportID == "" {
+		return store.ExportEntries(ctx, w, q)
+	}
+
+	key := id + "/" + exportID
+	progress := &models.ExportProgress{ID: exportID, SessionID: id, Format: string(q.Format), StartedAt: time.Now()}
+	m.exportMu.Lock()
+	m.exports[key] = progress
+	m.exportMu.Unlock()
+
+	onStart, onProgress := q.OnStart, q.Progress
+	q.OnStart = func(total int) {
+		m.exportMu.Lock()
+		progress.Total = total
+		m.exportMu.Unlock()
+		if onStart != nil {
+			onStart(total)
+		}
+	}
+	q.Progress = func(rows int) {
+		m.exportMu.Lock()
+		progress.Rows = rows
+		m.exportMu.Unlock()
+		if onProgress != nil {
+			onProgress(rows)
+		}
+	}
+
+	n, err := store.ExportEntries(ctx, w, q)
+
+	m.exportMu.Lock()
+	progress.Rows = n
+	progress.Done = true
+	if err != nil {
+		progress.Error = err.Error()
+	}
+	m.exportMu.Unlock()
+	time.AfterFunc(ExportProgressRetention, func() {
+		m.exportMu.Lock()
+		if m.exports[key] == progress {
+			delete(m.exports, key)
+		}
+		m.exportMu.Unlock()
+	})
+	return n, err
+}
+
+// GetExportProgress returns the progress of a running or recently finished export.
+func (m *Manager) GetExportProgress(id, exportID string) (*models.ExportProgress, bool) {
+	m.exportMu.Lock()
+	defer m.exportMu.Unlock()
+
+	progress, ok := m.exports[id+"/"+exportID]
+	if !ok {
+		return nil, false
+	}
+	copied := *progress
+	return &copied, true
+}
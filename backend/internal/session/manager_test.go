@@ -2,12 +2,14 @@ package session
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/plc-visualizer/backend/internal/models"
+	"github.com/plc-visualizer/backend/internal/parser"
 )
 
 func TestSessionManager(t *testing.T) {
@@ -37,7 +39,7 @@ func TestSessionManager(t *testing.T) {
 	m := NewManager()
 
 	// Start session
-	sess, err := m.StartSession("file-1", tmpFile)
+	sess, err := m.StartSession("file-1", tmpFile, 0)
 	if err != nil {
 		t.Fatalf("Failed to start session: %v", err)
 	}
@@ -73,3 +75,461 @@ func TestSessionManager(t *testing.T) {
 		t.Errorf("Expected DeviceID DEV-1, got %s", entries[0].DeviceID)
 	}
 }
+
+func TestSessionManager_LiveEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	tmpFile := filepath.Join(tmpDir, "test_live.log")
+	content := "2025-09-22 13:00:00.199 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n" +
+		"2025-09-22 13:00:01.199 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : OFF\n"
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	m := NewManager()
+	if _, _, ok := m.SubscribeSession("missing"); ok {
+		t.Fatal("Expected subscribing to an unknown session to fail")
+	}
+
+	sess, err := m.StartSession("file-live", tmpFile, 0)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	updates, unsubscribe, ok := m.SubscribeSession(sess.ID)
+	if !ok {
+		t.Fatal("Failed to subscribe")
+	}
+	defer unsubscribe()
+
+	// Subscribers are woken until the session is complete
+	timeout := time.After(10 * time.Second)
+	for {
+		s, _ := m.GetSession(sess.ID)
+		if s.Status == models.SessionStatusComplete {
+			break
+		}
+		if s.Status == models.SessionStatusError {
+			t.Fatalf("Session error: %v", s.Errors)
+		}
+		select {
+		case <-updates:
+		case <-timeout:
+			t.Fatal("Timed out waiting for session updates")
+		}
+	}
+
+	entries, info, err := m.GetLiveEntries(context.Background(), sess.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("GetLiveEntries failed: %v", err)
+	}
+	if info.Count != 2 || len(entries) != 1 || entries[0].Value != false {
+		t.Errorf("Expected the second of 2 entries, got %+v of %d", entries, info.Count)
+	}
+	if info.EndTime-info.StartTime != 1000 {
+		t.Errorf("Expected a 1s time range, got %d..%d", info.StartTime, info.EndTime)
+	}
+}
+
+func TestSessionManager_PauseAndCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	// Large enough that the parse is still running when it gets paused
+	tmpFile := filepath.Join(tmpDir, "test_cancel.log")
+	line := []byte("2025-09-22 13:00:00.199 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n")
+	content := make([]byte, 0, len(line)*100000)
+	for i := 0; i < 100000; i++ {
+		content = append(content, line...)
+	}
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	m := NewManager()
+	sess, err := m.StartSession("file-cancel", tmpFile, 0)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if err := m.PauseSession(sess.ID); err != nil {
+		t.Fatalf("PauseSession failed: %v", err)
+	}
+	if err := m.PauseSession(sess.ID); !errors.Is(err, ErrSessionState) {
+		t.Errorf("Expected pausing twice to fail, got %v", err)
+	}
+	if err := m.ResumeSession(sess.ID); err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if err := m.PauseSession(sess.ID); err != nil {
+		t.Fatalf("PauseSession failed: %v", err)
+	}
+
+	// Wait for the paused parse to have created its persistent store
+	dbPath := m.parsedStore.GetDBPath("file-cancel")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(dbPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Parse did not create %s", dbPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if s, _ := m.GetSession(sess.ID); s.Status != models.SessionStatusPaused {
+		t.Fatalf("Expected status paused, got %s", s.Status)
+	}
+
+	if err := m.CancelSession(sess.ID); err != nil {
+		t.Fatalf("CancelSession failed: %v", err)
+	}
+	if s, _ := m.GetSession(sess.ID); s.Status != models.SessionStatusCancelled {
+		t.Errorf("Expected status cancelled, got %s", s.Status)
+	}
+
+	// The partial persistent store is removed by the parse goroutine
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Partial store %s was not removed", dbPath)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if m.parsedStore.IsParsed("file-cancel") {
+		t.Error("Cancelled file must not be marked as parsed")
+	}
+
+	// Deleting a finished session removes it
+	if err := m.CancelSession(sess.ID); err != nil {
+		t.Fatalf("CancelSession failed: %v", err)
+	}
+	if _, ok := m.GetSession(sess.ID); ok {
+		t.Error("Expected the session to be removed")
+	}
+	if err := m.CancelSession(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestSessionManager_ParseQueue(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	line := []byte("2025-09-22 13:00:00.199 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n")
+	writeLog := func(name string, lines int) string {
+		path := filepath.Join(tmpDir, name)
+		content := make([]byte, 0, len(line)*lines)
+		for i := 0; i < lines; i++ {
+			content = append(content, line...)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		return path
+	}
+
+	m := NewManager()
+	m.SetMaxConcurrentParses(1)
+
+	// A parse blocked until released holds the only slot
+	release := holdParseSlot(m, "blocker")
+
+	low, _ := m.StartSession("file-b", writeLog("b.log", 10), 0)
+	high, _ := m.StartSession("file-c", writeLog("c.log", 10), 0)
+	position := func(id string) (models.SessionStatus, int) {
+		s, _ := m.GetSession(id)
+		return s.Status, s.QueuePosition
+	}
+	if status, pos := position(low.ID); status != models.SessionStatusPending || pos != 1 {
+		t.Fatalf("Expected first queued session pending at 1, got %s at %d", status, pos)
+	}
+	if _, pos := position(high.ID); pos != 2 {
+		t.Errorf("Expected second queued session at 2, got %d", pos)
+	}
+
+	if err := m.SetPriority(high.ID, 5); err != nil {
+		t.Fatalf("SetPriority failed: %v", err)
+	}
+	if _, pos := position(high.ID); pos != 1 {
+		t.Errorf("Expected prioritized session at 1, got %d", pos)
+	}
+	if _, pos := position(low.ID); pos != 2 {
+		t.Errorf("Expected other session at 2, got %d", pos)
+	}
+
+	// A session started with a priority is queued by it right away
+	urgent, _ := m.StartSession("file-d", writeLog("d.log", 10), 9)
+	if urgent.QueuePosition != 1 {
+		t.Errorf("Expected session started with priority 9 at 1, got %d", urgent.QueuePosition)
+	}
+	if err := m.CancelSession(urgent.ID); err != nil {
+		t.Fatalf("CancelSession failed: %v", err)
+	}
+
+	// Cancelling a queued session removes it from the queue
+	if err := m.CancelSession(low.ID); err != nil {
+		t.Fatalf("CancelSession failed: %v", err)
+	}
+	if status, pos := position(low.ID); status != models.SessionStatusCancelled || pos != 0 {
+		t.Errorf("Expected cancelled session out of the queue, got %s at %d", status, pos)
+	}
+
+	// Freeing the slot starts the next queued parse
+	close(release)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status, _ := position(high.ID)
+		if status == models.SessionStatusComplete {
+			break
+		}
+		if status == models.SessionStatusError || time.Now().After(deadline) {
+			t.Fatalf("Queued session did not complete, status %s", status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if status, _ := position(low.ID); status != models.SessionStatusCancelled {
+		t.Errorf("Cancelled session must not be parsed, got %s", status)
+	}
+}
+
+// holdParseSlot queues a parse for a new session id that holds its slot until the
+// returned channel is closed.
+func holdParseSlot(m *Manager, id string) chan struct{} {
+	release := make(chan struct{})
+	m.mu.Lock()
+	m.sessions[id] = &SessionState{Session: models.NewParseSession(id, id), LastAccessed: time.Now()}
+	m.mu.Unlock()
+	m.enqueueParse(id, func() { <-release })
+	return release
+}
+
+func TestSessionManager_PauseFreesSlot(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	line := []byte("2025-09-22 13:00:00.199 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n")
+	content := make([]byte, 0, len(line)*100000)
+	for i := 0; i < 100000; i++ {
+		content = append(content, line...)
+	}
+	path := filepath.Join(tmpDir, "paused.log")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	m := NewManager()
+	m.SetMaxConcurrentParses(1)
+
+	paused, err := m.StartSession("file-paused", path, 0)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if err := m.PauseSession(paused.ID); err != nil {
+		t.Fatalf("PauseSession failed: %v", err)
+	}
+
+	// The paused parse gave up its slot, so the next one starts right away
+	release := holdParseSlot(m, "blocker")
+	if s, _ := m.GetSession("blocker"); s.Status != models.SessionStatusParsing {
+		t.Fatalf("Expected the next parse to start, got %s", s.Status)
+	}
+
+	// Resuming waits for a free slot
+	if err := m.ResumeSession(paused.ID); err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if s, _ := m.GetSession(paused.ID); s.Status != models.SessionStatusPaused || s.QueuePosition != 1 {
+		t.Fatalf("Expected the session paused at 1 until a slot is free, got %s at %d", s.Status, s.QueuePosition)
+	}
+	if err := m.ResumeSession(paused.ID); !errors.Is(err, ErrSessionState) {
+		t.Errorf("Expected resuming twice to fail, got %v", err)
+	}
+
+	close(release)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s, _ := m.GetSession(paused.ID)
+		if s.Status == models.SessionStatusComplete {
+			break
+		}
+		if s.Status == models.SessionStatusError || time.Now().After(deadline) {
+			t.Fatalf("Resumed session did not complete, status %s", s.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSessionManager_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewManager()
+	m.SetMaxSessions(3)
+
+	now := time.Now()
+	add := func(id string, status models.SessionStatus, accessed time.Duration) {
+		sess := models.NewParseSession(id, id)
+		sess.Status = status
+		m.sessions[id] = &SessionState{Session: sess, LastAccessed: now.Add(-accessed)}
+	}
+	add("recent", models.SessionStatusComplete, time.Minute)
+	add("stale", models.SessionStatusComplete, time.Hour)
+	add("running", models.SessionStatusParsing, 2*time.Hour)
+
+	if err := m.cleanupOldSessionsIfNeeded(false); err != nil {
+		t.Fatalf("cleanupOldSessionsIfNeeded failed: %v", err)
+	}
+
+	if _, ok := m.sessions["stale"]; ok {
+		t.Error("Expected the least recently accessed finished session to be evicted")
+	}
+	for _, id := range []string{"recent", "running"} {
+		if _, ok := m.sessions[id]; !ok {
+			t.Errorf("Expected session %s to be kept", id)
+		}
+	}
+}
+
+func TestSessionManager_ParseWaitsForBudget(t *testing.T) {
+	m := NewManager()
+
+	// Started parses that have not opened their store yet take up the whole budget
+	m.mu.Lock()
+	m.opening = parser.StoreHeadroom()
+	m.mu.Unlock()
+
+	sess := models.NewParseSession("queued", "queued")
+	m.sessions["queued"] = &SessionState{Session: sess, LastAccessed: time.Now()}
+	started := make(chan struct{})
+	m.enqueueParse("queued", func() { close(started) })
+
+	if s, _ := m.GetSession("queued"); s.Status != models.SessionStatusPending || s.QueuePosition != 1 {
+		t.Fatalf("Expected the parse to wait at 1 for the budget, got %s at %d", s.Status, s.QueuePosition)
+	}
+
+	// Freeing the budget lets the next dispatch start it
+	m.mu.Lock()
+	m.opening = 0
+	m.mu.Unlock()
+	m.CleanupOldSessions(time.Hour)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the queued parse to start once the budget has room")
+	}
+}
+
+func TestSessionManager_DiffWithItself(t *testing.T) {
+	m := NewManager()
+	sess := models.NewParseSession("a", "a")
+	sess.Status = models.SessionStatusComplete
+	m.sessions["a"] = &SessionState{Session: sess, LastAccessed: time.Now()}
+
+	if _, err := m.DiffSessions(context.Background(), "a", "a", parser.DiffQuery{}); !errors.Is(err, parser.ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+	if _, err := m.DiffSessionSignal(context.Background(), "a", "a", "DEV::SIG", 0, 10); !errors.Is(err, parser.ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+}
+
+func TestSessionManager_DerivedSignalsPersist(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	tmpFile := filepath.Join(tmpDir, "test_derived.log")
+	content := "2025-09-22 13:00:00.000 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : ON\n" +
+		"2025-09-22 13:00:01.000 [Debug] [SYSTEM/PATH/DEV-1] [INPUT:SIG1] (Boolean) : OFF\n"
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	start := func(m *Manager) string {
+		sess, err := m.StartSession("file-derived", tmpFile, 0)
+		if err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			s, _ := m.GetSession(sess.ID)
+			if s.Status == models.SessionStatusComplete {
+				return sess.ID
+			}
+			if s.Status == models.SessionStatusError || time.Now().After(deadline) {
+				t.Fatalf("Parse did not complete, status %s", s.Status)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	ctx := context.Background()
+
+	m := NewManager()
+	id := start(m)
+	if _, err := m.SetDerivedSignal(ctx, id, parser.DerivedSignal{Name: "Off", Expression: "NOT DEV-1::SIG1"}); err != nil {
+		t.Fatalf("SetDerivedSignal failed: %v", err)
+	}
+	if _, err := m.SetDerivedSignal(ctx, id, parser.DerivedSignal{Name: "Same", Expression: "DEV-1::SIG1"}); err != nil {
+		t.Fatalf("SetDerivedSignal failed: %v", err)
+	}
+	if err := m.DeleteDerivedSignal(id, "Derived::Same"); err != nil {
+		t.Fatalf("DeleteDerivedSignal failed: %v", err)
+	}
+	if err := m.CancelSession(id); err != nil {
+		t.Fatalf("CancelSession failed: %v", err)
+	}
+
+	// A new manager loads the file from the persistent store with its derived signals
+	m = NewManager()
+	id = start(m)
+	if s, _ := m.GetSession(id); s.ParserName != "plc_debug_cached" {
+		t.Fatalf("Expected the file to be loaded from the persistent store, got parser %s", s.ParserName)
+	}
+	defs, err := m.ListDerivedSignals(id)
+	if err != nil || len(defs) != 1 || defs[0].Key() != "Derived::Off" {
+		t.Fatalf("Expected the saved derived signal, got %+v (%v)", defs, err)
+	}
+	values, ok := m.GetValuesAtTime(ctx, id, time.Date(2025, 9, 24, 0, 0, 0, 0, time.UTC), []string{"Derived::Off"})
+	if !ok || len(values) != 1 || values[0].Value != true {
+		t.Errorf("Expected Off=true after the signal turned off, got %+v", values)
+	}
+
+	if err := m.DeleteParsedFile("file-derived"); err != nil {
+		t.Fatalf("DeleteParsedFile failed: %v", err)
+	}
+	if defs, _ := m.derived.List("file-derived"); len(defs) != 0 {
+		t.Errorf("Expected derived signals to be deleted with the file, got %+v", defs)
+	}
+}
+
+func TestSessionManager_CleanupOrphanedSidecars(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("PARSED_DB_DIR", filepath.Join(tmpDir, "parsed"))
+	t.Setenv("DUCKDB_TEMP_DIR", filepath.Join(tmpDir, "temp"))
+
+	m := NewManager()
+	for _, fileID := range []string{"kept", "gone"} {
+		if _, err := m.AddAnnotation(fileID, models.Annotation{Kind: models.AnnotationKindPoint, StartTime: 1}); err != nil {
+			t.Fatalf("AddAnnotation failed: %v", err)
+		}
+		if err := m.derived.Put(fileID, parser.DerivedSignal{DeviceID: "Derived", Name: "X", Expression: "DEV::A"}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	m.CleanupOrphanedParsed([]string{"kept"})
+
+	if ids := m.annotations.FileIDs(); len(ids) != 1 || ids[0] != "kept" {
+		t.Errorf("Expected only the annotations of the kept file, got %v", ids)
+	}
+	if ids := m.derived.FileIDs(); len(ids) != 1 || ids[0] != "kept" {
+		t.Errorf("Expected only the derived signals of the kept file, got %v", ids)
+	}
+	if list, _ := m.ListAnnotations("gone", AnnotationFilter{}); len(list) != 0 {
+		t.Errorf("Expected no cached annotations of the removed file, got %+v", list)
+	}
+}
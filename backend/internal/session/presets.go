@@ -0,0 +1,186 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+// ErrPresetNotFound is returned for unknown preset IDs.
+var ErrPresetNotFound = errors.New("preset not found")
+
+// PresetStore persists filter presets in a single JSON file (presets.json) next to the
+// parsed DuckDB files. The list is small, so it is kept in memory and rewritten on change.
+type PresetStore struct {
+	path    string
+	mu      sync.RWMutex
+	presets []*models.FilterPreset
+	loaded  bool
+}
+
+// NewPresetStore creates a preset store rooted at dir.
+func NewPresetStore(dir string) *PresetStore {
+	os.MkdirAll(dir, 0755)
+	return &PresetStore{path: filepath.Join(dir, "presets.json")}
+}
+
+// load reads the presets from disk on first access. Caller must hold ps.mu (write lock).
+func (ps *PresetStore) load() error {
+	if ps.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(ps.path)
+	if os.IsNotExist(err) {
+		ps.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read presets: %w", err)
+	}
+	if err := json.Unmarshal(data, &ps.presets); err != nil {
+		return fmt.Errorf("failed to decode presets: %w", err)
+	}
+	ps.loaded = true
+	return nil
+}
+
+// save writes the presets to disk via a temp file and rename. Caller must hold ps.mu (write lock).
+func (ps *PresetStore) save(list []*models.FilterPreset) error {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode presets: %w", err)
+	}
+
+	tmpPath := ps.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write presets: %w", err)
+	}
+	if err := os.Rename(tmpPath, ps.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write presets: %w", err)
+	}
+
+	ps.presets = list
+	return nil
+}
+
+// List returns the presets visible in a workspace (global ones plus the workspace's own),
+// ordered by name. With all set, every preset of every workspace is returned.
+func (ps *PresetStore) List(workspace string, all bool) ([]models.FilterPreset, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.load(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.FilterPreset, 0, len(ps.presets))
+	for _, p := range ps.presets {
+		if all || p.VisibleIn(workspace) {
+			result = append(result, *p)
+		}
+	}
+	return result, nil
+}
+
+// Get returns a single preset.
+func (ps *PresetStore) Get(id string) (*models.FilterPreset, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.load(); err != nil {
+		return nil, err
+	}
+	for _, p := range ps.presets {
+		if p.ID == id {
+			copied := *p
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPresetNotFound, id)
+}
+
+// Add stores a new preset and returns it with ID and timestamps filled in.
+func (ps *PresetStore) Add(p models.FilterPreset) (*models.FilterPreset, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.load(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p.ID = uuid.New().String()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+
+	stored := p
+	list := append(append([]*models.FilterPreset(nil), ps.presets...), &stored)
+	if err := ps.save(list); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Update replaces an existing preset.
+func (ps *PresetStore) Update(id string, p models.FilterPreset) (*models.FilterPreset, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.load(); err != nil {
+		return nil, err
+	}
+
+	for i, existing := range ps.presets {
+		if existing.ID != id {
+			continue
+		}
+		p.ID = existing.ID
+		p.CreatedAt = existing.CreatedAt
+		p.UpdatedAt = time.Now()
+
+		updated := make([]*models.FilterPreset, len(ps.presets))
+		copy(updated, ps.presets)
+		stored := p
+		updated[i] = &stored
+		if err := ps.save(updated); err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPresetNotFound, id)
+}
+
+// Delete removes a preset.
+func (ps *PresetStore) Delete(id string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := ps.load(); err != nil {
+		return err
+	}
+
+	for i, existing := range ps.presets {
+		if existing.ID != id {
+			continue
+		}
+		updated := make([]*models.FilterPreset, 0, len(ps.presets)-1)
+		updated = append(updated, ps.presets[:i]...)
+		updated = append(updated, ps.presets[i+1:]...)
+		return ps.save(updated)
+	}
+	return fmt.Errorf("%w: %s", ErrPresetNotFound, id)
+}
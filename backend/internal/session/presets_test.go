@@ -0,0 +1,75 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+func TestPresetStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewPresetStore(dir)
+
+	global, err := store.Add(models.FilterPreset{Name: "Transfers", Search: "transfer"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if global.ID == "" || global.CreatedAt.IsZero() {
+		t.Fatalf("Expected ID and timestamps to be set, got %+v", global)
+	}
+	line3, err := store.Add(models.FilterPreset{Name: "Alarms", Workspace: "line3", Filter: "signal:*Alarm*"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	t.Run("lists global and workspace presets by name", func(t *testing.T) {
+		list, err := store.List("line3", false)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(list) != 2 || list[0].Name != "Alarms" || list[1].Name != "Transfers" {
+			t.Fatalf("Expected both presets sorted by name, got %+v", list)
+		}
+		list, _ = store.List("line1", false)
+		if len(list) != 1 || list[0].ID != global.ID {
+			t.Errorf("Expected only the global preset in another workspace, got %+v", list)
+		}
+		list, _ = store.List("", true)
+		if len(list) != 2 {
+			t.Errorf("Expected every preset with all set, got %d", len(list))
+		}
+	})
+
+	t.Run("updates keep ID and creation time", func(t *testing.T) {
+		updated, err := store.Update(line3.ID, models.FilterPreset{Name: "Line 3 alarms", Workspace: "line3"})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated.ID != line3.ID || !updated.CreatedAt.Equal(line3.CreatedAt) {
+			t.Errorf("Expected ID and creation time to be kept, got %+v", updated)
+		}
+	})
+
+	t.Run("persists across store instances", func(t *testing.T) {
+		reloaded, err := NewPresetStore(dir).Get(line3.ID)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if reloaded.Name != "Line 3 alarms" {
+			t.Errorf("Expected the updated name, got %q", reloaded.Name)
+		}
+	})
+
+	t.Run("delete and not found", func(t *testing.T) {
+		if err := store.Delete(global.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Get(global.ID); !errors.Is(err, ErrPresetNotFound) {
+			t.Errorf("Expected ErrPresetNotFound, got %v", err)
+		}
+		if err := store.Delete(global.ID); !errors.Is(err, ErrPresetNotFound) {
+			t.Errorf("Expected ErrPresetNotFound deleting twice, got %v", err)
+		}
+	})
+}
@@ -40,6 +40,8 @@ type Job struct {
 	Error          string           `json:"error,omitempty"`
 	CreatedAt      time.Time        `json:"createdAt"`
 	CompletedAt    *time.Time       `json:"completedAt,omitempty"`
+
+	onDone func(*Job) // Called once the job completes or fails
 }
 
 // Manager handles async upload processing.
@@ -66,8 +68,9 @@ func NewManager(uploadDir string, store Store) *Manager {
 	}
 }
 
-// StartJob begins async processing of an upload.
-func (m *Manager) StartJob(uploadID, fileName string, totalChunks int, originalSize, compressedSize int64, encoding string) *Job {
+// StartJob begins async processing of an upload and returns a snapshot of the new job.
+// onDone, if not nil, is called with a snapshot of the job once it completes or fails.
+func (m *Manager) StartJob(uploadID, fileName string, totalChunks int, originalSize, compressedSize int64, encoding string, onDone func(*Job)) *Job {
 	job := &Job{
 		ID:             uuid.New().String(),
 		UploadID:       uploadID,
@@ -81,24 +84,30 @@ func (m *Manager) StartJob(uploadID, fileName string, totalChunks int, originalS
 		Stage:          "preparing",
 		StageProgress:  0,
 		CreatedAt:      time.Now(),
+		onDone:         onDone,
 	}
 
 	m.mu.Lock()
 	m.jobs[job.ID] = job
+	snapshot := *job
 	m.mu.Unlock()
 
 	// Start async processing
 	go m.processJob(job)
 
-	return job
+	return &snapshot
 }
 
-// GetJob retrieves a job by ID.
+// GetJob retrieves a snapshot of a job by ID.
 func (m *Manager) GetJob(id string) (*Job, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	job, ok := m.jobs[id]
-	return job, ok
+	if !ok {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
 }
 
 // processJob handles the actual async processing.
@@ -139,8 +148,7 @@ func (m *Manager) processJob(job *Job) {
 	}
 
 	// Complete
-	job.FileInfo = info
-	m.markJobComplete(job)
+	m.markJobComplete(job, info)
 	fmt.Printf("[UploadJob %s] Processing complete: %s (%d bytes)\n", job.ID[:8], info.ID, info.Size)
 }
 
@@ -259,27 +267,36 @@ func (m *Manager) updateJobStatus(job *Job, status Status, stage string, stagePr
 	}
 }
 
-// markJobComplete marks job as complete (thread-safe).
-func (m *Manager) markJobComplete(job *Job) {
+// markJobComplete marks job as complete with the stored file (thread-safe).
+func (m *Manager) markJobComplete(job *Job, info *models.FileInfo) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	job.Status = StatusComplete
 	job.Progress = 100
+	job.FileInfo = info
 	now := time.Now()
 	job.CompletedAt = &now
+	snapshot := *job
+	m.mu.Unlock()
+
+	if snapshot.onDone != nil {
+		snapshot.onDone(&snapshot)
+	}
 }
 
 // markJobError marks job as failed (thread-safe).
 func (m *Manager) markJobError(job *Job, errMsg string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	job.Status = StatusError
 	job.Error = errMsg
 	now := time.Now()
 	job.CompletedAt = &now
+	snapshot := *job
+	m.mu.Unlock()
+
 	fmt.Printf("[UploadJob %s] Error: %s\n", job.ID[:8], errMsg)
+	if snapshot.onDone != nil {
+		snapshot.onDone(&snapshot)
+	}
 }
 
 // CleanupOldJobs removes jobs older than the specified duration.
@@ -0,0 +1,42 @@
+package upload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/plc-visualizer/backend/internal/models"
+)
+
+type fakeStore struct{}
+
+func (fakeStore) CompleteChunkedUpload(uploadID string, name string, totalChunks int) (*models.FileInfo, error) {
+	return &models.FileInfo{ID: "file-1", Name: name}, nil
+}
+func (fakeStore) GetFilePath(id string) (string, error) { return "", nil }
+func (fakeStore) RegisterFile(info *models.FileInfo)    {}
+
+func TestManager_JobSnapshots(t *testing.T) {
+	m := NewManager(t.TempDir(), fakeStore{})
+
+	done := make(chan *Job, 1)
+	job := m.StartJob("up-1", "line.log", 1, 10, 10, "", func(j *Job) { done <- j })
+
+	// Polling reads snapshots while the job goroutine updates the job
+	for i := 0; i < 100; i++ {
+		if j, ok := m.GetJob(job.ID); !ok || j.ID != job.ID {
+			t.Fatalf("Expected job %s, got %+v", job.ID, j)
+		}
+	}
+
+	select {
+	case j := <-done:
+		if j.Status != StatusComplete || j.FileInfo == nil || j.FileInfo.ID != "file-1" {
+			t.Errorf("Expected a complete job with its file, got %+v", j)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("onDone was not called")
+	}
+	if j, _ := m.GetJob(job.ID); j.Status != StatusComplete || j.Progress != 100 {
+		t.Errorf("Expected the stored job to be complete, got %+v", j)
+	}
+}
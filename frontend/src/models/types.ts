@@ -26,7 +26,7 @@ export interface TimeRange {
     end: number;   // Unix ms
 }
 
-export type SessionStatus = 'pending' | 'parsing' | 'complete' | 'error';
+export type SessionStatus = 'pending' | 'parsing' | 'paused' | 'complete' | 'error' | 'cancelled';
 
 export interface ParseSession {
     id: string;
@@ -34,6 +34,8 @@ export interface ParseSession {
     fileIds?: string[]; // All file IDs for merged sessions
     status: SessionStatus;
     progress: number; // 0-100
+    queuePosition?: number; // 1-based position while waiting for a parse slot
+    priority?: number;
     entryCount?: number;
     signalCount?: number;
     processingTimeMs?: number;